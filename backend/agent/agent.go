@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"calculatorapi/utility/calculation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errShuttingDown = errors.New("Server is shutting down")
+	errQueueFull    = errors.New("Server queue is full")
+)
+
+// minRetryAfter - наименьшая подсказка о повторе, которую агент возвращает при заполненной очереди.
+const minRetryAfter = time.Second
+
+// OperationRequest - тело запроса POST /calculate.
+type OperationRequest struct {
+	ID        int            `json:"id"`
+	Operation string         `json:"operation"`
+	Times     map[string]int `json:"times"`
+	Deadline  time.Time      `json:"deadline,omitempty"`
+	Attempt   int            `json:"attempt,omitempty"`
+}
+
+// task - вычисление, принятое агентом.
+type task struct {
+	seq       int // Порядковый номер внутри агента; идентификатор вычисления может повториться при повторной отправке
+	id        int
+	attempt   int // Номер попытки, который указывается в отчетах оркестратору
+	operation string
+	times     calculation.OperationTimes
+	timeout   time.Duration // Время на выполнение, отсчитывается от его начала; 0 - без ограничения
+	estimate  time.Duration // Ожидаемая длительность выполнения
+}
+
+// agent выполняет вычисления, полученные от оркестратора по gRPC или HTTP, и сообщает оркестратору результаты.
+// Доступа к базе данных у агента нет.
+// Вычисления выполняются пулом из MaxConcurrency исполнителей; еще QueueSize вычислений могут ожидать
+// в очереди, и только при заполненной очереди новые вычисления отклоняются.
+type agent struct {
+	pb.UnimplementedCalculatorServiceServer
+
+	cfg      config
+	reporter resultReporter // nil, если оркестратор не задан
+	queue    chan task
+
+	mu         sync.Mutex
+	seq        int
+	queued     int               // Количество вычислений в очереди, включая принимаемые
+	queuedWork time.Duration     // Ожидаемая суммарная длительность вычислений в очереди
+	running    map[int]time.Time // Ожидаемое время завершения выполняемых вычислений по seq
+	accepting  bool              // false после запроса на остановку
+	started    time.Time         // Время запуска агента
+	completed  int64             // Количество завершенных вычислений
+	failed     int64             // Количество прерванных вычислений и вычислений, результат которых оркестратор не принял
+	stopped    chan struct{}     // Закрывается при запросе на остановку
+	freed      chan struct{}     // Получает сигнал, когда исполнитель завершает вычисление
+	wg         sync.WaitGroup    // Принятые и еще не завершенные вычисления
+}
+
+func newAgent(cfg config, reporter resultReporter) *agent {
+	return &agent{
+		cfg:       cfg,
+		reporter:  reporter,
+		queue:     make(chan task, cfg.MaxConcurrency+cfg.QueueSize),
+		running:   map[int]time.Time{},
+		accepting: true,
+		started:   time.Now(),
+		stopped:   make(chan struct{}),
+		freed:     make(chan struct{}, 1),
+	}
+}
+
+// start запускает исполнителей.
+func (a *agent) start() {
+	for i := 0; i < a.cfg.MaxConcurrency; i++ {
+		go func() {
+			for t := range a.queue {
+				a.run(t)
+			}
+		}()
+	}
+}
+
+// ConvertOperationTimes переводит длительности операций из секунд в calculation.OperationTimes.
+func ConvertOperationTimes(times map[string]int) calculation.OperationTimes {
+	operationTimes := calculation.OperationTimes{}
+	for k, v := range times {
+		switch k {
+		case "add_duration":
+			operationTimes["+"] = time.Duration(v) * time.Second
+		case "subtract_duration":
+			operationTimes["-"] = time.Duration(v) * time.Second
+		case "multiply_duration":
+			operationTimes["*"] = time.Duration(v) * time.Second
+		case "divide_duration":
+			operationTimes["/"] = time.Duration(v) * time.Second
+		}
+	}
+	return operationTimes
+}
+
+func convertToIntMap(input map[string]int32) map[string]int {
+	output := make(map[string]int)
+	for key, value := range input {
+		output[key] = int(value)
+	}
+	return output
+}
+
+// estimatedWaitLocked оценивает, через сколько освободится исполнитель для нового вычисления:
+// оставшееся время выполняемых и длительность ожидающих вычислений, поделенные между исполнителями.
+// Вызывается под a.mu.
+func (a *agent) estimatedWaitLocked(now time.Time) time.Duration {
+	if a.queued+len(a.running) < a.cfg.MaxConcurrency {
+		return 0
+	}
+	total := a.queuedWork
+	for _, end := range a.running {
+		if end.After(now) {
+			total += end.Sub(now)
+		}
+	}
+	return total / time.Duration(a.cfg.MaxConcurrency)
+}
+
+// submit ставит попытку attempt вычисления в очередь. Оркестратор отмечает вычисление принятым по успешному ответу.
+// При заполненной очереди возвращает errQueueFull и время, через которое стоит повторить отправку.
+func (a *agent) submit(id, attempt int, operation string, times map[string]int, deadline time.Time) (time.Duration, error) {
+	t := task{id: id, attempt: attempt, operation: operation, times: ConvertOperationTimes(times)}
+	t.estimate = calculation.EstimateDuration(operation, t.times)
+	if !deadline.IsZero() {
+		// Оркестратор отсчитывает срок заново от отчета о начале выполнения, поэтому ожидание в очереди его не сокращает
+		t.timeout = time.Until(deadline)
+		if t.timeout <= 0 {
+			t.timeout = time.Nanosecond
+		}
+	}
+
+	a.mu.Lock()
+	if !a.accepting {
+		a.mu.Unlock()
+		return 0, errShuttingDown
+	}
+	if a.queued+len(a.running) >= a.cfg.MaxConcurrency+a.cfg.QueueSize {
+		wait := a.estimatedWaitLocked(time.Now())
+		a.mu.Unlock()
+		if wait < minRetryAfter {
+			wait = minRetryAfter
+		}
+		return wait, errQueueFull
+	}
+	a.seq++
+	t.seq = a.seq
+	a.queued++
+	a.queuedWork += t.estimate
+	a.wg.Add(1)
+	a.mu.Unlock()
+
+	a.queue <- t // Место в канале зарезервировано выше, поэтому отправка не блокируется
+	return 0, nil
+}
+
+// run выполняет вычисление, взятое исполнителем из очереди.
+// Если задан срок, вычисление прерывается без результата, когда от начала выполнения проходит t.timeout:
+// к этому времени оркестратор уже переназначает его.
+func (a *agent) run(t task) {
+	a.mu.Lock()
+	a.queued--
+	a.queuedWork -= t.estimate
+	a.running[t.seq] = time.Now().Add(t.estimate)
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.running, t.seq)
+		a.mu.Unlock()
+		a.wg.Done()
+		select {
+		case a.freed <- struct{}{}:
+		default:
+		}
+	}()
+
+	ctx := context.Background()
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	a.report(t, pb.ReportResultRequest_STARTED, 0, "", 0)
+
+	operations, result, err := calculation.EvaluateOperationContext(ctx, t.operation, t.times)
+	for _, op := range operations {
+		fmt.Println(op)
+	}
+	if err != nil {
+		fmt.Printf("Calculation ID %d aborted: %v\n", t.id, err)
+		a.report(t, pb.ReportResultRequest_ABORTED, 0, err.Error(), reportRetries)
+		a.count(false)
+		return
+	}
+	fmt.Printf("Calculation ID %d completed. Result: %.6f\n", t.id, result)
+
+	a.count(a.report(t, pb.ReportResultRequest_COMPLETED, result, "", reportRetries))
+}
+
+// count учитывает завершенное (ok) или неудавшееся вычисление в статистике агента.
+func (a *agent) count(ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if ok {
+		a.completed++
+	} else {
+		a.failed++
+	}
+}
+
+// retryAfterSeconds округляет подсказку о повторе вверх до целых секунд.
+func retryAfterSeconds(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
+
+// PerformCalculation принимает вычисление от оркестратора по gRPC.
+// При заполненной очереди возвращает ResourceExhausted с заголовком retry-after в секундах.
+func (a *agent) PerformCalculation(ctx context.Context, req *pb.CalculationRequest) (*pb.CalculationResponse, error) {
+	var deadline time.Time
+	if req.Deadline != nil {
+		deadline = req.Deadline.AsTime()
+	}
+
+	wait, err := a.submit(int(req.Id), int(req.Attempt), req.Operation, convertToIntMap(req.Times), deadline)
+	switch {
+	case errors.Is(err, errShuttingDown):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, errQueueFull):
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(wait)))
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	return &pb.CalculationResponse{Id: req.Id}, nil
+}
+
+// routes регистрирует HTTP обработчики агента.
+func (a *agent) routes(mux *http.ServeMux) {
+	mux.HandleFunc("/calculate", a.calculateHandler)
+	mux.HandleFunc("/goroutines", a.goroutinesHandler)
+	mux.HandleFunc("/shutdown", a.shutdownHandler)
+	mux.HandleFunc("/ping", a.pingHandler)
+}
+
+func (a *agent) calculateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method is not supported.", http.StatusNotFound)
+		return
+	}
+
+	var request OperationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wait, err := a.submit(request.ID, request.Attempt, request.Operation, request.Times, request.Deadline)
+	switch {
+	case errors.Is(err, errShuttingDown):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, errQueueFull):
+		w.Header().Set("Retry-After", retryAfterSeconds(wait))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintln(w, "Calculation started successfully.")
+}
+
+func (a *agent) goroutinesHandler(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	fmt.Fprintf(w, "Current number of goroutines: %d\n", len(a.running))
+	a.mu.Unlock()
+}
+
+// shutdownHandler прекращает прием новых вычислений. Агент завершается после окончания принятых.
+func (a *agent) shutdownHandler(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	if !a.accepting {
+		a.mu.Unlock()
+		http.Error(w, "Server is already shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	a.accepting = false
+	close(a.stopped)
+	a.mu.Unlock()
+	fmt.Fprintln(w, "Server is shutting down...")
+}
+
+// pingResponse - ответ на GET /ping.
+type pingResponse struct {
+	Status               string            `json:"status"`
+	AgentID              string            `json:"agentId"`
+	Labels               map[string]string `json:"labels"`
+	MaxGoroutines        int               `json:"maxGoroutines"`
+	CurrentGoroutines    int               `json:"currentGoroutines"`
+	QueueDepth           int               `json:"queueDepth"`           // Количество вычислений, ожидающих исполнителя
+	QueueSize            int               `json:"queueSize"`            // Вместимость очереди
+	EstimatedWaitSeconds float64           `json:"estimatedWaitSeconds"` // Ожидаемое время до начала выполнения нового вычисления
+}
+
+func (a *agent) pingHandler(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	response := pingResponse{
+		Status:               "running",
+		AgentID:              a.cfg.AgentID,
+		Labels:               a.cfg.Labels,
+		MaxGoroutines:        a.cfg.MaxConcurrency,
+		CurrentGoroutines:    len(a.running),
+		QueueDepth:           a.queued,
+		QueueSize:            a.cfg.QueueSize,
+		EstimatedWaitSeconds: a.estimatedWaitLocked(time.Now()).Seconds(),
+	}
+	if !a.accepting {
+		response.Status = "shutting down"
+	}
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// drain ожидает запроса на остановку и завершения всех принятых вычислений.
+func (a *agent) drain() {
+	<-a.stopped
+	fmt.Println("Server stopped accepting new requests. Waiting for ongoing operations to complete...")
+	a.wg.Wait()
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"calculatorapi/utility/calculation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeReporter - оркестратор, записывающий отчеты агента. Отчеты о вычислениях из rejected отклоняются
+// как устаревшие, а на первые unavailable вызовов оркестратор не отвечает.
+type fakeReporter struct {
+	mu          sync.Mutex
+	reports     []*pb.ReportResultRequest
+	rejected    map[int32]bool
+	unavailable int
+}
+
+func (r *fakeReporter) ReportResult(ctx context.Context, req *pb.ReportResultRequest, opts ...grpc.CallOption) (*pb.ReportResultResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports = append(r.reports, req)
+	if r.unavailable > 0 {
+		r.unavailable--
+		return nil, status.Error(codes.Unavailable, "orchestrator is down")
+	}
+	if r.rejected[req.CalculationId] {
+		return nil, status.Error(codes.FailedPrecondition, "attempt is not assigned to the agent")
+	}
+	return &pb.ReportResultResponse{}, nil
+}
+
+// outcomes возвращает отчеты о вычислении id в виде "исход/попытка/результат".
+func (r *fakeReporter) outcomes(id int32) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var outcomes []string
+	for _, req := range r.reports {
+		if req.CalculationId == id {
+			outcomes = append(outcomes, fmt.Sprintf("%v/%d/%g", req.Outcome, req.Attempt, req.Result))
+		}
+	}
+	return outcomes
+}
+
+// newTestAgent создает агента, отчитывающегося перед fakeReporter. Исполнители не запускаются.
+func newTestAgent(t *testing.T, maxConcurrency, queueSize int) (*agent, *fakeReporter) {
+	t.Helper()
+	reporter := &fakeReporter{rejected: map[int32]bool{}}
+	return newAgent(config{MaxConcurrency: maxConcurrency, QueueSize: queueSize, AgentID: "agent-1", Labels: map[string]string{"region": "eu"}}, reporter), reporter
+}
+
+// Функция проверки конвертации операций
+func TestConvertOperationTimes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    map[string]int
+		expected calculation.OperationTimes
+	}{
+		{
+			name: "All Operations",
+			input: map[string]int{
+				"add_duration":      10,
+				"subtract_duration": 20,
+				"multiply_duration": 30,
+				"divide_duration":   40,
+			},
+			expected: calculation.OperationTimes{
+				"+": 10 * time.Second,
+				"-": 20 * time.Second,
+				"*": 30 * time.Second,
+				"/": 40 * time.Second,
+			},
+		},
+		{
+			name: "Partial Operations",
+			input: map[string]int{
+				"add_duration":      5,
+				"multiply_duration": 15,
+			},
+			expected: calculation.OperationTimes{
+				"+": 5 * time.Second,
+				"*": 15 * time.Second,
+			},
+		},
+		{
+			name:     "Empty Input",
+			input:    map[string]int{},
+			expected: calculation.OperationTimes{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ConvertOperationTimes(tc.input)
+			if len(result) != len(tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, result)
+			}
+			for key, expectedDuration := range tc.expected {
+				if dur, ok := result[key]; !ok || dur != expectedDuration {
+					t.Errorf("For key %s, expected %v, got %v", key, expectedDuration, dur)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateEndpoint(t *testing.T) {
+	a, reporter := newTestAgent(t, 2, 0)
+	a.start()
+
+	mux := http.NewServeMux()
+	a.routes(mux)
+
+	body := `{"id":1,"operation":"2+3","times":{"add_duration":0},"attempt":2}`
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/calculate", strings.NewReader(body)))
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	if expected := "Calculation started successfully.\n"; rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+
+	a.wg.Wait()
+	if got := reporter.outcomes(1); !slices.Equal(got, []string{"STARTED/2/0", "COMPLETED/2/5"}) {
+		t.Errorf("Unexpected reports %v", got)
+	}
+}
+
+func TestPerformCalculationAbortsAfterDeadline(t *testing.T) {
+	a, reporter := newTestAgent(t, 1, 0)
+	a.start()
+
+	_, err := a.PerformCalculation(context.Background(), &pb.CalculationRequest{
+		Id:        2,
+		Operation: "2+3",
+		Times:     map[string]int32{"add_duration": 60},
+		Deadline:  timestamppb.New(time.Now().Add(10 * time.Millisecond)),
+		Attempt:   1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	a.wg.Wait()
+	if got := reporter.outcomes(2); !slices.Equal(got, []string{"STARTED/1/0", "ABORTED/1/0"}) {
+		t.Errorf("Unexpected reports %v", got)
+	}
+	if reason := reporter.reports[1].Error; reason == "" {
+		t.Error("Expected the abort report to carry the reason")
+	}
+}
+
+func TestAgentCountsDeadlineFromStart(t *testing.T) {
+	a, reporter := newTestAgent(t, 1, 1)
+	if _, err := a.submit(4, 1, "1+1", map[string]int{"add_duration": 0}, time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatalf("Calculation was not queued: %v", err)
+	}
+
+	// Вычисление ждет в очереди дольше отведенного времени, но срок отсчитывается от начала выполнения
+	time.Sleep(100 * time.Millisecond)
+	a.start()
+
+	a.wg.Wait()
+	if got := reporter.outcomes(4); !slices.Equal(got, []string{"STARTED/1/0", "COMPLETED/1/2"}) {
+		t.Errorf("Unexpected reports %v", got)
+	}
+}
+
+func TestAgentQueuesBeyondConcurrency(t *testing.T) {
+	a, reporter := newTestAgent(t, 1, 2)
+	a.start()
+
+	for id := 1; id <= 3; id++ {
+		if _, err := a.submit(id, 1, "1+1", map[string]int{"add_duration": 0}, time.Time{}); err != nil {
+			t.Fatalf("Calculation %d was not queued: %v", id, err)
+		}
+	}
+
+	a.wg.Wait()
+	for id := int32(1); id <= 3; id++ {
+		if got := reporter.outcomes(id); !slices.Equal(got, []string{"STARTED/1/0", "COMPLETED/1/2"}) {
+			t.Errorf("Unexpected reports of calculation %d: %v", id, got)
+		}
+	}
+}
+
+func TestAgentRejectsWhenQueueIsFull(t *testing.T) {
+	a, _ := newTestAgent(t, 1, 1)
+	for id := 1; id <= 2; id++ {
+		if _, err := a.submit(id, 1, "1+1+1", map[string]int{"add_duration": 5}, time.Time{}); err != nil {
+			t.Fatalf("Calculation %d was not queued: %v", id, err)
+		}
+	}
+
+	// Исполнители не запущены: оба вычисления ждут в очереди, по 10 секунд каждое
+	_, err := a.PerformCalculation(context.Background(), &pb.CalculationRequest{Id: 3, Operation: "1+1"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted with a full queue, got %v", err)
+	}
+
+	mux := http.NewServeMux()
+	a.routes(mux)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/calculate", strings.NewReader(`{"id":3,"operation":"1+1"}`)))
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "20" {
+		t.Errorf("Expected status 429 with Retry-After 20, got %d and %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	var resp pingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unexpected response %s", rr.Body.String())
+	}
+	if resp.QueueDepth != 2 || resp.QueueSize != 1 || resp.EstimatedWaitSeconds != 20 {
+		t.Errorf("Unexpected queue status %+v", resp)
+	}
+}
+
+func TestAgentShutdown(t *testing.T) {
+	a, _ := newTestAgent(t, 1, 1)
+	a.start()
+
+	mux := http.NewServeMux()
+	a.routes(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/shutdown", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "Server is shutting down...\n" {
+		t.Errorf("Unexpected shutdown response %d: %s", rr.Code, rr.Body.String())
+	}
+
+	_, err := a.PerformCalculation(context.Background(), &pb.CalculationRequest{Id: 3, Operation: "1+1"})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable after shutdown, got %v", err)
+	}
+
+	// Остановка завершается, когда принятых вычислений не осталось
+	done := make(chan struct{})
+	go func() {
+		a.drain()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("drain did not return after all calculations finished")
+	}
+}
+
+func TestPingEndpoint(t *testing.T) {
+	a, _ := newTestAgent(t, 4, 10)
+	a.running[1] = time.Now().Add(time.Minute)
+
+	mux := http.NewServeMux()
+	a.routes(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	var resp pingResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Unexpected response %s", rr.Body.String())
+	}
+	if resp.Status != "running" || resp.AgentID != "agent-1" || resp.Labels["region"] != "eu" || resp.MaxGoroutines != 4 || resp.CurrentGoroutines != 1 {
+		t.Errorf("Unexpected ping response %+v", resp)
+	}
+	if resp.QueueDepth != 0 || resp.QueueSize != 10 || resp.EstimatedWaitSeconds != 0 {
+		t.Errorf("Expected an idle worker to be available, got %+v", resp)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/goroutines", nil))
+	if expected := "Current number of goroutines: 1\n"; rr.Body.String() != expected {
+		t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+	}
+}
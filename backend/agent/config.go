@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// config определяет настройки агента. Каждая настройка задается флагом командной строки,
+// а если флаг не указан - переменной окружения.
+type config struct {
+	HTTPAddr       string            // Адрес HTTP сервера (/calculate, /ping и др.)
+	GRPCAddr       string            // Адрес gRPC сервера CalculatorService
+	MaxConcurrency int               // Максимальное количество одновременно выполняемых вычислений
+	QueueSize      int               // Количество вычислений, которые могут ожидать свободного исполнителя
+	AgentID        string            // Идентификатор, которым агент подписывает выполненные вычисления
+	Labels         map[string]string // Произвольные метки агента, например region=eu
+	Orchestrator   string            // Адрес реестра агентов оркестратора; пустой адрес отключает регистрацию
+	Token          string            // Общий токен агентов, которым агент подтверждает доступ к реестру оркестратора
+	AdvertiseHost  string            // Хост, по которому оркестратор обращается к агенту, если в адресах он не указан
+	Pull           bool              // Агент сам запрашивает задачи у оркестратора, а не ждет их отправки
+}
+
+// loadConfig читает настройки агента из аргументов командной строки args и переменных окружения getenv.
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+	envOr := func(name, def string) string {
+		if value := getenv(name); value != "" {
+			return value
+		}
+		return def
+	}
+
+	pullDefault, err := strconv.ParseBool(envOr("CALCULATOR_AGENT_PULL", "false"))
+	if err != nil {
+		return config{}, fmt.Errorf("CALCULATOR_AGENT_PULL must be a boolean, got %q", getenv("CALCULATOR_AGENT_PULL"))
+	}
+
+	fs := flag.NewFlagSet("agent", flag.ContinueOnError)
+	httpAddr := fs.String("http", envOr("CALCULATOR_AGENT_HTTP_ADDR", ":8081"), "HTTP listen address (CALCULATOR_AGENT_HTTP_ADDR)")
+	grpcAddr := fs.String("grpc", envOr("CALCULATOR_AGENT_GRPC_ADDR", ":50051"), "gRPC listen address (CALCULATOR_AGENT_GRPC_ADDR)")
+	maxConcurrency := fs.String("max-concurrency", envOr("CALCULATOR_AGENT_MAX_CONCURRENCY", "5"), "maximum number of concurrent calculations (CALCULATOR_AGENT_MAX_CONCURRENCY)")
+	queueSize := fs.String("queue-size", envOr("CALCULATOR_AGENT_QUEUE_SIZE", "20"), "number of calculations waiting for a free worker (CALCULATOR_AGENT_QUEUE_SIZE)")
+	agentID := fs.String("id", getenv("CALCULATOR_AGENT_ID"), "agent ID, defaults to the host name and HTTP address (CALCULATOR_AGENT_ID)")
+	labels := fs.String("labels", getenv("CALCULATOR_AGENT_LABELS"), "comma-separated key=value labels (CALCULATOR_AGENT_LABELS)")
+	orchestrator := fs.String("orchestrator", envOr("CALCULATOR_ORCHESTRATOR_ADDR", "localhost:9091"), "orchestrator agent registry address, empty to disable registration (CALCULATOR_ORCHESTRATOR_ADDR)")
+	token := fs.String("token", getenv("CALCULATOR_AGENT_TOKEN"), "shared agent token required by the orchestrator agent registry (CALCULATOR_AGENT_TOKEN)")
+	advertiseHost := fs.String("advertise-host", envOr("CALCULATOR_AGENT_ADVERTISE_HOST", "localhost"), "host the orchestrator uses to reach the agent (CALCULATOR_AGENT_ADVERTISE_HOST)")
+	pull := fs.Bool("pull", pullDefault, "request tasks from the orchestrator instead of waiting for them, works behind NAT (CALCULATOR_AGENT_PULL)")
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+
+	cfg := config{HTTPAddr: *httpAddr, GRPCAddr: *grpcAddr, AgentID: *agentID, Orchestrator: *orchestrator, Token: *token, AdvertiseHost: *advertiseHost, Pull: *pull}
+
+	if cfg.MaxConcurrency, err = strconv.Atoi(*maxConcurrency); err != nil || cfg.MaxConcurrency < 1 {
+		return config{}, fmt.Errorf("max concurrency must be a positive integer, got %q", *maxConcurrency)
+	}
+	if cfg.QueueSize, err = strconv.Atoi(*queueSize); err != nil || cfg.QueueSize < 0 {
+		return config{}, fmt.Errorf("queue size must be a non-negative integer, got %q", *queueSize)
+	}
+	if cfg.Labels, err = parseLabels(*labels); err != nil {
+		return config{}, err
+	}
+	if cfg.Pull && cfg.Orchestrator == "" {
+		return config{}, fmt.Errorf("pull mode requires the orchestrator address")
+	}
+	if cfg.AgentID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "localhost"
+		}
+		cfg.AgentID = host + cfg.HTTPAddr
+	}
+	return cfg, nil
+}
+
+// parseLabels разбирает метки вида "key=value,key2=value2".
+func parseLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = strings.TrimSpace(val)
+	}
+	return labels, nil
+}
+
+// advertised возвращает адрес, по которому оркестратор обращается к слушающему на addr серверу агента.
+// Если хост в addr не указан, используется AdvertiseHost.
+func (c config) advertised(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = c.AdvertiseHost
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// String возвращает настройки в виде строки для журнала.
+func (c config) String() string {
+	keys := make([]string, 0, len(c.Labels))
+	for key := range c.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labels := make([]string, 0, len(keys))
+	for _, key := range keys {
+		labels = append(labels, key+"="+c.Labels[key])
+	}
+	mode := ""
+	if c.Pull {
+		mode = ", pull mode"
+	}
+	return fmt.Sprintf("agent %s (http %s, grpc %s, max concurrency %d, queue size %d, labels [%s]%s)",
+		c.AgentID, c.HTTPAddr, c.GRPCAddr, c.MaxConcurrency, c.QueueSize, strings.Join(labels, ","), mode)
+}
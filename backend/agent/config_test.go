@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	env := map[string]string{
+		"CALCULATOR_AGENT_HTTP_ADDR":       ":9081",
+		"CALCULATOR_AGENT_MAX_CONCURRENCY": "8",
+		"CALCULATOR_AGENT_QUEUE_SIZE":      "0",
+		"CALCULATOR_AGENT_ID":              "env-agent",
+		"CALCULATOR_AGENT_LABELS":          "region=eu, tier = fast",
+		"CALCULATOR_ORCHESTRATOR_ADDR":     "orchestrator:9091",
+		"CALCULATOR_AGENT_PULL":            "true",
+		"CALCULATOR_AGENT_TOKEN":           "env-secret",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	cfg, err := loadConfig(nil, getenv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.HTTPAddr != ":9081" || cfg.GRPCAddr != ":50051" || cfg.MaxConcurrency != 8 || cfg.QueueSize != 0 || cfg.AgentID != "env-agent" {
+		t.Errorf("Unexpected configuration from the environment: %+v", cfg)
+	}
+	if len(cfg.Labels) != 2 || cfg.Labels["region"] != "eu" || cfg.Labels["tier"] != "fast" {
+		t.Errorf("Unexpected labels %v", cfg.Labels)
+	}
+	if cfg.Orchestrator != "orchestrator:9091" || cfg.Token != "env-secret" || cfg.AdvertiseHost != "localhost" || !cfg.Pull {
+		t.Errorf("Unexpected registration settings %+v", cfg)
+	}
+
+	// Флаги имеют приоритет над переменными окружения
+	cfg, err = loadConfig([]string{"-http", ":8082", "-grpc", ":50052", "-max-concurrency", "2", "-queue-size", "4", "-id", "flag-agent", "-labels", "gpu=no", "-orchestrator", "", "-advertise-host", "agent.local", "-pull=false", "-token", "flag-secret"}, getenv)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.HTTPAddr != ":8082" || cfg.GRPCAddr != ":50052" || cfg.MaxConcurrency != 2 || cfg.QueueSize != 4 || cfg.AgentID != "flag-agent" || len(cfg.Labels) != 1 {
+		t.Errorf("Unexpected configuration from flags: %+v", cfg)
+	}
+	if cfg.Orchestrator != "" || cfg.Token != "flag-secret" || cfg.Pull || cfg.advertised(cfg.HTTPAddr) != "agent.local:8082" || cfg.advertised("10.0.0.5:50052") != "10.0.0.5:50052" {
+		t.Errorf("Unexpected advertised addresses for %+v", cfg)
+	}
+	if got := cfg.String(); got != "agent flag-agent (http :8082, grpc :50052, max concurrency 2, queue size 4, labels [gpu=no])" {
+		t.Errorf("Unexpected string %q", got)
+	}
+	cfg.Pull = true
+	if got := cfg.String(); !strings.HasSuffix(got, "labels [gpu=no], pull mode)") {
+		t.Errorf("Unexpected string %q", got)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := loadConfig(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.HTTPAddr != ":8081" || cfg.GRPCAddr != ":50051" || cfg.MaxConcurrency != 5 || cfg.QueueSize != 20 || len(cfg.Labels) != 0 || cfg.Orchestrator != "localhost:9091" || cfg.Pull {
+		t.Errorf("Unexpected default configuration: %+v", cfg)
+	}
+	if !strings.HasSuffix(cfg.AgentID, ":8081") {
+		t.Errorf("Expected the default agent ID to end with the HTTP address, got %q", cfg.AgentID)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := map[string][]string{
+		"zero concurrency":          {"-max-concurrency", "0"},
+		"invalid number":            {"-max-concurrency", "many"},
+		"negative queue":            {"-queue-size", "-1"},
+		"label without key":         {"-labels", "=eu"},
+		"label without pair":        {"-labels", "region"},
+		"unknown flag":              {"-port", "8081"},
+		"pull without orchestrator": {"-pull", "-orchestrator", ""},
+	}
+	for name, args := range tests {
+		if _, err := loadConfig(args, func(string) string { return "" }); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := loadConfig(nil, func(name string) string {
+		if name == "CALCULATOR_AGENT_PULL" {
+			return "sometimes"
+		}
+		return ""
+	}); err == nil {
+		t.Error("invalid pull mode: expected an error")
+	}
+}
@@ -0,0 +1,104 @@
+// Агент выполняет вычисления, которые ему отправляет оркестратор, и сообщает ему результаты.
+// В режиме pull агент сам запрашивает задачи у оркестратора, поэтому может работать за NAT.
+// При запуске агент регистрируется в реестре оркестратора и затем периодически сообщает, что активен.
+// Доступ к базе данных агенту не нужен.
+// Адреса, ограничение параллельности, идентификатор и метки задаются флагами или переменными окружения,
+// поэтому на одной машине можно запустить несколько агентов, например:
+//
+//	go run ./agent -http :8081 -grpc :50051
+//	go run ./agent -http :8082 -grpc :50052 -labels region=eu
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Оркестратор держит соединения с агентами открытыми и пингует их; пинги чаще раза в 20 секунд отклоняются
+var keepaliveEnforcement = keepalive.EnforcementPolicy{MinTime: 20 * time.Second, PermitWithoutStream: true}
+
+func main() {
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	log.Printf("Starting %s", cfg)
+
+	// Реестр агентов оркестратора принимает и сигналы активности, и отчеты о вычислениях
+	var (
+		registryClient pb.AgentRegistryServiceClient
+		reporter       resultReporter // Остается nil без оркестратора
+	)
+	if cfg.Orchestrator != "" {
+		conn, err := grpc.NewClient(cfg.Orchestrator,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithPerRPCCredentials(agentCredentials(cfg.Token)))
+		if err != nil {
+			log.Fatalf("failed to connect to the orchestrator: %v", err)
+		}
+		registryClient = pb.NewAgentRegistryServiceClient(conn)
+		reporter = registryClient
+	}
+	a := newAgent(cfg, reporter)
+	a.start()
+
+	lis, err := net.Listen("tcp", cfg.GRPCAddr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.KeepaliveEnforcementPolicy(keepaliveEnforcement))
+	pb.RegisterCalculatorServiceServer(grpcServer, a)
+
+	// Проверка состояния по протоколу grpc.health.v1: после запроса на остановку агент отвечает NOT_SERVING
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.CalculatorService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	fmt.Printf("gRPC server is starting on %s...\n", cfg.GRPCAddr)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			log.Fatalf("failed to serve: %v", err)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	a.routes(mux)
+
+	// Регистрация в оркестраторе и сигналы активности; после запроса на остановку агент удаляется из реестра
+	announced := make(chan struct{})
+	if registryClient != nil {
+		go func() {
+			a.announce(registryClient)
+			close(announced)
+		}()
+	} else {
+		close(announced)
+	}
+	if cfg.Pull {
+		go a.pull(registryClient)
+	}
+
+	go func() {
+		<-a.stopped
+		healthServer.Shutdown()
+		a.drain()
+		<-announced
+		grpcServer.Stop()
+		log.Println("Server gracefully shut down")
+		os.Exit(0)
+	}()
+
+	fmt.Printf("Calculator server is starting on %s...\n", cfg.HTTPAddr)
+	log.Fatal(http.ListenAndServe(cfg.HTTPAddr, mux))
+}
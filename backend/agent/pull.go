@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Время, которое оркестратор держит запрос задач, если очередь пуста
+const pullWaitSeconds = 20
+
+// Пауза перед повторным запросом задач после ошибки
+var pullRetryInterval = time.Second
+
+// taskSource выдает агенту задачи из очереди оркестратора. Реализуется pb.AgentRegistryServiceClient.
+type taskSource interface {
+	GetTask(ctx context.Context, in *pb.GetTaskRequest, opts ...grpc.CallOption) (*pb.GetTaskResponse, error)
+}
+
+// freeSlots возвращает количество вычислений, которое агент может принять: свободные исполнители и места в очереди.
+func (a *agent) freeSlots() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return max(a.cfg.MaxConcurrency+a.cfg.QueueSize-a.queued-len(a.running), 0)
+}
+
+// pull запрашивает у оркестратора задачи, пока у агента есть свободное место, до запроса на остановку.
+// Оркестратор арендует выданные задачи за агентом; результаты передаются так же, как в режиме push, через report.
+// Если оркестратор еще не знает агента, запрос повторяется после регистрации в announce.
+func (a *agent) pull(source taskSource) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-a.stopped:
+			cancel() // Прерывает ожидающий запрос
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		free := a.freeSlots()
+		if free == 0 {
+			select {
+			case <-a.freed:
+				continue
+			case <-a.stopped:
+				return
+			}
+		}
+
+		callCtx, callCancel := context.WithTimeout(ctx, pullWaitSeconds*time.Second+registryCallTimeout)
+		resp, err := source.GetTask(callCtx, &pb.GetTaskRequest{AgentId: a.cfg.AgentID, MaxTasks: int32(free), WaitSeconds: pullWaitSeconds})
+		callCancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if status.Code(err) != codes.NotFound {
+				log.Printf("Failed to request tasks from the orchestrator: %v", err)
+			}
+			select {
+			case <-time.After(pullRetryInterval):
+				continue
+			case <-a.stopped:
+				return
+			}
+		}
+
+		for _, req := range resp.Tasks {
+			var deadline time.Time
+			if req.Deadline != nil {
+				deadline = req.Deadline.AsTime()
+			}
+			if _, err := a.submit(int(req.Id), int(req.Attempt), req.Operation, convertToIntMap(req.Times), deadline); err != nil {
+				// Место заняли вычисления, принятые по HTTP, или агент останавливается: по отчету ABORTED оркестратор
+				// сразу возвращает вычисление в очередь, и эта попытка не расходуется
+				t := task{id: int(req.Id), attempt: int(req.Attempt)}
+				a.report(t, pb.ReportResultRequest_ABORTED, 0, err.Error(), 0)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTaskSource - оркестратор, отвечающий на запросы задач по очереди ответами responses.
+// Когда ответы заканчиваются, запрос ожидает отмены.
+type fakeTaskSource struct {
+	mu        sync.Mutex
+	requests  []*pb.GetTaskRequest
+	responses []*pb.GetTaskResponse // nil означает, что агент еще не зарегистрирован
+}
+
+func (s *fakeTaskSource) GetTask(ctx context.Context, req *pb.GetTaskRequest, opts ...grpc.CallOption) (*pb.GetTaskResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if len(s.responses) == 0 {
+		s.mu.Unlock()
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	s.mu.Unlock()
+	if resp == nil {
+		return nil, status.Error(codes.NotFound, "agent is not registered")
+	}
+	return resp, nil
+}
+
+func TestPull(t *testing.T) {
+	old := pullRetryInterval
+	t.Cleanup(func() { pullRetryInterval = old })
+	pullRetryInterval = time.Millisecond
+
+	a, reporter := newTestAgent(t, 1, 1)
+	a.start()
+	times := map[string]int32{"add_duration": 0}
+	source := &fakeTaskSource{responses: []*pb.GetTaskResponse{
+		nil,
+		{Tasks: []*pb.CalculationRequest{{Id: 1, Operation: "1+1", Times: times, Attempt: 2}, {Id: 2, Operation: "2+2", Times: times, Attempt: 1}}},
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		a.pull(source)
+		close(done)
+	}()
+
+	// Выданные задачи выполняются, и агент снова запрашивает задачи на освободившиеся места
+	deadline := time.Now().Add(2 * time.Second)
+	for len(reporter.outcomes(2)) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := reporter.outcomes(1); !slices.Equal(got, []string{"STARTED/2/0", "COMPLETED/2/2"}) {
+		t.Errorf("Unexpected reports of calculation 1: %v", got)
+	}
+	if got := reporter.outcomes(2); !slices.Equal(got, []string{"STARTED/1/0", "COMPLETED/1/4"}) {
+		t.Errorf("Unexpected reports of calculation 2: %v", got)
+	}
+
+	// Запрос на остановку прерывает ожидающий запрос задач
+	mux := http.NewServeMux()
+	a.routes(mux)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/shutdown", nil))
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("pull did not return after shutdown")
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if len(source.requests) < 3 {
+		t.Fatalf("Expected the agent to keep requesting tasks, got %d requests", len(source.requests))
+	}
+	for _, req := range source.requests[:2] {
+		if req.AgentId != "agent-1" || req.MaxTasks != 2 || req.WaitSeconds != pullWaitSeconds {
+			t.Errorf("Unexpected task request %+v", req)
+		}
+	}
+}
+
+func TestPullWaitsForFreeSlot(t *testing.T) {
+	a, _ := newTestAgent(t, 1, 0)
+	if _, err := a.submit(1, 1, "1+1", map[string]int{"add_duration": 0}, time.Time{}); err != nil {
+		t.Fatalf("Calculation was not queued: %v", err)
+	}
+	source := &fakeTaskSource{}
+
+	done := make(chan struct{})
+	go func() {
+		a.pull(source)
+		close(done)
+	}()
+
+	// Исполнители не запущены, мест нет, поэтому задачи не запрашиваются
+	time.Sleep(50 * time.Millisecond)
+	source.mu.Lock()
+	requested := len(source.requests)
+	source.mu.Unlock()
+	if requested != 0 {
+		t.Errorf("Expected no task requests without free slots, got %d", requested)
+	}
+
+	close(a.stopped)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pull did not return after shutdown")
+	}
+}
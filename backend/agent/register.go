@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	registerRetryInterval = 5 * time.Second // Пауза перед повторной регистрацией, если оркестратор недоступен
+	registryCallTimeout   = 5 * time.Second // Время ожидания ответа реестра агентов
+)
+
+// agentCredentials передает реестру оркестратора общий токен агентов в метаданных authorization каждого вызова.
+type agentCredentials string
+
+func (c agentCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(c)}, nil
+}
+
+// RequireTransportSecurity разрешает передавать токен по соединению без TLS, которым агент подключается к оркестратору.
+func (c agentCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// announce регистрирует агента в реестре оркестратора и отправляет сигналы активности до запроса на остановку,
+// после которого удаляет агента из реестра, чтобы оркестратор перестал отправлять ему вычисления.
+// Если оркестратор не знает агента, например после своего перезапуска, агент регистрируется заново.
+func (a *agent) announce(client pb.AgentRegistryServiceClient) {
+	interval := registerRetryInterval
+	registered := false
+	for {
+		if !registered {
+			if resp, err := a.register(client); err != nil {
+				log.Printf("Failed to register with the orchestrator: %v", err)
+			} else {
+				registered = true
+				if resp.HeartbeatIntervalSeconds > 0 {
+					interval = time.Duration(resp.HeartbeatIntervalSeconds) * time.Second
+				}
+			}
+		} else if err := a.heartbeat(client); status.Code(err) == codes.NotFound {
+			log.Printf("Orchestrator does not know agent %s, registering again", a.cfg.AgentID)
+			registered = false
+			continue
+		} else if err != nil {
+			log.Printf("Failed to send heartbeat: %v", err)
+		}
+
+		select {
+		case <-a.stopped:
+			a.deregister(client)
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// register отправляет реестру сведения об агенте.
+func (a *agent) register(client pb.AgentRegistryServiceClient) (*pb.RegisterResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryCallTimeout)
+	defer cancel()
+	return client.Register(ctx, &pb.RegisterRequest{
+		AgentId:        a.cfg.AgentID,
+		HttpUrl:        "http://" + a.cfg.advertised(a.cfg.HTTPAddr),
+		GrpcAddr:       a.cfg.advertised(a.cfg.GRPCAddr),
+		MaxConcurrency: int32(a.cfg.MaxConcurrency),
+		QueueSize:      int32(a.cfg.QueueSize),
+		Labels:         a.cfg.Labels,
+		Version:        buildVersion(),
+		Pull:           a.cfg.Pull,
+	})
+}
+
+// heartbeat сообщает реестру, что агент активен, и передает его текущую загрузку.
+func (a *agent) heartbeat(client pb.AgentRegistryServiceClient) error {
+	a.mu.Lock()
+	req := &pb.HeartbeatRequest{AgentId: a.cfg.AgentID, Running: int32(len(a.running)), QueueDepth: int32(a.queued)}
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryCallTimeout)
+	defer cancel()
+	_, err := client.Heartbeat(ctx, req)
+	return err
+}
+
+// deregister удаляет агента из реестра.
+func (a *agent) deregister(client pb.AgentRegistryServiceClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryCallTimeout)
+	defer cancel()
+	if _, err := client.Deregister(ctx, &pb.DeregisterRequest{AgentId: a.cfg.AgentID}); err != nil {
+		log.Printf("Failed to deregister from the orchestrator: %v", err)
+	}
+}
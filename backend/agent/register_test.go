@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeRegistry - реестр агентов, который забывает агента после первого сигнала активности.
+type fakeRegistry struct {
+	pb.UnimplementedAgentRegistryServiceServer
+	calls      chan string
+	registered []*pb.RegisterRequest
+	tokens     []string // Метаданные authorization вызовов Register
+	forgotten  bool
+}
+
+func (r *fakeRegistry) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	r.registered = append(r.registered, req)
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.tokens = append(r.tokens, md.Get("authorization")...)
+	r.calls <- "register"
+	return &pb.RegisterResponse{HeartbeatIntervalSeconds: 1}, nil
+}
+
+func (r *fakeRegistry) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	r.calls <- "heartbeat"
+	if !r.forgotten {
+		r.forgotten = true
+		return nil, status.Error(codes.NotFound, "agent is not registered")
+	}
+	return &pb.HeartbeatResponse{}, nil
+}
+
+func (r *fakeRegistry) Deregister(ctx context.Context, req *pb.DeregisterRequest) (*pb.DeregisterResponse, error) {
+	r.calls <- "deregister"
+	return &pb.DeregisterResponse{}, nil
+}
+
+func TestAnnounce(t *testing.T) {
+	a, _ := newTestAgent(t, 2, 5)
+	a.cfg.HTTPAddr, a.cfg.GRPCAddr, a.cfg.AdvertiseHost = ":8081", ":50051", "agent.local"
+
+	registry := &fakeRegistry{calls: make(chan string, 10)}
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	pb.RegisterAgentRegistryServiceServer(server, registry)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(agentCredentials("agent-secret")))
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	done := make(chan struct{})
+	go func() {
+		a.announce(pb.NewAgentRegistryServiceClient(conn))
+		close(done)
+	}()
+
+	// Реестр забыл агента после первого сигнала активности, поэтому агент регистрируется повторно
+	for _, expected := range []string{"register", "heartbeat", "register"} {
+		select {
+		case call := <-registry.calls:
+			if call != expected {
+				t.Fatalf("Expected %s, got %s", expected, call)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for %s", expected)
+		}
+	}
+
+	mux := http.NewServeMux()
+	a.routes(mux)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/shutdown", nil))
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("announce did not return after shutdown")
+	}
+	if call := <-registry.calls; call != "deregister" {
+		t.Errorf("Expected the agent to deregister on shutdown, got %s", call)
+	}
+
+	req := registry.registered[0]
+	if req.AgentId != "agent-1" || req.HttpUrl != "http://agent.local:8081" || req.GrpcAddr != "agent.local:50051" ||
+		req.MaxConcurrency != 2 || req.QueueSize != 5 || req.Labels["region"] != "eu" || req.Version == "" || req.Pull {
+		t.Errorf("Unexpected registration %+v", req)
+	}
+	if len(registry.tokens) == 0 || registry.tokens[0] != "Bearer agent-secret" {
+		t.Errorf("Expected the agent token in the registration metadata, got %v", registry.tokens)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	reportRetries     = 5               // Количество повторов отчета о завершении, пока оркестратор недоступен
+	reportCallTimeout = 5 * time.Second // Время ожидания ответа оркестратора на отчет
+)
+
+// Пауза перед первым повтором отчета, каждый следующий повтор ждет вдвое дольше
+var reportRetryInterval = time.Second
+
+// resultReporter передает оркестратору отчеты о вычислениях. Реализуется pb.AgentRegistryServiceClient.
+type resultReporter interface {
+	ReportResult(ctx context.Context, in *pb.ReportResultRequest, opts ...grpc.CallOption) (*pb.ReportResultResponse, error)
+}
+
+// report отправляет оркестратору отчет о попытке t и возвращает true, если отчет принят.
+// Пока оркестратор недоступен, отчет повторяется до retries раз. Отклоненный оркестратором отчет
+// не повторяется: попытка уже не назначена агенту. Без оркестратора отчет только записывается в журнал.
+func (a *agent) report(t task, outcome pb.ReportResultRequest_Outcome, result float64, reason string, retries int) bool {
+	if a.reporter == nil {
+		log.Printf("Calculation ID %d, attempt %d: %v (no orchestrator to report to)", t.id, t.attempt, outcome)
+		return true
+	}
+	req := &pb.ReportResultRequest{
+		AgentId:       a.cfg.AgentID,
+		CalculationId: int32(t.id),
+		Attempt:       int32(t.attempt),
+		Outcome:       outcome,
+		Result:        result,
+		Error:         reason,
+	}
+
+	wait := reportRetryInterval
+	for try := 0; ; try++ {
+		ctx, cancel := context.WithTimeout(context.Background(), reportCallTimeout)
+		_, err := a.reporter.ReportResult(ctx, req)
+		cancel()
+		switch status.Code(err) {
+		case codes.OK:
+			return true
+		case codes.Unavailable, codes.DeadlineExceeded:
+			if try < retries {
+				log.Printf("Failed to report calculation ID %d, retrying in %v: %v", t.id, wait, err)
+				time.Sleep(wait)
+				wait *= 2
+				continue
+			}
+		}
+		log.Printf("Report %v of calculation ID %d, attempt %d was not accepted: %v", outcome, t.id, t.attempt, err)
+		return false
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+)
+
+func TestReport(t *testing.T) {
+	old := reportRetryInterval
+	t.Cleanup(func() { reportRetryInterval = old })
+	reportRetryInterval = time.Millisecond
+
+	a, reporter := newTestAgent(t, 1, 0)
+
+	// Пока оркестратор недоступен, отчет о завершении повторяется
+	reporter.unavailable = 2
+	if !a.report(task{id: 1, attempt: 3}, pb.ReportResultRequest_COMPLETED, 4, "", reportRetries) {
+		t.Error("Expected the report to be accepted after retries")
+	}
+	if got := len(reporter.outcomes(1)); got != 3 {
+		t.Errorf("Expected 3 report attempts, got %d", got)
+	}
+
+	// Отчет о начале выполнения не повторяется
+	reporter.unavailable = 1
+	if a.report(task{id: 2, attempt: 1}, pb.ReportResultRequest_STARTED, 0, "", 0) {
+		t.Error("Expected the report to fail while the orchestrator is down")
+	}
+
+	// Отклоненный отчет не повторяется
+	reporter.rejected[3] = true
+	if a.report(task{id: 3, attempt: 1}, pb.ReportResultRequest_COMPLETED, 4, "", reportRetries) {
+		t.Error("Expected a stale report to be rejected")
+	}
+	if got := len(reporter.outcomes(3)); got != 1 {
+		t.Errorf("Expected a rejected report not to be retried, got %d attempts", got)
+	}
+
+	// Без оркестратора результат только записывается в журнал
+	standalone := newAgent(config{MaxConcurrency: 1, AgentID: "agent-2"}, nil)
+	if !standalone.report(task{id: 4, attempt: 1}, pb.ReportResultRequest_COMPLETED, 4, "", reportRetries) {
+		t.Error("Expected a standalone agent to accept its own result")
+	}
+}
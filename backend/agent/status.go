@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+)
+
+// version - версия сборки агента, задается при сборке:
+//
+//	go build -ldflags "-X main.version=1.2.0" ./agent
+//
+// Если версия не задана, используется ревизия из сведений о сборке Go.
+var version = ""
+
+// buildVersion возвращает версию сборки агента.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+		if info.Main.Version != "" {
+			return info.Main.Version
+		}
+	}
+	return "dev"
+}
+
+// runtimeStats возвращает состояние среды выполнения Go.
+func runtimeStats() *pb.RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return &pb.RuntimeStats{
+		GoVersion:      runtime.Version(),
+		NumCpu:         int32(runtime.NumCPU()),
+		Goroutines:     int32(runtime.NumGoroutine()),
+		HeapAllocBytes: mem.HeapAlloc,
+		SysBytes:       mem.Sys,
+		NumGc:          mem.NumGC,
+	}
+}
+
+// CheckStatus сообщает оркестратору состояние агента: загрузку, очередь, счетчики вычислений и среду выполнения.
+func (a *agent) CheckStatus(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	now := time.Now()
+	a.mu.Lock()
+	response := &pb.StatusResponse{
+		Running:              a.accepting,
+		MaxGoroutines:        int32(a.cfg.MaxConcurrency),
+		CurrentGoroutines:    int32(len(a.running)),
+		AgentId:              a.cfg.AgentID,
+		Version:              buildVersion(),
+		UptimeSeconds:        int64(now.Sub(a.started).Seconds()),
+		Completed:            a.completed,
+		Failed:               a.failed,
+		QueueDepth:           int32(a.queued),
+		QueueSize:            int32(a.cfg.QueueSize),
+		EstimatedWaitSeconds: a.estimatedWaitLocked(now).Seconds(),
+	}
+	a.mu.Unlock()
+
+	response.Runtime = runtimeStats()
+	return response, nil
+}
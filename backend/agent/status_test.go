@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+)
+
+func TestCheckStatus(t *testing.T) {
+	a, reporter := newTestAgent(t, 1, 3)
+	a.start()
+	reporter.rejected[2] = true // Попытка вычисления 2 переназначена, результат не принимается
+
+	for id, operation := range map[int]string{1: "1+1", 2: "2+2"} {
+		if _, err := a.submit(id, 1, operation, nil, time.Time{}); err != nil {
+			t.Fatalf("Calculation %d was not queued: %v", id, err)
+		}
+	}
+	a.wg.Wait()
+
+	resp, err := a.CheckStatus(context.Background(), &pb.StatusRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.Running || resp.AgentId != "agent-1" || resp.MaxGoroutines != 1 || resp.QueueSize != 3 || resp.QueueDepth != 0 {
+		t.Errorf("Unexpected status %+v", resp)
+	}
+	if resp.Completed != 1 || resp.Failed != 1 {
+		t.Errorf("Expected 1 completed and 1 failed calculation, got %d and %d", resp.Completed, resp.Failed)
+	}
+	if resp.Version == "" || resp.Runtime == nil || resp.Runtime.GoVersion == "" || resp.Runtime.Goroutines < 1 {
+		t.Errorf("Expected version and runtime stats, got %q and %+v", resp.Version, resp.Runtime)
+	}
+
+	// После запроса на остановку агент не принимает вычисления
+	a.accepting = false
+	if resp, _ := a.CheckStatus(context.Background(), &pb.StatusRequest{}); resp.Running {
+		t.Error("Expected the agent to report that it is not running after shutdown")
+	}
+}
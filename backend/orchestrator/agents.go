@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Пауза для агента, отклонившего вычисление из-за заполненной очереди без подсказки retry-after
+const defaultAgentRetryAfter = 5 * time.Second
+
+// agentBackoff запоминает агентов с заполненной очередью, чтобы не отправлять им вычисления
+// до истечения времени, которое агент указал в заголовке retry-after.
+type agentBackoff struct {
+	mu    sync.Mutex
+	until map[string]time.Time // Адрес агента -> время, до которого ему не отправляются вычисления
+}
+
+// Агенты, временно не принимающие вычисления
+var busyAgents = newAgentBackoff()
+
+func newAgentBackoff() *agentBackoff {
+	return &agentBackoff{until: make(map[string]time.Time)}
+}
+
+// hold откладывает отправку вычислений агенту serverURL до момента until.
+func (b *agentBackoff) hold(serverURL string, until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.until[serverURL] = until
+}
+
+// busy сообщает, отложена ли отправка вычислений агенту serverURL на момент now.
+func (b *agentBackoff) busy(serverURL string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.until[serverURL]
+	if ok && !now.Before(until) {
+		delete(b.until, serverURL)
+		return false
+	}
+	return ok
+}
+
+// agentRetryAfter читает из заголовков ответа агента время, через которое стоит повторить отправку.
+func agentRetryAfter(header metadata.MD) time.Duration {
+	if values := header.Get("retry-after"); len(values) > 0 {
+		if seconds, err := strconv.Atoi(values[0]); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultAgentRetryAfter
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fullAgent - агент, очередь которого всегда заполнена.
+type fullAgent struct {
+	pb.UnimplementedCalculatorServiceServer
+	retryAfter string
+}
+
+func (a fullAgent) PerformCalculation(ctx context.Context, req *pb.CalculationRequest) (*pb.CalculationResponse, error) {
+	if a.retryAfter != "" {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", a.retryAfter))
+	}
+	return nil, status.Error(codes.ResourceExhausted, "Server queue is full")
+}
+
+// acceptingAgent - агент, принимающий все вычисления и передающий их в received.
+type acceptingAgent struct {
+	pb.UnimplementedCalculatorServiceServer
+	received chan<- *pb.CalculationRequest
+}
+
+func (a acceptingAgent) PerformCalculation(ctx context.Context, req *pb.CalculationRequest) (*pb.CalculationResponse, error) {
+	a.received <- req
+	return &pb.CalculationResponse{Id: req.Id}, nil
+}
+
+// statusAgent - агент, отвечающий на проверку статуса через delay.
+type statusAgent struct {
+	pb.UnimplementedCalculatorServiceServer
+	status *pb.StatusResponse
+	delay  time.Duration
+}
+
+func (a statusAgent) CheckStatus(ctx context.Context, req *pb.StatusRequest) (*pb.StatusResponse, error) {
+	select {
+	case <-time.After(a.delay):
+		return a.status, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startFakeAgent запускает gRPC сервер агента и возвращает его адрес.
+func startFakeAgent(t *testing.T, agent pb.CalculatorServiceServer) string {
+	t.Helper()
+	addr, _ := startFakeAgentWithHealth(t, agent)
+	return addr
+}
+
+// startFakeAgentWithHealth запускает gRPC сервер агента, отвечающий SERVING на проверку состояния,
+// и возвращает его адрес и сервер проверки состояния.
+func startFakeAgentWithHealth(t *testing.T, agent pb.CalculatorServiceServer) (string, *health.Server) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	pb.RegisterCalculatorServiceServer(server, agent)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.CalculatorService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String(), healthServer
+}
+
+func TestStartCalculationGRPCQueueFull(t *testing.T) {
+	withRegistry(t)
+	addr := startFakeAgent(t, fullAgent{retryAfter: "20"})
+	started, retryAfter := startCalculationGRPC(addr, &pb.CalculationRequest{Id: 1, Operation: "1+1"})
+	if started || retryAfter != 20*time.Second {
+		t.Errorf("Expected rejection with a 20s retry hint, got %v, %v", started, retryAfter)
+	}
+
+	addr = startFakeAgent(t, fullAgent{})
+	if _, retryAfter := startCalculationGRPC(addr, &pb.CalculationRequest{Id: 1, Operation: "1+1"}); retryAfter != defaultAgentRetryAfter {
+		t.Errorf("Expected the default retry hint without a header, got %v", retryAfter)
+	}
+}
+
+func TestAgentBackoff(t *testing.T) {
+	backoff := newAgentBackoff()
+	now := time.Now()
+
+	backoff.hold("http://localhost:8081", now.Add(20*time.Second))
+	if !backoff.busy("http://localhost:8081", now) {
+		t.Error("Expected the agent to be busy before the retry time")
+	}
+	if backoff.busy("http://localhost:8082", now) {
+		t.Error("Another agent is busy")
+	}
+	if backoff.busy("http://localhost:8081", now.Add(20*time.Second)) {
+		t.Error("Expected the agent to accept calculations after the retry time")
+	}
+}
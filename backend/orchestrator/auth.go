@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// parseToken проверяет подпись JWT токена и возвращает его утверждения.
+func parseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// authenticateRequest извлекает JWT токен из заголовка Authorization ("Bearer <token>")
+// или из параметра запроса token (EventSource в браузере не умеет передавать заголовки).
+func authenticateRequest(r *http.Request) (*Claims, error) {
+	tokenString := ""
+	if header := r.Header.Get("Authorization"); header != "" {
+		tokenString = strings.TrimPrefix(header, "Bearer ")
+	} else {
+		tokenString = r.URL.Query().Get("token")
+	}
+
+	if tokenString == "" {
+		return nil, errors.New("missing token")
+	}
+
+	return parseToken(tokenString)
+}
+
+// Логины администраторов, задаются через запятую в переменной окружения CALCULATOR_ADMINS
+var adminLogins = parseAdminLogins(os.Getenv("CALCULATOR_ADMINS"))
+
+// parseAdminLogins разбирает список логинов, разделенных запятыми.
+func parseAdminLogins(value string) map[string]bool {
+	logins := make(map[string]bool)
+	for _, login := range strings.Split(value, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			logins[login] = true
+		}
+	}
+	return logins
+}
+
+// isAdmin сообщает, является ли владелец токена администратором.
+func isAdmin(claims *Claims) bool {
+	return claims != nil && adminLogins[claims.Login]
+}
+
+// authenticateAdmin проверяет, что запрос отправлен администратором.
+// Иначе сам отвечает 401 или 403 и возвращает false.
+func authenticateAdmin(w http.ResponseWriter, r *http.Request) (*Claims, bool) {
+	claims, err := authenticateRequest(r)
+	if err != nil {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if !isAdmin(claims) {
+		sendJSONError(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return claims, true
+}
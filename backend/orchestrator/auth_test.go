@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// newTestToken подписывает JWT токен пользователя так же, как обработчик /api/v1/login.
+func newTestToken(t *testing.T, userID int) string {
+	t.Helper()
+	claims := &Claims{
+		Login:          "tester",
+		UserID:         userID,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtKey)
+	if err != nil {
+		t.Fatalf("Failed to sign test token: %v", err)
+	}
+	return token
+}
+
+func TestAuthenticateRequest(t *testing.T) {
+	token := newTestToken(t, 42)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	claims, err := authenticateRequest(req)
+	if err != nil || claims.UserID != 42 {
+		t.Errorf("Expected user 42 from header, got %v, %v", claims, err)
+	}
+
+	req = httptest.NewRequest("GET", "/?token="+token, nil)
+	if claims, err = authenticateRequest(req); err != nil || claims.UserID != 42 {
+		t.Errorf("Expected user 42 from query, got %v, %v", claims, err)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer garbage")
+	if _, err = authenticateRequest(req); err == nil {
+		t.Error("Expected invalid token to be rejected")
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const (
+	maxBatchSize     = 1000     // Максимальное количество вычислений в одном пакете
+	maxBatchBodySize = 10 << 20 // Максимальный размер тела запроса с пакетом
+)
+
+// Поддерживаемые форматы загрузки пакета
+const (
+	batchFormatJSON   = "json"
+	batchFormatNDJSON = "ndjson"
+	batchFormatCSV    = "csv"
+)
+
+// Элемент пакета в формате JSON и NDJSON, поля совпадают с CalculationRequest.
+// Незаданные длительности берутся из настроек пользователя.
+type batchItem struct {
+	Operation          string `json:"operation"`
+	AddDuration        *int   `json:"add_duration"`
+	SubtractDuration   *int   `json:"subtract_duration"`
+	MultiplyDuration   *int   `json:"multiply_duration"`
+	DivideDuration     *int   `json:"divide_duration"`
+	InactiveServerTime *int   `json:"inactive_server_time"`
+	Priority           *int   `json:"priority"`
+}
+
+func (item batchItem) toRequest(settings models.UserSettings) models.CalculationRequest {
+	return CalculationRequest{
+		UserId:             settings.UserId,
+		Operation:          item.Operation,
+		AddDuration:        item.AddDuration,
+		SubtractDuration:   item.SubtractDuration,
+		MultiplyDuration:   item.MultiplyDuration,
+		DivideDuration:     item.DivideDuration,
+		InactiveServerTime: item.InactiveServerTime,
+		Priority:           item.Priority,
+	}.withDefaults(settings)
+}
+
+// validate проверяет элемент пакета; n - номер элемента (строки) для сообщения об ошибке.
+func (item batchItem) validate(n int) error {
+	if strings.TrimSpace(item.Operation) == "" {
+		return fmt.Errorf("item %d: operation is required", n)
+	}
+	for _, duration := range []*int{item.AddDuration, item.SubtractDuration, item.MultiplyDuration, item.DivideDuration, item.InactiveServerTime} {
+		if duration != nil && *duration < 0 {
+			return fmt.Errorf("item %d: durations must not be negative", n)
+		}
+	}
+	if item.Priority != nil {
+		if err := validatePriority(*item.Priority); err != nil {
+			return fmt.Errorf("item %d: %w", n, err)
+		}
+	}
+	return validateExpression(item.Operation, n)
+}
+
+// batchFormat определяет формат пакета по Content-Type или расширению файла.
+func batchFormat(contentType, filename string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return batchFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return batchFormatNDJSON
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return batchFormatCSV
+	case ".ndjson", ".jsonl":
+		return batchFormatNDJSON
+	}
+	return batchFormatJSON
+}
+
+// parseBatchJSON разбирает JSON массив объектов.
+func parseBatchJSON(r io.Reader) ([]batchItem, error) {
+	var items []batchItem
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid JSON array: %v", err)
+	}
+	return items, nil
+}
+
+// parseBatchNDJSON разбирает поток JSON объектов, по одному на строку. Пустые строки пропускаются.
+func parseBatchNDJSON(r io.Reader) ([]batchItem, error) {
+	var items []batchItem
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBatchBodySize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var item batchItem
+		if err := json.Unmarshal(text, &item); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %v", line, err)
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// parseBatchCSV разбирает CSV с заголовком. Обязателен столбец operation,
+// остальные столбцы называются так же, как поля JSON и могут отсутствовать.
+func parseBatchCSV(r io.Reader) ([]batchItem, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "operation", "add_duration", "subtract_duration", "multiply_duration", "divide_duration", "inactive_server_time", "priority":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+	}
+	if _, ok := columns["operation"]; !ok {
+		return nil, errors.New("CSV header must contain an operation column")
+	}
+
+	var items []batchItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		// Пустая ячейка или отсутствующий столбец означают значение из настроек пользователя
+		intColumn := func(name string) (*int, error) {
+			i, ok := columns[name]
+			if !ok || strings.TrimSpace(record[i]) == "" {
+				return nil, nil
+			}
+			value, err := strconv.Atoi(strings.TrimSpace(record[i]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, name, record[i])
+			}
+			return &value, nil
+		}
+
+		item := batchItem{Operation: strings.TrimSpace(record[columns["operation"]])}
+		for name, field := range map[string]**int{
+			"add_duration":         &item.AddDuration,
+			"subtract_duration":    &item.SubtractDuration,
+			"multiply_duration":    &item.MultiplyDuration,
+			"divide_duration":      &item.DivideDuration,
+			"inactive_server_time": &item.InactiveServerTime,
+			"priority":             &item.Priority,
+		} {
+			if *field, err = intColumn(name); err != nil {
+				return nil, err
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// parseBatch читает пакет из тела запроса или из файла "file" в multipart/form-data.
+func parseBatch(r *http.Request) ([]batchItem, error) {
+	body := io.Reader(r.Body)
+	format := batchFormat(r.Header.Get("Content-Type"), "")
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("missing file field: %v", err)
+		}
+		defer file.Close()
+		body = file
+		format = batchFormat(header.Header.Get("Content-Type"), header.Filename)
+	}
+
+	var (
+		items []batchItem
+		err   error
+	)
+	switch format {
+	case batchFormatCSV:
+		items, err = parseBatchCSV(body)
+	case batchFormatNDJSON:
+		items, err = parseBatchNDJSON(body)
+	default:
+		items, err = parseBatchJSON(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("batch is empty")
+	}
+	if len(items) > maxBatchSize {
+		return nil, fmt.Errorf("batch exceeds %d calculations", maxBatchSize)
+	}
+	for i, item := range items {
+		if err := item.validate(i + 1); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// summarizeBatch считает сводный статус пакета по его вычислениям.
+func summarizeBatch(batch models.Batch, calculations []models.OperationResponse) models.BatchStatus {
+	status := models.BatchStatus{
+		Batch:   batch,
+		Counts:  make(map[string]int),
+		Results: calculations,
+	}
+	if status.Results == nil {
+		status.Results = []models.OperationResponse{}
+	}
+
+	for _, calc := range calculations {
+		status.Counts[calc.Status]++
+		if isTerminalStatus(calc.Status) {
+			status.Finished++
+		}
+	}
+	if batch.Total > 0 {
+		status.Progress = float64(status.Finished) / float64(batch.Total)
+	}
+	return status
+}
+
+// batchesHandler обрабатывает POST /api/v1/batches: создает пакет вычислений из JSON массива,
+// NDJSON или CSV (тело запроса или файл в multipart/form-data) в одной транзакции.
+// Поддерживает заголовок Idempotency-Key.
+func batchesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBodySize))
+		if err != nil {
+			sendJSONError(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		items, err := parseBatch(r)
+		var exprErr invalidExpressionError
+		if errors.As(err, &exprErr) {
+			sendExpressionError(w, exprErr)
+			return
+		}
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Повтор с тем же ключом получает сохраненный ответ и не расходует лимит запросов
+		claim, ok := claimIdempotencyKey(db, w, r, claims.UserID, body)
+		if !ok {
+			return
+		}
+		defer claim.release()
+
+		if !allowSubmission(w, claims.UserID) {
+			return
+		}
+
+		settings, err := loadUserSettings(db, claims.UserID)
+		if err != nil {
+			log.Printf("Error fetching settings for user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		requests := make([]models.CalculationRequest, len(items))
+		for i, item := range items {
+			requests[i] = item.toRequest(settings)
+		}
+
+		batch, ids, err := database.InsertBatch(db, claims.UserID, requests, quotas.MaxQueued)
+		if errors.Is(err, database.ErrQueueFull) {
+			sendJSONError(w, quotaExceededError{limit: quotas.MaxQueued}.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if err != nil {
+			log.Printf("Error inserting batch: %v", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		for i, id := range ids {
+			events.publish(models.CalculationEvent{ID: id, UserId: claims.UserID, Operation: requests[i].Operation, Status: "created", Time: batch.CreatedTime})
+		}
+		dispatch.notify()
+
+		claim.respond(w, http.StatusCreated, struct {
+			models.Batch
+			IDs []int `json:"ids"`
+		}{*batch, ids})
+	}
+}
+
+// loadBatch извлекает пакет текущего пользователя по {id} из пути, отвечая ошибкой при неудаче.
+func loadBatch(db *sql.DB, w http.ResponseWriter, r *http.Request) (*models.Batch, bool) {
+	claims, err := authenticateRequest(r)
+	if err != nil {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid batch id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	batch, err := database.GetBatch(db, id, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Batch not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Printf("Error fetching batch %d: %v", id, err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return batch, true
+}
+
+// batchHandler обрабатывает GET /api/v1/batches/{id}: количество вычислений по статусам,
+// прогресс и результаты всех вычислений пакета.
+func batchHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		batch, ok := loadBatch(db, w, r)
+		if !ok {
+			return
+		}
+
+		calculations, err := database.FetchBatchCalculations(db, batch.ID)
+		if err != nil {
+			log.Printf("Error fetching batch calculations: %v", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(summarizeBatch(*batch, calculations))
+	}
+}
+
+// batchCancelHandler обрабатывает POST /api/v1/batches/{id}/cancel: отменяет все вычисления пакета,
+// еще не отправленные на калькуляторы. Уже выполняющиеся вычисления завершаются как обычно.
+func batchCancelHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		batch, ok := loadBatch(db, w, r)
+		if !ok {
+			return
+		}
+
+		ids, err := database.CancelBatch(db, batch.ID)
+		if err != nil {
+			log.Printf("Error cancelling batch %d: %v", batch.ID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now().UTC()
+		for _, id := range ids {
+			events.publish(models.CalculationEvent{ID: id, UserId: batch.UserId, Status: "cancelled", Time: now})
+		}
+		if ids == nil {
+			ids = []int{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"cancelled": len(ids), "ids": ids})
+	}
+}
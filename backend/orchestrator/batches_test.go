@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"calculatorapi/utility/models"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseBatchFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"JSON", "application/json", `[{"operation":"2+2","add_duration":1},{"operation":"3*3"}]`},
+		{"NDJSON", "application/x-ndjson", "{\"operation\":\"2+2\",\"add_duration\":1}\n\n{\"operation\":\"3*3\"}\n"},
+		{"CSV", "text/csv", "operation,add_duration\n2+2,1\n3*3,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			items, err := parseBatch(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(items) != 2 || items[0].Operation != "2+2" || *items[0].AddDuration != 1 || items[1].Operation != "3*3" || items[1].AddDuration != nil {
+				t.Errorf("Unexpected items %+v", items)
+			}
+		})
+	}
+}
+
+func TestParseBatchMultipart(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "batch.csv")
+	part.Write([]byte("operation\n1+1\n"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	items, err := parseBatch(req)
+	if err != nil || len(items) != 1 || items[0].Operation != "1+1" {
+		t.Errorf("Unexpected result %+v, %v", items, err)
+	}
+}
+
+func TestParseBatchErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":            `[]`,
+		"missing op":       `[{"add_duration":1}]`,
+		"negative":         `[{"operation":"1+1","add_duration":-1}]`,
+		"priority":         `[{"operation":"1+1","priority":10}]`,
+		"malformed":        `[{"operation":`,
+		"unknown CSV col":  "operation,color\n1+1,red\n",
+		"CSV without op":   "add_duration\n1\n",
+		"CSV bad duration": "operation,add_duration\n1+1,fast\n",
+		"CSV bad priority": "operation,priority\n1+1,-1\n",
+	}
+
+	for name, body := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(body))
+		if strings.Contains(name, "CSV") {
+			req.Header.Set("Content-Type", "text/csv")
+		}
+		if _, err := parseBatch(req); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSummarizeBatch(t *testing.T) {
+	batch := models.Batch{ID: 1, Total: 4}
+	status := summarizeBatch(batch, []models.OperationResponse{
+		{ID: 1, Status: "completed", Result: 4},
+		{ID: 2, Status: "completed", Result: 9},
+		{ID: 3, Status: "work"},
+		{ID: 4, Status: "cancelled"},
+	})
+
+	if status.Counts["completed"] != 2 || status.Counts["work"] != 1 || status.Counts["cancelled"] != 1 {
+		t.Errorf("Unexpected counts %v", status.Counts)
+	}
+	if status.Finished != 3 || status.Progress != 0.75 {
+		t.Errorf("Expected 3 finished and progress 0.75, got %d and %v", status.Finished, status.Progress)
+	}
+}
+
+func TestBatchesHandlerInsertsInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM user_settings").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"add_duration", "subtract_duration", "multiply_duration", "divide_duration", "inactive_server_time", "precision", "output_format", "locale", "updated_time"}).
+			AddRow(2, 3, 4, 5, 30, 2, "decimal", "ru-RU", time.Now()))
+	expectQueueReserve(mock, 5, 0)
+	mock.ExpectQuery("INSERT INTO batches").WithArgs(5, 2, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	prep := mock.ExpectPrepare("INSERT INTO calculations")
+	prep.ExpectQuery().WithArgs(5, "2+2", sqlmock.AnyArg(), 1, 3, 4, 5, 30, 9, 5).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100))
+	prep.ExpectQuery().WithArgs(5, "3*3", sqlmock.AnyArg(), 2, 3, 4, 5, 30, 9, 8).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(101))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(`[{"operation":"2+2","add_duration":1},{"operation":"3*3","priority":8}]`))
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 5))
+	rr := httptest.NewRecorder()
+	batchesHandler(db)(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		ID    int   `json:"id"`
+		Total int   `json:"total"`
+		IDs   []int `json:"ids"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.ID != 9 || resp.Total != 2 || len(resp.IDs) != 2 || resp.IDs[1] != 101 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchesHandlerQueueFull(t *testing.T) {
+	withQuotas(t, quotaConfig{MaxQueued: 3})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Место в очереди проверяется в транзакции вставки, поэтому пакет не создается
+	mock.ExpectQuery("FROM user_settings").WithArgs(5).WillReturnError(sql.ErrNoRows)
+	expectQueueReserve(mock, 5, 2)
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(`[{"operation":"2+2"},{"operation":"3*3"}]`))
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 5))
+	rr := httptest.NewRecorder()
+	batchesHandler(db)(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), "quota of 3") {
+		t.Errorf("Expected 429 quota error, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchCancelHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM batches WHERE id").WithArgs(9, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "total", "created_time"}).AddRow(9, 5, 2, time.Now()))
+	mock.ExpectQuery("UPDATE calculations").WithArgs(sqlmock.AnyArg(), 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(101))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/batches/{id}/cancel", batchCancelHandler(db))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batches/9/cancel", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 5))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"cancelled":1`) {
+		t.Errorf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"calculatorapi/utility/calculation" // Пакет для разбора выражений
+	"calculatorapi/utility/database"    // Пакет для работы с базой данных
+	"calculatorapi/utility/models"      // Пакет с моделями данных
+)
+
+// Операции над вычислениями, общие для HTTP и gRPC API.
+
+// Ошибки операций над вычислениями, которые сообщаются клиенту
+var (
+	errCalculationNotFound = errors.New("calculation not found")
+	errNotCancellable      = errors.New("calculation has already been sent to a calculator and can no longer be cancelled")
+)
+
+// invalidRequestError - ошибка в параметрах запроса клиента.
+type invalidRequestError struct {
+	message string
+}
+
+func (e invalidRequestError) Error() string {
+	return e.message
+}
+
+// invalidExpressionError - выражение, которое не удалось разобрать. Для пакета item - номер элемента.
+type invalidExpressionError struct {
+	item int
+	*calculation.SyntaxError
+}
+
+func (e invalidExpressionError) Error() string {
+	if e.item > 0 {
+		return fmt.Sprintf("item %d: %s", e.item, e.SyntaxError.Error())
+	}
+	return e.SyntaxError.Error()
+}
+
+// validateExpression разбирает выражение и возвращает invalidExpressionError, если оно не может быть вычислено.
+func validateExpression(operation string, item int) error {
+	err := calculation.Validate(operation)
+	var syntaxErr *calculation.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return invalidExpressionError{item: item, SyntaxError: syntaxErr}
+	}
+	return err
+}
+
+// validatePriority проверяет, что приоритет входит в допустимый диапазон.
+func validatePriority(priority int) error {
+	if priority < models.MinPriority || priority > models.MaxPriority {
+		return invalidRequestError{fmt.Sprintf("priority must be between %d and %d", models.MinPriority, models.MaxPriority)}
+	}
+	return nil
+}
+
+// validate проверяет запрос на вычисление.
+func (req CalculationRequest) validate() error {
+	if strings.TrimSpace(req.Operation) == "" {
+		return invalidRequestError{"operation is required"}
+	}
+	if err := validateExpression(req.Operation, 0); err != nil {
+		return err
+	}
+	for _, duration := range []*int{req.AddDuration, req.SubtractDuration, req.MultiplyDuration, req.DivideDuration, req.InactiveServerTime} {
+		if duration != nil && *duration < 0 {
+			return invalidRequestError{"durations must not be negative"}
+		}
+	}
+	if req.Priority != nil {
+		if err := validatePriority(*req.Priority); err != nil {
+			return err
+		}
+	}
+	if req.WebhookURL != "" {
+		if err := validateWebhookURL(req.WebhookURL); err != nil {
+			return invalidRequestError{err.Error()}
+		}
+		if req.WebhookSecret == "" {
+			return invalidRequestError{"webhook_secret is required with webhook_url"}
+		}
+	}
+	return nil
+}
+
+// createCalculation сохраняет проверенный запрос на вычисление и возвращает идентификатор вычисления.
+// Незаданные длительности заполняются из настроек пользователя. Возвращает quotaExceededError,
+// если очередь пользователя заполнена.
+func createCalculation(db *sql.DB, req CalculationRequest) (int, error) {
+	settings := models.DefaultUserSettings(req.UserId)
+	if !req.hasAllDurations() {
+		var err error
+		if settings, err = loadUserSettings(db, req.UserId); err != nil {
+			return 0, fmt.Errorf("fetching settings for user %d: %w", req.UserId, err)
+		}
+	}
+
+	id, err := database.InsertCalculationRequest(db, req.withDefaults(settings), quotas.MaxQueued)
+	if errors.Is(err, database.ErrQueueFull) {
+		return 0, quotaExceededError{limit: quotas.MaxQueued}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("writing calculation to database: %w", err)
+	}
+
+	events.publish(models.CalculationEvent{ID: id, UserId: req.UserId, Operation: req.Operation, Status: "created", Time: time.Now().UTC()})
+	dispatch.notify()
+	return id, nil
+}
+
+// getCalculation возвращает вычисление пользователя userId.
+// Чужие вычисления считаются не найденными.
+func getCalculation(db *sql.DB, id, userId int) (*models.CalculationResponse, error) {
+	calc, err := database.GetCalculationResultByID(db, id)
+	if err == sql.ErrNoRows {
+		return nil, errCalculationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching calculation %d: %w", id, err)
+	}
+	if calc.UserId != userId {
+		return nil, errCalculationNotFound
+	}
+	return calc, nil
+}
+
+// listCalculations возвращает вычисления пользователя, при непустом status - только в этом статусе.
+func listCalculations(db *sql.DB, userId int, status string) ([]models.OperationResponse, error) {
+	calculations, err := database.FetchCalculationsByUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return calculations, nil
+	}
+
+	filtered := calculations[:0]
+	for _, calc := range calculations {
+		if calc.Status == status {
+			filtered = append(filtered, calc)
+		}
+	}
+	return filtered, nil
+}
+
+// cancelCalculation отменяет вычисление пользователя, еще не отправленное на калькулятор.
+// Повторная отмена уже отмененного вычисления не считается ошибкой.
+func cancelCalculation(db *sql.DB, id, userId int) (*models.CalculationResponse, error) {
+	calc, err := getCalculation(db, id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if calc.Status == "cancelled" {
+		return calc, nil
+	}
+
+	cancelled, err := database.CancelCalculation(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, errNotCancellable
+	}
+
+	calc.Status = "cancelled"
+	events.publish(models.CalculationEvent{ID: calc.ID, UserId: calc.UserId, Operation: calc.Operation, Status: calc.Status, Time: time.Now().UTC()})
+	return calc, nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"calculatorapi/utility/models" // Пакет с моделями данных
+)
+
+const clientGRPCAddr = ":9090" // Адрес gRPC API для клиентов
+
+// clientService реализует gRPC сервис CalculatorClientService поверх тех же операций, что и HTTP API.
+type clientService struct {
+	pb.UnimplementedCalculatorClientServiceServer
+	db *sql.DB
+}
+
+// Ключ контекста, под которым сохраняются утверждения JWT токена вызывающего пользователя
+type claimsContextKey struct{}
+
+// authenticateGRPC извлекает JWT токен из метаданных authorization ("Bearer <token>").
+func authenticateGRPC(ctx context.Context) (*Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || values[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+
+	claims, err := parseToken(strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return claims, nil
+}
+
+// unaryAuthInterceptor проверяет токен перед каждым обычным вызовом.
+func unaryAuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	claims, err := authenticateGRPC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, claimsContextKey{}, claims), req)
+}
+
+// authenticatedStream подменяет контекст потока контекстом с утверждениями токена.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// streamAuthInterceptor проверяет токен перед открытием потока.
+func streamAuthInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	claims, err := authenticateGRPC(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: context.WithValue(ss.Context(), claimsContextKey{}, claims)})
+}
+
+// userIDFromContext возвращает идентификатор пользователя, проверенный интерцептором.
+func userIDFromContext(ctx context.Context) int {
+	claims, _ := ctx.Value(claimsContextKey{}).(*Claims)
+	if claims == nil {
+		return 0
+	}
+	return claims.UserID
+}
+
+// grpcError переводит ошибку операции над вычислением в статус gRPC.
+func grpcError(err error) error {
+	var invalid invalidRequestError
+	var quotaErr quotaExceededError
+	var exprErr invalidExpressionError
+	switch {
+	case errors.As(err, &invalid):
+		return status.Error(codes.InvalidArgument, invalid.message)
+	case errors.As(err, &exprErr):
+		return status.Error(codes.InvalidArgument, "invalid expression: "+exprErr.Error())
+	case errors.As(err, &quotaErr):
+		return status.Error(codes.ResourceExhausted, quotaErr.Error())
+	case errors.Is(err, errCalculationNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, errNotCancellable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		log.Printf("Error handling gRPC request: %v", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// optionalInt переводит необязательное поле protobuf в длительность запроса.
+func optionalInt(value *int32) *int {
+	if value == nil {
+		return nil
+	}
+	v := int(*value)
+	return &v
+}
+
+// toProtoCalculation формирует сообщение Calculation. Результат заполняется только у завершенных вычислений.
+func toProtoCalculation(id, userId int, operation string, result float64, status string) *pb.Calculation {
+	calc := &pb.Calculation{
+		Id:        int32(id),
+		UserId:    int32(userId),
+		Operation: operation,
+		Status:    status,
+	}
+	if status == "completed" {
+		calc.Result = &result
+	}
+	return calc
+}
+
+// withExecution дополняет сообщение Calculation сведениями о выполнении вычисления.
+func withExecution(calc *pb.Calculation, execution models.CalculationExecution) *pb.Calculation {
+	timestamp := func(t *time.Time) *timestamppb.Timestamp {
+		if t == nil {
+			return nil
+		}
+		return timestamppb.New(*t)
+	}
+	calc.CreatedTime = timestamp(execution.CreatedTime)
+	calc.StartTime = timestamp(execution.StartTime)
+	calc.EndTime = timestamp(execution.EndTime)
+	calc.OperationServer = execution.OperationServer
+	calc.ServerStatus = execution.ServerStatus
+	calc.AgentId = execution.AgentID
+	calc.Attempts = int32(execution.Attempts)
+	return calc
+}
+
+func (s *clientService) Submit(ctx context.Context, in *pb.SubmitRequest) (*pb.Calculation, error) {
+	req := CalculationRequest{
+		UserId:             userIDFromContext(ctx),
+		Operation:          in.Operation,
+		AddDuration:        optionalInt(in.AddDuration),
+		SubtractDuration:   optionalInt(in.SubtractDuration),
+		MultiplyDuration:   optionalInt(in.MultiplyDuration),
+		DivideDuration:     optionalInt(in.DivideDuration),
+		InactiveServerTime: optionalInt(in.InactiveServerTime),
+		WebhookURL:         in.WebhookUrl,
+		WebhookSecret:      in.WebhookSecret,
+		Priority:           optionalInt(in.Priority),
+	}
+	if err := req.validate(); err != nil {
+		return nil, grpcError(err)
+	}
+
+	if allowed, wait := submitLimiter.allow(req.UserId, time.Now()); !allowed {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfterSeconds(wait))))
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded, retry later")
+	}
+
+	id, err := createCalculation(s.db, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toProtoCalculation(id, req.UserId, req.Operation, 0, "created"), nil
+}
+
+func (s *clientService) Get(ctx context.Context, in *pb.GetRequest) (*pb.Calculation, error) {
+	calc, err := getCalculation(s.db, int(in.Id), userIDFromContext(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return withExecution(toProtoCalculation(calc.ID, calc.UserId, calc.Operation, calc.Result, calc.Status), calc.CalculationExecution), nil
+}
+
+func (s *clientService) List(ctx context.Context, in *pb.ListRequest) (*pb.ListResponse, error) {
+	calculations, err := listCalculations(s.db, userIDFromContext(ctx), in.Status)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &pb.ListResponse{Calculations: make([]*pb.Calculation, 0, len(calculations))}
+	for _, calc := range calculations {
+		resp.Calculations = append(resp.Calculations, withExecution(toProtoCalculation(calc.ID, calc.UserId, calc.Operation, calc.Result, calc.Status), calc.CalculationExecution))
+	}
+	return resp, nil
+}
+
+func (s *clientService) Cancel(ctx context.Context, in *pb.CancelRequest) (*pb.Calculation, error) {
+	calc, err := cancelCalculation(s.db, int(in.Id), userIDFromContext(ctx))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return withExecution(toProtoCalculation(calc.ID, calc.UserId, calc.Operation, calc.Result, calc.Status), calc.CalculationExecution), nil
+}
+
+// Watch отправляет изменения статусов вычисления in.Id, начиная с текущего состояния, и завершается
+// после окончательного статуса. При in.Id равном 0 отправляет изменения всех вычислений пользователя.
+func (s *clientService) Watch(in *pb.WatchRequest, stream pb.CalculatorClientService_WatchServer) error {
+	ctx := stream.Context()
+	userID := userIDFromContext(ctx)
+
+	send := func(ev models.CalculationEvent) error {
+		return stream.Send(&pb.CalculationEvent{
+			Calculation: toProtoCalculation(ev.ID, ev.UserId, ev.Operation, ev.Result, ev.Status),
+			Time:        timestamppb.New(ev.Time),
+		})
+	}
+
+	// Подписываемся до чтения текущего состояния, чтобы не пропустить изменение между ними
+	var sub *eventSubscriber
+	lastStatus := make(map[int]string)
+	if in.Id != 0 {
+		sub = events.subscribe(int(in.Id), 0)
+		defer events.unsubscribe(sub)
+
+		current, err := getCalculation(s.db, int(in.Id), userID)
+		if err != nil {
+			return grpcError(err)
+		}
+		events.remember(current.ID, current.Status)
+		lastStatus[current.ID] = current.Status
+
+		snapshot := models.CalculationEvent{ID: current.ID, UserId: current.UserId, Operation: current.Operation, Result: current.Result, Status: current.Status, Time: time.Now().UTC()}
+		if err := send(snapshot); err != nil || isTerminalStatus(current.Status) {
+			return err
+		}
+	} else {
+		sub = events.subscribe(0, userID)
+		defer events.unsubscribe(sub)
+
+		// Запоминаем текущие статусы, чтобы отправлять только последующие изменения
+		calculations, err := listCalculations(s.db, userID, "")
+		if err != nil {
+			return grpcError(err)
+		}
+		for _, calc := range calculations {
+			events.remember(calc.ID, calc.Status)
+			lastStatus[calc.ID] = calc.Status
+		}
+	}
+
+	for ev := range statusChanges(ctx, sub, lastStatus, in.Id != 0) {
+		if err := send(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newClientGRPCServer создает gRPC сервер с сервисом CalculatorClientService и проверкой JWT токена.
+func newClientGRPCServer(db *sql.DB) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryAuthInterceptor),
+		grpc.StreamInterceptor(streamAuthInterceptor),
+	)
+	pb.RegisterCalculatorClientServiceServer(server, &clientService{db: db})
+	return server
+}
+
+// serveClientGRPC запускает gRPC API для клиентов на адресе addr.
+func serveClientGRPC(db *sql.DB, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Client gRPC API is running on %s...", addr)
+	return newClientGRPCServer(db).Serve(lis)
+}
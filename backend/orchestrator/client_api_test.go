@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"calculatorapi/utility/models"
+)
+
+// newTestClient запускает CalculatorClientService в памяти и возвращает клиента к нему.
+func newTestClient(t *testing.T) (pb.CalculatorClientServiceClient, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	server := newClientGRPCServer(db)
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		db.Close()
+	})
+	return pb.NewCalculatorClientServiceClient(conn), mock
+}
+
+func withToken(t *testing.T, userID int) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+newTestToken(t, userID))
+}
+
+func TestClientAPIRequiresToken(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.List(context.Background(), &pb.ListRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer garbage")
+	stream, err := client.Watch(ctx, &pb.WatchRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for stream, got %v", err)
+	}
+}
+
+func TestClientAPISubmit(t *testing.T) {
+	client, mock := newTestClient(t)
+
+	mock.ExpectQuery("FROM user_settings").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"add_duration", "subtract_duration", "multiply_duration", "divide_duration", "inactive_server_time", "precision", "output_format", "locale", "updated_time"}).
+			AddRow(3, 4, 5, 6, 30, 6, "decimal", "en-US", time.Now()))
+	expectQueueReserve(mock, 7, 0)
+	mock.ExpectQuery("INSERT INTO calculations").WithArgs(7, "2+2", "created", sqlmock.AnyArg(), 1, 4, 5, 6, 30, nil, nil, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(55))
+	mock.ExpectCommit()
+
+	add := int32(1)
+	calc, err := client.Submit(withToken(t, 7), &pb.SubmitRequest{Operation: "2+2", AddDuration: &add})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calc.Id != 55 || calc.UserId != 7 || calc.Status != "created" || calc.Result != nil {
+		t.Errorf("Unexpected calculation %v", calc)
+	}
+
+	_, err = client.Submit(withToken(t, 7), &pb.SubmitRequest{Operation: " "})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for empty operation, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestClientAPIGetAndCancel(t *testing.T) {
+	client, mock := newTestClient(t)
+	resultRows := func(status string, userId int) *sqlmock.Rows {
+		return calculationRows(resultColumns, []interface{}{"2+2", nil, status, userId})
+	}
+
+	// Чужое вычисление не видно
+	mock.ExpectQuery("FROM calculations WHERE id").WithArgs(10).WillReturnRows(resultRows("created", 8))
+	if _, err := client.Get(withToken(t, 7), &pb.GetRequest{Id: 10}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for another user's calculation, got %v", err)
+	}
+
+	mock.ExpectQuery("FROM calculations WHERE id").WithArgs(11).WillReturnRows(resultRows("created", 7))
+	mock.ExpectExec("UPDATE calculations").WithArgs(sqlmock.AnyArg(), 11).WillReturnResult(sqlmock.NewResult(0, 1))
+	calc, err := client.Cancel(withToken(t, 7), &pb.CancelRequest{Id: 11})
+	if err != nil || calc.Status != "cancelled" {
+		t.Errorf("Expected cancelled calculation, got %v, %v", calc, err)
+	}
+
+	mock.ExpectQuery("FROM calculations WHERE id").WithArgs(12).WillReturnRows(resultRows("work", 7))
+	mock.ExpectExec("UPDATE calculations").WithArgs(sqlmock.AnyArg(), 12).WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := client.Cancel(withToken(t, 7), &pb.CancelRequest{Id: 12}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for running calculation, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestClientAPIList(t *testing.T) {
+	client, mock := newTestClient(t)
+
+	mock.ExpectQuery("FROM calculations WHERE userId").WithArgs(7).
+		WillReturnRows(calculationRows(listColumns,
+			[]interface{}{1, 7, "2+2", 4.0, "completed"}, []interface{}{2, 7, "3*3", nil, "created"}))
+
+	resp, err := client.List(withToken(t, 7), &pb.ListRequest{Status: "completed"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Calculations) != 1 || resp.Calculations[0].GetResult() != 4 {
+		t.Errorf("Unexpected calculations %v", resp.Calculations)
+	}
+}
+
+func TestClientAPIWatch(t *testing.T) {
+	client, mock := newTestClient(t)
+	events = newEventBroker()
+
+	mock.ExpectQuery("FROM calculations WHERE id").WithArgs(20).
+		WillReturnRows(calculationRows(resultColumns, []interface{}{"2+2", nil, "work", 7}))
+
+	ctx, cancel := context.WithTimeout(withToken(t, 7), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &pb.WatchRequest{Id: 20})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	first, err := stream.Recv()
+	if err != nil || first.Calculation.Status != "work" {
+		t.Fatalf("Expected current state first, got %v, %v", first, err)
+	}
+
+	events.publish(models.CalculationEvent{ID: 20, UserId: 7, Operation: "2+2", Result: 4, Status: "completed", Time: time.Now()})
+
+	second, err := stream.Recv()
+	if err != nil || second.Calculation.Status != "completed" || second.Calculation.GetResult() != 4 {
+		t.Fatalf("Expected completion event, got %v, %v", second, err)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected stream to end after the calculation finished")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Период резервного опроса очереди на случай пропущенных уведомлений и вычислений, отложенных до next_attempt_time
+var dispatchPollInterval = time.Duration(max(envInt("CALCULATOR_DISPATCH_POLL_SECONDS", 30), 1)) * time.Second
+
+// Наибольшее количество вычислений, отправляемых агентам за один проход
+var maxDispatchBatch = max(envInt("CALCULATOR_MAX_DISPATCH_BATCH", 100), 1)
+
+// dispatcher запускает отправку вычислений агентам, как только в очереди появляется работа
+// или у агентов освобождается место. Агенты, которые сами запрашивают задачи, будятся на каждом проходе.
+type dispatcher struct {
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending chan struct{} // Закрывается на следующем проходе
+}
+
+// Диспетчер отправки вычислений
+var dispatch = newDispatcher()
+
+func newDispatcher() *dispatcher {
+	return &dispatcher{wake: make(chan struct{}, 1), pending: make(chan struct{})}
+}
+
+// nextPass возвращает канал, который закроется на следующем проходе диспетчера.
+// Канал берется до проверки очереди, чтобы не пропустить проход, начавшийся во время проверки.
+func (d *dispatcher) nextPass() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending
+}
+
+// broadcast будит всех, кто ожидает прохода.
+func (d *dispatcher) broadcast() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	close(d.pending)
+	d.pending = make(chan struct{})
+}
+
+// notify будит диспетчер. Уведомления, пришедшие во время прохода, объединяются в один следующий проход.
+func (d *dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run отправляет вычисления агентам при каждом уведомлении notify, уведомлении Postgres из notifications
+// и раз в dispatchPollInterval до закрытия shutdownCh. Канал notifications может быть nil.
+func (d *dispatcher) run(db *sql.DB, notifications <-chan *pq.Notification, shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(dispatchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-notifications: // nil приходит после переподключения, пропущенные уведомления забираются проходом
+		case <-shutdownCh:
+			log.Println("Stopping submission of new calculations.")
+			return
+		}
+		d.broadcast()
+		submitCalculations(db)
+	}
+}
+
+// pushAgents возвращает агентов, которым оркестратор сам отправляет вычисления.
+func pushAgents(agents []registeredAgent) []registeredAgent {
+	push := make([]registeredAgent, 0, len(agents))
+	for _, agent := range agents {
+		if !agent.Pull {
+			push = append(push, agent)
+		}
+	}
+	return push
+}
+
+// dispatchCapacity возвращает количество вычислений, которое агенты agents могут принять на момент now:
+// свободные исполнители и места в очередях агентов, которые не отказали в приеме и доступны по gRPC.
+// Результат ограничен maxDispatchBatch.
+func dispatchCapacity(agents []registeredAgent, now time.Time) int {
+	capacity := 0
+	for _, agent := range agents {
+		if busyAgents.busy(agent.URL, now) || agentConns.failing(agent.GRPCAddr) {
+			continue
+		}
+		capacity += max(agent.MaxConcurrency+agent.QueueSize-agent.Running-agent.QueueDepth, 0)
+	}
+	return min(capacity, maxDispatchBatch)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// queueRows возвращает выборку вычислений для отправки без строк.
+func queueRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "userId", "operation", "add_duration", "subtract_duration", "multiply_duration", "divide_duration", "inactive_server_time", "attempts"})
+}
+
+// waitForExpectations ждет, пока будут выполнены все ожидаемые запросы к базе данных.
+func waitForExpectations(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := mock.ExpectationsWereMet()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("There were unfulfilled expectations: %s", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDispatcherNotifyCoalesces(t *testing.T) {
+	d := newDispatcher()
+	d.notify()
+	d.notify() // Не блокируется, если проход уже запрошен
+	if len(d.wake) != 1 {
+		t.Errorf("Expected a single pending wake-up, got %d", len(d.wake))
+	}
+}
+
+func TestDispatcherRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	withRegistry(t, registeredAgent{ID: "a", URL: "http://localhost:8081", GRPCAddr: "localhost:1", MaxConcurrency: 2})
+
+	d := newDispatcher()
+	notifications := make(chan *pq.Notification)
+	shutdownCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		d.run(db, notifications, shutdownCh)
+		close(done)
+	}()
+
+	// Проход по уведомлению из обработчика
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(queueRows())
+	d.notify()
+	waitForExpectations(t, mock)
+
+	// Проход по уведомлению Postgres, в том числе nil после переподключения
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(queueRows())
+	notifications <- nil
+	waitForExpectations(t, mock)
+
+	close(shutdownCh)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the dispatcher to stop on shutdown")
+	}
+}
+
+func TestDispatchCapacity(t *testing.T) {
+	withRegistry(t)
+	t.Cleanup(func() { busyAgents = newAgentBackoff() })
+	busyAgents.hold("http://localhost:8083", time.Now().Add(time.Minute))
+
+	agents := []registeredAgent{
+		{URL: "http://localhost:8081", MaxConcurrency: 4, QueueSize: 10, Running: 4, QueueDepth: 3},
+		{URL: "http://localhost:8082", MaxConcurrency: 2, Running: 3}, // Учтено больше вычислений, чем мест
+		{URL: "http://localhost:8083", MaxConcurrency: 8},             // Отказал в приеме
+	}
+	if got := dispatchCapacity(agents, time.Now()); got != 7 {
+		t.Errorf("Expected capacity 7, got %d", got)
+	}
+
+	old := maxDispatchBatch
+	t.Cleanup(func() { maxDispatchBatch = old })
+	maxDispatchBatch = 5
+	if got := dispatchCapacity(agents, time.Now()); got != 5 {
+		t.Errorf("Expected capacity to be capped at 5, got %d", got)
+	}
+}
+
+func TestSubmitCalculationsBatchSize(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	// Без агентов очередь не запрашивается
+	withRegistry(t)
+	submitCalculations(db)
+
+	// Партия равна свободному месту у агентов, которые не запрашивают задачи сами
+	withRegistry(t,
+		registeredAgent{ID: "a", URL: "http://localhost:8081", GRPCAddr: "localhost:1", MaxConcurrency: 2, QueueSize: 3, Running: 1},
+		registeredAgent{ID: "b", URL: "http://localhost:8082", GRPCAddr: "localhost:2", MaxConcurrency: 4},
+		registeredAgent{ID: "c", Pull: true, MaxConcurrency: 10},
+	)
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").
+		WithArgs(8, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(queueRows())
+	submitCalculations(db)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const (
+	eventPollInterval    = 1 * time.Second  // Период опроса базы данных на предмет изменения статусов
+	sseHeartbeatInterval = 15 * time.Second // Период отправки пустых комментариев, чтобы соединение не закрывалось прокси
+	maxLongPollWait      = 60 * time.Second // Максимальное время ожидания для параметра ?wait=
+	subscriberBufferSize = 32               // Размер буфера канала подписчика
+	eventPollClockSkew   = 5 * time.Second  // Запас на расхождение часов оркестраторов, завершающих вычисления
+)
+
+// Подписчик на события об изменении статусов вычислений.
+// Если calcID равен 0, подписчик получает события по всем вычислениям пользователя userID.
+type eventSubscriber struct {
+	calcID int
+	userID int
+	ch     chan models.CalculationEvent
+}
+
+// eventBroker рассылает события об изменении статусов вычислений подписчикам.
+// Статусы меняются как в самом оркестраторе, так и на серверах калькуляторов,
+// поэтому брокер дополнительно опрашивает базу данных по отслеживаемым вычислениям.
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+	statuses    map[int]string // Последний известный статус отслеживаемых вычислений, очищается при каждом опросе
+	polledAt    time.Time      // Время предыдущего опроса базы данных
+}
+
+// Глобальный брокер событий оркестратора
+var events = newEventBroker()
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subscribers: make(map[*eventSubscriber]struct{}),
+		statuses:    make(map[int]string),
+	}
+}
+
+// Окончательные статусы вычисления, после которых изменений больше не будет
+var terminalStatuses = []string{"completed", "cancelled", "failed"}
+
+// isTerminalStatus сообщает, является ли статус окончательным.
+func isTerminalStatus(status string) bool {
+	for _, terminal := range terminalStatuses {
+		if status == terminal {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe регистрирует нового подписчика на события вычисления calcID или всех вычислений пользователя userID.
+func (b *eventBroker) subscribe(calcID, userID int) *eventSubscriber {
+	sub := &eventSubscriber{
+		calcID: calcID,
+		userID: userID,
+		ch:     make(chan models.CalculationEvent, subscriberBufferSize),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// unsubscribe удаляет подписчика из брокера.
+func (b *eventBroker) unsubscribe(sub *eventSubscriber) {
+	b.mu.Lock()
+	delete(b.subscribers, sub)
+	b.mu.Unlock()
+}
+
+// remember запоминает статус ещё не отслеживаемого вычисления, не рассылая событие.
+// Уже известный статус не перезаписывается, чтобы другие подписчики не пропустили изменение.
+func (b *eventBroker) remember(id int, status string) {
+	b.mu.Lock()
+	if _, ok := b.statuses[id]; !ok {
+		b.statuses[id] = status
+	}
+	b.mu.Unlock()
+}
+
+// publish рассылает событие подписчикам, если статус вычисления действительно изменился.
+// Медленные подписчики с переполненным буфером пропускают событие.
+func (b *eventBroker) publish(ev models.CalculationEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if last, ok := b.statuses[ev.ID]; ok && last == ev.Status {
+		return
+	}
+	b.statuses[ev.ID] = ev.Status
+
+	for sub := range b.subscribers {
+		if sub.calcID != ev.ID && (sub.calcID != 0 || sub.userID != ev.UserId) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			log.Printf("Event subscriber for calculation %d is too slow, dropping event", ev.ID)
+		}
+	}
+}
+
+// watched возвращает идентификаторы вычислений и пользователей, на которые есть подписки.
+func (b *eventBroker) watched() ([]int, []int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var ids, userIds []int
+	for sub := range b.subscribers {
+		if sub.calcID != 0 {
+			ids = append(ids, sub.calcID)
+		} else {
+			userIds = append(userIds, sub.userID)
+		}
+	}
+	return ids, userIds
+}
+
+// poll сверяет статусы отслеживаемых вычислений в базе данных и рассылает события об изменениях.
+func (b *eventBroker) poll(db *sql.DB) {
+	ids, userIds := b.watched()
+	if len(ids) == 0 && len(userIds) == 0 {
+		// Подписчиков нет, забываем накопленные статусы
+		b.mu.Lock()
+		b.statuses = make(map[int]string)
+		b.polledAt = time.Time{}
+		b.mu.Unlock()
+		return
+	}
+
+	// Вычисления пользователей, завершенные до предыдущего опроса, уже разосланы
+	now := time.Now().UTC()
+	b.mu.Lock()
+	since := b.polledAt
+	b.mu.Unlock()
+	if since.IsZero() {
+		since = now
+	}
+
+	calculations, err := database.FetchCalculationStatuses(db, ids, userIds, terminalStatuses, since.Add(-eventPollClockSkew))
+	if err != nil {
+		log.Printf("Error polling calculation statuses: %v", err)
+		return
+	}
+	b.mu.Lock()
+	b.polledAt = now
+	b.mu.Unlock()
+
+	watched := make(map[int]bool, len(calculations))
+	for _, calc := range calculations {
+		watched[calc.ID] = true
+		b.publish(models.CalculationEvent{
+			ID:        calc.ID,
+			UserId:    calc.UserId,
+			Operation: calc.Operation,
+			Result:    calc.Result,
+			Status:    calc.Status,
+			Time:      now,
+		})
+	}
+	b.forgetUnwatched(watched)
+}
+
+// forgetUnwatched забывает статусы вычислений, которых нет в watched: на них никто не подписан,
+// например поток завершенного вычисления уже закрыт. Иначе при долгоживущих подписчиках карта росла бы без ограничений.
+func (b *eventBroker) forgetUnwatched(watched map[int]bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id := range b.statuses {
+		if !watched[id] {
+			delete(b.statuses, id)
+		}
+	}
+}
+
+// run периодически опрашивает базу данных до закрытия канала shutdownCh.
+func (b *eventBroker) run(db *sql.DB, shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.poll(db)
+		case <-shutdownCh:
+			log.Println("Stopping calculation events broker.")
+			return
+		}
+	}
+}
+
+// waitForStatusChange ожидает события с отличным от status статусом, истечения timeout или отмены ctx.
+// Возвращает true, если статус изменился.
+func waitForStatusChange(ctx context.Context, sub *eventSubscriber, status string, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case ev := <-sub.ch:
+			if ev.Status != status {
+				return true
+			}
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// parseWaitParam разбирает параметр ?wait= (например, "30s" или "30") и ограничивает его maxLongPollWait.
+func parseWaitParam(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		// Допускаем значение в секундах без единиц измерения
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait parameter %q", value)
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	if wait < 0 {
+		return 0, fmt.Errorf("wait parameter must not be negative")
+	}
+	if wait > maxLongPollWait {
+		wait = maxLongPollWait
+	}
+	return wait, nil
+}
+
+// startEventStream отправляет заголовки SSE ответа.
+func startEventStream(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return flusher, true
+}
+
+// writeEvent записывает одно событие в SSE поток.
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, ev models.CalculationEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// statusChanges возвращает канал переходов статуса из событий подписчика sub, о которых клиент еще не знает:
+// события со статусом, уже записанным в lastStatus, пропускаются. Канал закрывается при завершении ctx,
+// а если stopOnTerminal установлен, то и после окончательного статуса. Не зависит от транспорта,
+// поэтому используется и SSE потоками, и gRPC методом Watch.
+func statusChanges(ctx context.Context, sub *eventSubscriber, lastStatus map[int]string, stopOnTerminal bool) <-chan models.CalculationEvent {
+	changes := make(chan models.CalculationEvent)
+	go func() {
+		defer close(changes)
+		for {
+			select {
+			case ev := <-sub.ch:
+				if lastStatus[ev.ID] == ev.Status {
+					continue
+				}
+				lastStatus[ev.ID] = ev.Status
+				select {
+				case changes <- ev:
+				case <-ctx.Done():
+					return
+				}
+				if stopOnTerminal && isTerminalStatus(ev.Status) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// streamEvents пересылает переходы статуса из событий подписчика в SSE поток до отключения клиента.
+// Если stopOnTerminal установлен, поток закрывается после окончательного статуса.
+func streamEvents(w http.ResponseWriter, r *http.Request, flusher http.Flusher, sub *eventSubscriber, lastStatus map[int]string, stopOnTerminal bool) {
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	changes := statusChanges(r.Context(), sub, lastStatus, stopOnTerminal)
+	for {
+		select {
+		case ev, ok := <-changes:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, flusher, ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// expressionEventsHandler обрабатывает GET /api/v1/expressions/{id}/events:
+// SSE поток переходов статуса одного вычисления пользователя, определяемого по JWT токену.
+// Первым событием отправляется текущее состояние, поток закрывается после завершения вычисления.
+func expressionEventsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid id parameter", http.StatusBadRequest)
+			return
+		}
+
+		// Подписываемся до чтения текущего состояния, чтобы не пропустить изменение между ними
+		sub := events.subscribe(id, 0)
+		defer events.unsubscribe(sub)
+
+		// Вычисления других пользователей не отличаются от несуществующих
+		current, err := getCalculation(db, id, claims.UserID)
+		if err != nil {
+			if errors.Is(err, errCalculationNotFound) {
+				sendJSONError(w, "Calculation not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("Error fetching calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		events.remember(current.ID, current.Status)
+
+		flusher, ok := startEventStream(w)
+		if !ok {
+			sendJSONError(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		snapshot := models.CalculationEvent{
+			ID:        current.ID,
+			UserId:    current.UserId,
+			Operation: current.Operation,
+			Result:    current.Result,
+			Status:    current.Status,
+			Time:      time.Now().UTC(),
+		}
+		if err := writeEvent(w, flusher, snapshot); err != nil || isTerminalStatus(current.Status) {
+			return
+		}
+
+		streamEvents(w, r, flusher, sub, map[int]string{current.ID: current.Status}, true)
+	}
+}
+
+// userEventsHandler обрабатывает GET /api/v1/events: SSE поток переходов статуса
+// всех вычислений пользователя, определяемого по JWT токену.
+func userEventsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		sub := events.subscribe(0, claims.UserID)
+		defer events.unsubscribe(sub)
+
+		// Запоминаем текущие статусы, чтобы рассылать только последующие изменения
+		calculations, err := database.FetchCalculationsByUser(db, claims.UserID)
+		if err != nil {
+			log.Printf("Error fetching calculations for user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		lastStatus := make(map[int]string, len(calculations))
+		for _, calc := range calculations {
+			events.remember(calc.ID, calc.Status)
+			lastStatus[calc.ID] = calc.Status
+		}
+
+		flusher, ok := startEventStream(w)
+		if !ok {
+			sendJSONError(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		streamEvents(w, r, flusher, sub, lastStatus, false)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"calculatorapi/utility/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestEventBrokerPublish(t *testing.T) {
+	broker := newEventBroker()
+	byID := broker.subscribe(1, 0)
+	byUser := broker.subscribe(0, 7)
+	other := broker.subscribe(0, 8)
+
+	broker.publish(models.CalculationEvent{ID: 1, UserId: 7, Status: "created"})
+	broker.publish(models.CalculationEvent{ID: 1, UserId: 7, Status: "created"}) // Повтор того же статуса не рассылается
+	broker.publish(models.CalculationEvent{ID: 2, UserId: 7, Status: "work"})
+
+	if len(byID.ch) != 1 {
+		t.Errorf("Expected 1 event for calculation subscriber, got %d", len(byID.ch))
+	}
+	if len(byUser.ch) != 2 {
+		t.Errorf("Expected 2 events for user subscriber, got %d", len(byUser.ch))
+	}
+	if len(other.ch) != 0 {
+		t.Errorf("Expected no events for another user, got %d", len(other.ch))
+	}
+
+	broker.unsubscribe(byID)
+	broker.publish(models.CalculationEvent{ID: 1, UserId: 7, Status: "completed"})
+	if len(byID.ch) != 1 {
+		t.Errorf("Unsubscribed subscriber should not receive events")
+	}
+}
+
+func TestStatusChanges(t *testing.T) {
+	sub := &eventSubscriber{calcID: 1, ch: make(chan models.CalculationEvent, subscriberBufferSize)}
+	for _, status := range []string{"created", "work", "work", "completed", "failed"} {
+		sub.ch <- models.CalculationEvent{ID: 1, Status: status}
+	}
+
+	// Известный клиенту статус и повторы пропускаются, наблюдение заканчивается на окончательном статусе
+	var got []string
+	for ev := range statusChanges(context.Background(), sub, map[int]string{1: "created"}, true) {
+		got = append(got, ev.Status)
+	}
+	if strings.Join(got, ",") != "work,completed" {
+		t.Errorf("Unexpected status changes %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := statusChanges(ctx, sub, map[int]string{}, false)
+	if ev := <-changes; ev.Status != "failed" {
+		t.Errorf("Expected the remaining event, got %+v", ev)
+	}
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Error("Expected the channel to be closed after the context is done")
+	}
+}
+
+func TestEventBrokerPoll(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	broker := newEventBroker()
+	sub := broker.subscribe(3, 0)
+	broker.remember(3, "work")
+	broker.remember(4, "completed") // Поток этого вычисления уже закрыт
+
+	rows := sqlmock.NewRows([]string{"id", "userId", "operation", "result", "status"}).
+		AddRow(3, 1, "2+2", 4.0, "completed")
+	mock.ExpectQuery("FROM calculations WHERE deleted_time IS NULL AND \\(id = ANY").WillReturnRows(rows)
+
+	broker.poll(db)
+
+	select {
+	case ev := <-sub.ch:
+		if ev.Status != "completed" || ev.Result != 4.0 {
+			t.Errorf("Unexpected event %+v", ev)
+		}
+	default:
+		t.Error("Expected a status change event")
+	}
+	if _, ok := broker.statuses[4]; ok || len(broker.statuses) != 1 {
+		t.Errorf("Expected statuses of unwatched calculations to be forgotten, got %v", broker.statuses)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestEventBrokerPollUserSince(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	broker := newEventBroker()
+	broker.subscribe(0, 7)
+	polledAt := time.Now().UTC().Add(-time.Second)
+	broker.polledAt = polledAt
+
+	// По пользователю извлекаются только незавершенные вычисления и завершенные после предыдущего опроса
+	mock.ExpectQuery("userId = ANY\\(\\$2\\) AND \\(status <> ALL\\(\\$3\\) OR end_time >= \\$4\\)").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), polledAt.Add(-eventPollClockSkew)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "operation", "result", "status"}))
+	broker.poll(db)
+
+	if !broker.polledAt.After(polledAt) {
+		t.Errorf("Expected the poll time to advance, got %v", broker.polledAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestParseWaitParam(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"5", 5 * time.Second, false},
+		{"10m", maxLongPollWait, false},
+		{"-1s", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWaitParam(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWaitParam(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWaitForStatusChange(t *testing.T) {
+	sub := &eventSubscriber{calcID: 1, ch: make(chan models.CalculationEvent, 2)}
+	sub.ch <- models.CalculationEvent{ID: 1, Status: "created"}
+	sub.ch <- models.CalculationEvent{ID: 1, Status: "work"}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if !waitForStatusChange(req.Context(), sub, "created", time.Second) {
+		t.Error("Expected status change to be detected")
+	}
+	if waitForStatusChange(req.Context(), sub, "work", 10*time.Millisecond) {
+		t.Error("Expected wait to time out")
+	}
+}
+
+func TestExpressionEventsHandlerCompleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rows := calculationRows(resultColumns, []interface{}{"2+2", 4.0, "completed", 1})
+	mock.ExpectQuery("^SELECT (.+) FROM calculations WHERE id").WithArgs(5).WillReturnRows(rows)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/expressions/{id}/events", expressionEventsHandler(db))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/expressions/5/events", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 1))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream content type, got %q", ct)
+	}
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, "event: status\ndata: ") || !strings.Contains(body, `"status":"completed"`) {
+		t.Errorf("Unexpected stream body: %q", body)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestExpressionEventsHandlerOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/expressions/{id}/events", expressionEventsHandler(db))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/expressions/5/events", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without a token, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	// Поток вычисления другого пользователя не открывается
+	mock.ExpectQuery("^SELECT (.+) FROM calculations WHERE id").WithArgs(5).
+		WillReturnRows(calculationRows(resultColumns, []interface{}{"2+2", nil, "work", 1}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/expressions/5/events", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 2))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUserEventsHandlerRequiresToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	rr := httptest.NewRecorder()
+	userEventsHandler(nil)(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"calculatorapi/utility/calculation" // Пакет для разбора выражений
+)
+
+// Тело ответа 422 на выражение, которое не удалось разобрать
+type expressionErrorResponse struct {
+	Error string `json:"error"`
+	Item  int    `json:"item,omitempty"` // Номер элемента пакета, начиная с 1
+	*calculation.SyntaxError
+}
+
+// sendExpressionError отвечает 422 Unprocessable Entity с позицией ошибки в выражении.
+func sendExpressionError(w http.ResponseWriter, err invalidExpressionError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(expressionErrorResponse{Error: "Invalid expression", Item: err.item, SyntaxError: err.SyntaxError})
+}
+
+// Ответ на POST /api/v1/validate. Для некорректного выражения содержит позицию и описание ошибки.
+type validateResponse struct {
+	Valid bool `json:"valid"`
+	*calculation.SyntaxError
+}
+
+// validateHandler обрабатывает POST /api/v1/validate: проверяет выражение без сохранения,
+// чтобы интерфейс мог подсвечивать ошибку по мере ввода.
+func validateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Operation string `json:"operation"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp := validateResponse{Valid: true}
+	var exprErr invalidExpressionError
+	if errors.As(validateExpression(req.Operation, 0), &exprErr) {
+		resp = validateResponse{SyntaxError: exprErr.SyntaxError}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
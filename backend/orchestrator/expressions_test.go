@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSubmitCalculationRejectsInvalidExpression(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rr := httptest.NewRecorder()
+	submitCalculationHandler(db)(rr, newSubmitRequest(t, `{"userId":1,"operation":"2++"}`, ""))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Error    string `json:"error"`
+		Position int    `json:"position"`
+		Expected string `json:"expected"`
+		Found    string `json:"found"`
+		Message  string `json:"message"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Position != 2 || resp.Expected != "number" || resp.Found != "+" || resp.Message == "" {
+		t.Errorf("Unexpected error response %+v", resp)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBatchRejectsInvalidExpression(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batches", strings.NewReader(`[{"operation":"1+1"},{"operation":"2*"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 5))
+	rr := httptest.NewRecorder()
+	batchesHandler(db)(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity || !strings.Contains(rr.Body.String(), `"item":2`) || !strings.Contains(rr.Body.String(), `"position":2`) {
+		t.Errorf("Expected 422 for item 2, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestValidateHandler(t *testing.T) {
+	tests := []struct {
+		operation string
+		want      string
+	}{
+		{"2 + 2 * 3", `{"valid":true}`},
+		{"2 3", `{"valid":false,"position":2,"expected":"operator","found":"3","message":"expected operator, found \"3\""}`},
+		{"", `{"valid":false,"position":0,"expected":"number","found":"","message":"expected number, found end of expression"}`},
+	}
+
+	for _, tt := range tests {
+		body, _ := json.Marshal(map[string]string{"operation": tt.operation})
+		rr := httptest.NewRecorder()
+		validateHandler(rr, httptest.NewRequest(http.MethodPost, "/api/v1/validate", strings.NewReader(string(body))))
+
+		if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != tt.want {
+			t.Errorf("validate(%q) = %d %s, want %s", tt.operation, rr.Code, rr.Body.String(), tt.want)
+		}
+	}
+}
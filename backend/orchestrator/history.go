@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const (
+	historyPageSize      = 500       // Количество вычислений, читаемых из базы данных за один запрос при экспорте
+	maxHistoryImportSize = 100 << 20 // Максимальный размер тела запроса с импортируемой историей
+	historyDateLayout    = "2006-01-02"
+)
+
+// Столбцы CSV при экспорте истории. При импорте столбец id не обязателен и игнорируется.
+var historyCSVColumns = []string{"id", "operation", "result", "status", "created_time", "end_time"}
+
+// historyFormatError - ошибка в импортируемых данных, в отличие от ошибок базы данных.
+type historyFormatError struct {
+	error
+}
+
+func (e historyFormatError) Unwrap() error { return e.error }
+
+// parseHistoryTime разбирает границу интервала в формате RFC 3339 или YYYY-MM-DD.
+// Дата в качестве конца интервала включает весь день.
+func parseHistoryTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(historyDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected RFC 3339 or YYYY-MM-DD", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// parseHistoryFilter читает параметры from и to запроса экспорта.
+func parseHistoryFilter(r *http.Request) (models.HistoryFilter, error) {
+	var (
+		filter models.HistoryFilter
+		err    error
+	)
+	if filter.From, err = parseHistoryTime(r.URL.Query().Get("from"), false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseHistoryTime(r.URL.Query().Get("to"), true); err != nil {
+		return filter, err
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
+
+// historyWriter записывает экспортируемые записи в выбранном формате.
+type historyWriter struct {
+	format  string
+	w       io.Writer
+	csv     *csv.Writer
+	written int
+}
+
+func newHistoryWriter(w io.Writer, format string) *historyWriter {
+	hw := &historyWriter{format: format, w: w}
+	if format == batchFormatCSV {
+		hw.csv = csv.NewWriter(w)
+	}
+	return hw
+}
+
+// begin записывает начало документа: заголовок CSV или открывающую скобку массива JSON.
+func (hw *historyWriter) begin() error {
+	switch hw.format {
+	case batchFormatCSV:
+		return hw.csv.Write(historyCSVColumns)
+	case batchFormatJSON:
+		_, err := io.WriteString(hw.w, "[")
+		return err
+	}
+	return nil
+}
+
+func (hw *historyWriter) write(record models.HistoryRecord) error {
+	defer func() { hw.written++ }()
+
+	if hw.format == batchFormatCSV {
+		result, endTime := "", ""
+		if record.Result != nil {
+			result = strconv.FormatFloat(*record.Result, 'g', -1, 64)
+		}
+		if record.EndTime != nil {
+			endTime = record.EndTime.Format(time.RFC3339Nano)
+		}
+		return hw.csv.Write([]string{strconv.Itoa(record.ID), record.Operation, result, record.Status, record.CreatedTime.Format(time.RFC3339Nano), endTime})
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	switch {
+	case hw.format == batchFormatNDJSON:
+		data = append(data, '\n')
+	case hw.written > 0:
+		data = append([]byte(","), data...)
+	}
+	_, err = hw.w.Write(data)
+	return err
+}
+
+// flush отправляет клиенту накопленные данные.
+func (hw *historyWriter) flush() error {
+	if hw.csv != nil {
+		hw.csv.Flush()
+		if err := hw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := hw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// end завершает документ.
+func (hw *historyWriter) end() error {
+	if hw.format == batchFormatJSON {
+		if _, err := io.WriteString(hw.w, "]\n"); err != nil {
+			return err
+		}
+	}
+	return hw.flush()
+}
+
+// Типы содержимого экспортируемых файлов
+var historyContentTypes = map[string]string{
+	batchFormatJSON:   "application/json",
+	batchFormatNDJSON: "application/x-ndjson",
+	batchFormatCSV:    "text/csv; charset=utf-8",
+}
+
+// historyExportHandler обрабатывает GET /api/v1/calculations/export: отдает историю вычислений пользователя
+// в формате csv, json или ndjson (параметр format), читая её из базы данных постранично.
+// Параметры from и to ограничивают время создания вычислений.
+func historyExportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		format := strings.ToLower(r.URL.Query().Get("format"))
+		if format == "" {
+			format = batchFormatCSV
+		}
+		contentType, ok := historyContentTypes[format]
+		if !ok {
+			sendJSONError(w, "format must be one of csv, json, ndjson", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseHistoryFilter(r)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Первая страница читается до отправки заголовков, чтобы ошибка базы данных вернула 500
+		page, err := database.FetchCalculationsByUserPage(db, claims.UserID, 0, historyPageSize, filter)
+		if err != nil {
+			log.Printf("Error exporting calculations for user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="calculations.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+
+		hw := newHistoryWriter(w, format)
+		if err := hw.begin(); err != nil {
+			return
+		}
+		for len(page) > 0 {
+			for _, record := range page {
+				if err := hw.write(record); err != nil {
+					log.Printf("Error writing export for user %d: %v", claims.UserID, err)
+					return
+				}
+			}
+			if err := hw.flush(); err != nil {
+				log.Printf("Error writing export for user %d: %v", claims.UserID, err)
+				return
+			}
+			if len(page) < historyPageSize {
+				break
+			}
+
+			// После отправки заголовков о сбое можно сообщить только обрывом документа
+			page, err = database.FetchCalculationsByUserPage(db, claims.UserID, page[len(page)-1].ID, historyPageSize, filter)
+			if err != nil {
+				log.Printf("Error exporting calculations for user %d: %v", claims.UserID, err)
+				return
+			}
+		}
+		if err := hw.end(); err != nil {
+			log.Printf("Error writing export for user %d: %v", claims.UserID, err)
+		}
+	}
+}
+
+// checkHistoryRecord проверяет импортируемую запись; n - номер записи (строки) для сообщения об ошибке.
+// Возвращает false для записей, которые не импортируются, так как вычисление не было завершено.
+func checkHistoryRecord(record *models.HistoryRecord, n int) (bool, error) {
+	if record.Status != "completed" {
+		return false, nil
+	}
+	if strings.TrimSpace(record.Operation) == "" {
+		return false, historyFormatError{fmt.Errorf("record %d: operation is required", n)}
+	}
+	if record.Result == nil {
+		return false, historyFormatError{fmt.Errorf("record %d: result is required for completed calculations", n)}
+	}
+	if record.CreatedTime.IsZero() {
+		return false, historyFormatError{fmt.Errorf("record %d: created_time is required", n)}
+	}
+	if record.EndTime != nil && record.EndTime.Before(record.CreatedTime) {
+		return false, historyFormatError{fmt.Errorf("record %d: end_time is before created_time", n)}
+	}
+	return true, nil
+}
+
+// historyJSONReader возвращает функцию, читающую записи JSON массива по одной.
+func historyJSONReader(r io.Reader) func() (*models.HistoryRecord, error) {
+	decoder := json.NewDecoder(r)
+	started := false
+	return func() (*models.HistoryRecord, error) {
+		if !started {
+			started = true
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, historyFormatError{fmt.Errorf("invalid JSON array: %w", err)}
+			}
+			if token != json.Delim('[') {
+				return nil, historyFormatError{errors.New("invalid JSON array")}
+			}
+		}
+		if !decoder.More() {
+			return nil, io.EOF
+		}
+		var record models.HistoryRecord
+		if err := decoder.Decode(&record); err != nil {
+			return nil, historyFormatError{fmt.Errorf("invalid JSON array: %w", err)}
+		}
+		return &record, nil
+	}
+}
+
+// historyNDJSONReader возвращает функцию, читающую записи NDJSON по одной. Пустые строки пропускаются.
+func historyNDJSONReader(r io.Reader) func() (*models.HistoryRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBatchBodySize)
+	line := 0
+	return func() (*models.HistoryRecord, error) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var record models.HistoryRecord
+			if err := json.Unmarshal(text, &record); err != nil {
+				return nil, historyFormatError{fmt.Errorf("line %d: invalid JSON: %v", line, err)}
+			}
+			return &record, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, historyFormatError{err}
+		}
+		return nil, io.EOF
+	}
+}
+
+// historyCSVReader возвращает функцию, читающую записи CSV с заголовком в формате экспорта.
+func historyCSVReader(r io.Reader) func() (*models.HistoryRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	var columns map[string]int
+	line := 1
+
+	return func() (*models.HistoryRecord, error) {
+		if columns == nil {
+			header, err := reader.Read()
+			if err != nil {
+				return nil, historyFormatError{fmt.Errorf("reading CSV header: %w", err)}
+			}
+			columns = make(map[string]int, len(header))
+			for i, name := range header {
+				columns[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			for _, name := range historyCSVColumns[1:] {
+				if _, ok := columns[name]; !ok {
+					return nil, historyFormatError{fmt.Errorf("CSV header must contain a %s column", name)}
+				}
+			}
+		}
+
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		line++
+		if err != nil {
+			return nil, historyFormatError{fmt.Errorf("line %d: %w", line, err)}
+		}
+		column := func(name string) string { return strings.TrimSpace(row[columns[name]]) }
+
+		record := &models.HistoryRecord{Operation: column("operation"), Status: column("status")}
+		if value := column("result"); value != "" {
+			result, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, historyFormatError{fmt.Errorf("line %d: invalid result %q", line, value)}
+			}
+			record.Result = &result
+		}
+		if value := column("created_time"); value != "" {
+			if record.CreatedTime, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return nil, historyFormatError{fmt.Errorf("line %d: invalid created_time %q", line, value)}
+			}
+		}
+		if value := column("end_time"); value != "" {
+			endTime, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, historyFormatError{fmt.Errorf("line %d: invalid end_time %q", line, value)}
+			}
+			record.EndTime = &endTime
+		}
+		return record, nil
+	}
+}
+
+// Ответ на импорт истории
+type historyImportResponse struct {
+	Imported int `json:"imported"` // Количество созданных вычислений
+	Skipped  int `json:"skipped"`  // Количество пропущенных незавершенных вычислений
+}
+
+// historyImportHandler обрабатывает POST /api/v1/calculations/import: в одной транзакции создает
+// завершенные вычисления пользователя из истории в формате экспорта (JSON, NDJSON или CSV),
+// сохраняя исходные времена. Незавершенные вычисления пропускаются.
+func historyImportHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body := io.Reader(http.MaxBytesReader(w, r.Body, maxHistoryImportSize))
+		format := batchFormat(r.Header.Get("Content-Type"), "")
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				sendJSONError(w, fmt.Sprintf("missing file field: %v", err), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			body = file
+			format = batchFormat(header.Header.Get("Content-Type"), header.Filename)
+		}
+
+		var read func() (*models.HistoryRecord, error)
+		switch format {
+		case batchFormatCSV:
+			read = historyCSVReader(body)
+		case batchFormatNDJSON:
+			read = historyNDJSONReader(body)
+		default:
+			read = historyJSONReader(body)
+		}
+
+		var response historyImportResponse
+		n := 0
+		next := func() (*models.HistoryRecord, error) {
+			for {
+				record, err := read()
+				if err != nil {
+					return nil, err
+				}
+				n++
+				ok, err := checkHistoryRecord(record, n)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					return record, nil
+				}
+				response.Skipped++
+			}
+		}
+
+		response.Imported, err = database.ImportCalculations(db, claims.UserID, next)
+		var formatErr historyFormatError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			sendJSONError(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		case errors.As(err, &formatErr):
+			sendJSONError(w, formatErr.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			log.Printf("Error importing calculations for user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
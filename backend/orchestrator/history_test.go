@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var historyColumns = []string{"id", "operation", "result", "status", "created_time", "end_time"}
+
+func TestParseHistoryTime(t *testing.T) {
+	tests := []struct {
+		value string
+		end   bool
+		want  time.Time
+	}{
+		{"", false, time.Time{}},
+		{"2026-03-01", false, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-01", true, time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"2026-03-01T12:00:00+03:00", true, time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseHistoryTime(tt.value, tt.end)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseHistoryTime(%q, %v) = %v, %v; want %v", tt.value, tt.end, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseHistoryTime("yesterday", false); err == nil {
+		t.Error("Expected an error for an invalid date")
+	}
+}
+
+func TestHistoryExportHandlerPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	first := sqlmock.NewRows(historyColumns)
+	for id := 1; id <= historyPageSize; id++ {
+		first.AddRow(id, "2+2", 4.0, "completed", created, created.Add(time.Second))
+	}
+	mock.ExpectQuery("FROM calculations").WithArgs(4, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), historyPageSize).WillReturnRows(first)
+	mock.ExpectQuery("FROM calculations").WithArgs(4, historyPageSize, sqlmock.AnyArg(), sqlmock.AnyArg(), historyPageSize).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow(historyPageSize+1, "1/0", nil, "created", created, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/calculations/export?format=csv", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr := httptest.NewRecorder()
+	historyExportHandler(db).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="calculations.csv"` {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != historyPageSize+2 {
+		t.Fatalf("Expected header and %d rows, got %d lines", historyPageSize+1, len(lines))
+	}
+	if lines[0] != "id,operation,result,status,created_time,end_time" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if lines[1] != "1,2+2,4,completed,2026-03-01T10:00:00Z,2026-03-01T10:00:01Z" {
+		t.Errorf("Unexpected first row %q", lines[1])
+	}
+	if want := "501,1/0,,created,2026-03-01T10:00:00Z,"; lines[len(lines)-1] != want {
+		t.Errorf("Expected last row %q, got %q", want, lines[len(lines)-1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestHistoryExportHandlerFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `[{"id":1,"operation":"2+2","result":4,"status":"completed","created_time":"2026-03-01T10:00:00Z"},{"id":2,"operation":"2*2","result":null,"status":"work","created_time":"2026-03-01T10:00:00Z"}]` + "\n"},
+		{"ndjson", `{"id":1,"operation":"2+2","result":4,"status":"completed","created_time":"2026-03-01T10:00:00Z"}` + "\n" +
+			`{"id":2,"operation":"2*2","result":null,"status":"work","created_time":"2026-03-01T10:00:00Z"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+			from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+			mock.ExpectQuery("FROM calculations").WithArgs(4, 0, from, from.AddDate(0, 0, 1), historyPageSize).
+				WillReturnRows(sqlmock.NewRows(historyColumns).
+					AddRow(1, "2+2", 4.0, "completed", created, nil).
+					AddRow(2, "2*2", nil, "work", created, nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/calculations/export?format="+tt.format+"&from=2026-03-01&to=2026-03-01", nil)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+			rr := httptest.NewRecorder()
+			historyExportHandler(db).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			if rr.Body.String() != tt.want {
+				t.Errorf("Expected body\n%s\ngot\n%s", tt.want, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestHistoryExportHandlerBadRequest(t *testing.T) {
+	for _, query := range []string{"format=xml", "from=yesterday", "from=2026-03-02&to=2026-03-01"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/calculations/export?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+		rr := httptest.NewRecorder()
+		historyExportHandler(nil).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestHistoryImportHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"JSON", "application/json", `[
+			{"id":7,"operation":"2+2","result":4,"status":"completed","created_time":"2026-03-01T10:00:00Z","end_time":"2026-03-01T10:00:05Z"},
+			{"id":8,"operation":"2*2","result":null,"status":"work","created_time":"2026-03-01T10:00:00Z"},
+			{"operation":"3-1","result":2,"status":"completed","created_time":"2026-03-02T10:00:00Z"}]`},
+		{"NDJSON", "application/x-ndjson", `{"operation":"2+2","result":4,"status":"completed","created_time":"2026-03-01T10:00:00Z","end_time":"2026-03-01T10:00:05Z"}` + "\n" +
+			`{"operation":"2*2","status":"cancelled","created_time":"2026-03-01T10:00:00Z"}` + "\n\n" +
+			`{"operation":"3-1","result":2,"status":"completed","created_time":"2026-03-02T10:00:00Z"}` + "\n"},
+		{"CSV", "text/csv", "id,operation,result,status,created_time,end_time\n" +
+			"7,2+2,4,completed,2026-03-01T10:00:00Z,2026-03-01T10:00:05Z\n" +
+			"8,2*2,,created,2026-03-01T10:00:00Z,\n" +
+			"9,3-1,2,completed,2026-03-02T10:00:00Z,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+			second := created.AddDate(0, 0, 1)
+			mock.ExpectBegin()
+			prep := mock.ExpectPrepare("INSERT INTO calculations")
+			prep.ExpectExec().WithArgs(4, "2+2", 4.0, created, created.Add(5*time.Second)).WillReturnResult(sqlmock.NewResult(1, 1))
+			prep.ExpectExec().WithArgs(4, "3-1", 2.0, second, second).WillReturnResult(sqlmock.NewResult(2, 1))
+			mock.ExpectCommit()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculations/import", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+			rr := httptest.NewRecorder()
+			historyImportHandler(db).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+			}
+			var resp historyImportResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil || resp.Imported != 2 || resp.Skipped != 1 {
+				t.Errorf("Unexpected response %s", rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestHistoryImportHandlerRollsBackInvalidRecords(t *testing.T) {
+	tests := map[string]string{
+		"missing result":   `[{"operation":"2+2","status":"completed","created_time":"2026-03-01T10:00:00Z"},{"operation":"1+1","result":2,"status":"completed"}]`,
+		"malformed":        `[{"operation":"2+2","result":4,"status":"completed","created_time":"2026-03-01T10:00:00Z"},{"operation":`,
+		"not an array":     `{"operation":"2+2"}`,
+		"end before start": `[{"operation":"2+2","result":4,"status":"completed","created_time":"2026-03-01T10:00:00Z","end_time":"2026-02-01T10:00:00Z"}]`,
+	}
+
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.MatchExpectationsInOrder(false)
+			mock.ExpectBegin()
+			prep := mock.ExpectPrepare("INSERT INTO calculations")
+			prep.ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectRollback()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculations/import", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+			rr := httptest.NewRecorder()
+			historyImportHandler(db).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
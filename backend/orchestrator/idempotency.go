@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"     // Заголовок с ключом идемпотентности от клиента
+	idempotencyReplayHeader = "Idempotent-Replayed" // Заголовок, которым помечается повторно отданный ответ
+	idempotencyRetention    = 24 * time.Hour        // Время, в течение которого ключ защищает от повторной отправки
+	maxIdempotencyKeyLength = 255                   // Максимальная длина ключа
+)
+
+// idempotencyClaim - ключ идемпотентности, закрепленный за текущим запросом.
+// Методы допускают nil, когда клиент не передал ключ.
+type idempotencyClaim struct {
+	db        *sql.DB
+	id        int
+	completed bool
+}
+
+// hashIdempotentRequest вычисляет хэш запроса, по которому сравниваются повторы с одним ключом.
+func hashIdempotentRequest(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.URL.Path + "\n" + r.Header.Get("Content-Type") + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claimIdempotencyKey закрепляет ключ из заголовка Idempotency-Key за запросом пользователя userId.
+// Если ключ уже использован, сам отвечает клиенту: повтором исходного ответа при совпадении тела
+// или 409 Conflict при другом теле, и возвращает false. Без заголовка возвращает nil и true.
+func claimIdempotencyKey(db *sql.DB, w http.ResponseWriter, r *http.Request, userId int, body []byte) (*idempotencyClaim, bool) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		sendJSONError(w, "Idempotency-Key is too long", http.StatusBadRequest)
+		return nil, false
+	}
+
+	hash := hashIdempotentRequest(r, body)
+	claimed, record, err := database.ClaimIdempotencyKey(db, userId, key, r.URL.Path, hash, time.Now().UTC().Add(-idempotencyRetention))
+	if err != nil {
+		log.Printf("Error claiming idempotency key: %v", err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if claimed {
+		return &idempotencyClaim{db: db, id: record.ID}, true
+	}
+
+	switch {
+	case record.RequestHash != hash:
+		sendJSONError(w, "Idempotency-Key has already been used with a different request", http.StatusConflict)
+	case record.ResponseCode == 0:
+		sendJSONError(w, "A request with this Idempotency-Key is still being processed", http.StatusConflict)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(idempotencyReplayHeader, "true")
+		w.WriteHeader(record.ResponseCode)
+		w.Write([]byte(record.ResponseBody))
+	}
+	return nil, false
+}
+
+// respond отправляет JSON ответ и сохраняет его для повторов с тем же ключом.
+func (c *idempotencyClaim) respond(w http.ResponseWriter, statusCode int, value interface{}) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+
+	if c != nil {
+		if err := database.CompleteIdempotencyKey(c.db, c.id, statusCode, string(body)); err != nil {
+			log.Printf("Error saving idempotent response: %v", err)
+		} else {
+			c.completed = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// release освобождает ключ, если запрос не завершился успешным ответом, чтобы клиент мог повторить его.
+func (c *idempotencyClaim) release() {
+	if c == nil || c.completed {
+		return
+	}
+	if err := database.ReleaseIdempotencyKey(c.db, c.id); err != nil {
+		log.Printf("Error releasing idempotency key: %v", err)
+	}
+}
+
+// purgeIdempotencyKeys удаляет ключи, срок хранения которых истек.
+func purgeIdempotencyKeys(db *sql.DB) {
+	purged, err := database.PurgeExpiredIdempotencyKeys(db, time.Now().UTC().Add(-idempotencyRetention))
+	if err != nil {
+		log.Printf("Error purging idempotency keys: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d expired idempotency keys", purged)
+	}
+}
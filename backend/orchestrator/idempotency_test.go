@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newSubmitRequest(t *testing.T, body, key string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/submit-calculation", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 1))
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	return req
+}
+
+func TestSubmitCalculationIdempotentFirstRequest(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	body := `{"userId":1,"operation":"2+2","add_duration":1,"subtract_duration":1,"multiply_duration":1,"divide_duration":1,"inactive_server_time":60}`
+	mock.ExpectExec("DELETE FROM idempotency_keys").WithArgs(1, "key-1", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WithArgs(1, "key-1", "/submit-calculation", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	expectQueueReserve(mock, 1, 0)
+	mock.ExpectQuery("INSERT INTO calculations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(77))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE idempotency_keys SET response_code").WithArgs(200, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := httptest.NewRecorder()
+	submitCalculationHandler(db)(rr, newSubmitRequest(t, body, "key-1"))
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"id":77`) {
+		t.Errorf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSubmitCalculationIdempotentReplayAndConflict(t *testing.T) {
+	body := `{"userId":1,"operation":"2+2"}`
+	hash := hashIdempotentRequest(newSubmitRequest(t, body, ""), []byte(body))
+	stored := `{"id":77,"userId":1,"status":"created","operation":"2+2"}` + "\n"
+
+	tests := []struct {
+		name       string
+		body       string
+		code       int // Сохраненный код ответа, 0 означает NULL
+		wantStatus int
+		wantBody   string
+	}{
+		{"replay", body, 200, http.StatusOK, stored},
+		{"different body", `{"userId":1,"operation":"3+3"}`, 200, http.StatusConflict, "different request"},
+		{"in progress", body, 0, http.StatusConflict, "still being processed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			var code interface{}
+			if tt.code != 0 {
+				code = int64(tt.code)
+			}
+			mock.ExpectQuery("SELECT (.+) FROM idempotency_keys").WithArgs(1, "key-1").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "endpoint", "request_hash", "response_code", "response_body", "created_time"}).
+					AddRow(3, "/submit-calculation", hash, code, stored, time.Now()))
+
+			rr := httptest.NewRecorder()
+			submitCalculationHandler(db)(rr, newSubmitRequest(t, tt.body, "key-1"))
+
+			if rr.Code != tt.wantStatus || !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+			}
+			if tt.name == "replay" && rr.Header().Get(idempotencyReplayHeader) != "true" {
+				t.Error("Expected replayed response to be marked")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestSubmitCalculationIdempotencyBeforeRateLimit(t *testing.T) {
+	withQuotas(t, quotaConfig{RequestsPerMinute: 1, Burst: 1})
+	submitLimiter.allow(1, time.Now())
+
+	body := `{"userId":1,"operation":"2+2"}`
+	hash := hashIdempotentRequest(newSubmitRequest(t, body, ""), []byte(body))
+	stored := `{"id":77,"userId":1,"status":"created","operation":"2+2"}` + "\n"
+
+	t.Run("replay", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT (.+) FROM idempotency_keys").WithArgs(1, "key-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "endpoint", "request_hash", "response_code", "response_body", "created_time"}).
+				AddRow(3, "/submit-calculation", hash, int64(200), stored, time.Now()))
+
+		rr := httptest.NewRecorder()
+		submitCalculationHandler(db)(rr, newSubmitRequest(t, body, "key-1"))
+
+		if rr.Code != http.StatusOK || rr.Body.String() != stored {
+			t.Errorf("Expected replay despite exhausted rate limit, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("new key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		defer db.Close()
+
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectExec("DELETE FROM idempotency_keys").WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rr := httptest.NewRecorder()
+		submitCalculationHandler(db)(rr, newSubmitRequest(t, body, "key-2"))
+
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+}
@@ -5,14 +5,18 @@ import (
 	"context"       // Для работы с байтами
 	"database/sql"  // Для работы с базами данных SQL
 	"encoding/json" // Для кодирования и декодирования JSON
+	"errors"        // Для проверки типов ошибок
 	"fmt"           // Для форматированного вывода и ввода
+	"io"            // Для чтения тела запроса
 	"log"           // Для логирования
+	"math"          // Для округления оценки ожидания
 	"net/http"      // Для работы с HTTP
 	"strconv"       // Для конвертации строк в числа и обратно
-	"strings"
-	"time" // Для работы со временем
+	"sync"          // Для одновременного опроса агентов
+	"time"          // Для работы со временем
 
 	"github.com/golang-jwt/jwt/v4" // Для работы с токенами
+	"github.com/lib/pq"            // Для уведомлений PostgreSQL о новых вычислениях
 
 	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
 	"calculatorapi/utility/database" // Пакет для работы с базой данных
@@ -20,17 +24,48 @@ import (
 
 	"golang.org/x/crypto/bcrypt" // Драйвер для хэширования паролей
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// Структура для запроса калькуляции
+// Структура для запроса калькуляции.
+// Незаданные длительности берутся из настроек пользователя (/api/v1/settings).
 type CalculationRequest struct {
 	UserId             int    `json:"userId"`               // Идентификатор юзера
 	Operation          string `json:"operation"`            // Операция для калькуляции
-	AddDuration        int    `json:"add_duration"`         // Длительность операции сложения
-	SubtractDuration   int    `json:"subtract_duration"`    // Длительность операции вычитания
-	MultiplyDuration   int    `json:"multiply_duration"`    // Длительность операции умножения
-	DivideDuration     int    `json:"divide_duration"`      // Длительность операции деления
-	InactiveServerTime int    `json:"inactive_server_time"` // Время ожидания неактивного сервера
+	AddDuration        *int   `json:"add_duration"`         // Длительность операции сложения
+	SubtractDuration   *int   `json:"subtract_duration"`    // Длительность операции вычитания
+	MultiplyDuration   *int   `json:"multiply_duration"`    // Длительность операции умножения
+	DivideDuration     *int   `json:"divide_duration"`      // Длительность операции деления
+	InactiveServerTime *int   `json:"inactive_server_time"` // Время ожидания неактивного сервера
+	WebhookURL         string `json:"webhook_url"`          // Вебхук, уведомляемый о завершении вычисления (необязательно)
+	WebhookSecret      string `json:"webhook_secret"`       // Ключ HMAC подписи уведомлений, обязателен вместе с webhook_url
+	Priority           *int   `json:"priority"`             // Приоритет от 0 до 9, по умолчанию 5
+}
+
+// hasAllDurations сообщает, переданы ли в запросе все длительности.
+func (req CalculationRequest) hasAllDurations() bool {
+	return req.AddDuration != nil && req.SubtractDuration != nil && req.MultiplyDuration != nil &&
+		req.DivideDuration != nil && req.InactiveServerTime != nil
+}
+
+// withDefaults формирует запрос на вставку, заполняя незаданные длительности из настроек пользователя.
+// Незаданный приоритет заменяется на models.DefaultPriority.
+func (req CalculationRequest) withDefaults(settings models.UserSettings) models.CalculationRequest {
+	return models.CalculationRequest{
+		UserId:             req.UserId,
+		Operation:          req.Operation,
+		AddDuration:        withDefault(req.AddDuration, settings.AddDuration),
+		SubtractDuration:   withDefault(req.SubtractDuration, settings.SubtractDuration),
+		MultiplyDuration:   withDefault(req.MultiplyDuration, settings.MultiplyDuration),
+		DivideDuration:     withDefault(req.DivideDuration, settings.DivideDuration),
+		InactiveServerTime: withDefault(req.InactiveServerTime, settings.InactiveServerTime),
+		WebhookURL:         req.WebhookURL,
+		WebhookSecret:      req.WebhookSecret,
+		Priority:           withDefault(req.Priority, models.DefaultPriority),
+	}
 }
 
 // Структура для ответа на запрос калькуляции, содержащая id добавленной операции в базу данных
@@ -52,75 +87,129 @@ type Claims struct {
 
 var jwtKey = []byte("secret_key") // Secret key для подписания JWT токенов
 
-// Список развернутых серверов калькуляторов
-var servers = []string{
-	"http://localhost:8081",
-	"http://localhost:8082",
-}
-
-// Список развернутых серверов калькуляторов
-var GRPCservers = []string{
-	"http://localhost:50051",
-	"http://localhost:50052",
-}
+// Время ожидания ответа агента на проверку статуса
+var agentStatusTimeout = time.Duration(envInt("CALCULATOR_AGENT_STATUS_TIMEOUT_MS", 2000)) * time.Millisecond
 
 // Структура для статуса сервера калькулятора
 type ServerStatus struct {
-	URL               string `json:"url"`                     // URL сервера
-	Running           bool   `json:"running"`                 // Статус работы сервера
-	MaxGoroutines     int    `json:"maxGoroutines,omitempty"` // Максимальное количество горутин
-	CurrentGoroutines int    `json:"currentGoroutines"`       // Текущее количество горутин
-	Error             string `json:"error,omitempty"`         // Ошибка, если есть
+	URL               string            `json:"url"`                     // URL сервера
+	Running           bool              `json:"running"`                 // Статус работы сервера
+	MaxGoroutines     int               `json:"maxGoroutines,omitempty"` // Максимальное количество горутин
+	CurrentGoroutines int               `json:"currentGoroutines"`       // Текущее количество горутин
+	QueueDepth        int               `json:"queueDepth"`              // Количество вычислений, ожидающих исполнителя
+	QueueSize         int               `json:"queueSize"`               // Емкость очереди агента
+	EstimatedWait     int               `json:"estimatedWaitSeconds"`    // Оценка ожидания нового вычисления в очереди, в секундах
+	AgentID           string            `json:"agentId,omitempty"`       // Идентификатор агента
+	Labels            map[string]string `json:"labels,omitempty"`        // Метки агента
+	Pull              bool              `json:"pull,omitempty"`          // Агент сам запрашивает задачи, сведения о нем берутся из реестра
+	Connection        string            `json:"connection,omitempty"`    // Состояние gRPC соединения: READY, пока агент доступен и отвечает SERVING на проверку состояния
+	LastHeartbeat     time.Time         `json:"lastHeartbeat"`           // Время последнего сигнала активности агента
+	Version           string            `json:"version,omitempty"`       // Версия сборки агента
+	UptimeSeconds     int64             `json:"uptimeSeconds"`           // Время работы агента
+	Completed         int64             `json:"completed"`               // Завершенные агентом вычисления
+	Failed            int64             `json:"failed"`                  // Прерванные агентом вычисления
+	Runtime           *RuntimeStatus    `json:"runtime,omitempty"`       // Состояние среды выполнения Go агента
+	Error             string            `json:"error,omitempty"`         // Ошибка, если есть
 }
 
-// Функция для проверки статуса всех серверов калькуляторов
-func pingServers() []ServerStatus {
-	var statuses []ServerStatus // Список статусов серверов
+// Состояние среды выполнения Go агента
+type RuntimeStatus struct {
+	GoVersion      string `json:"goVersion"`
+	NumCPU         int    `json:"numCpu"`
+	Goroutines     int    `json:"goroutines"`
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	SysBytes       uint64 `json:"sysBytes"`
+	NumGC          uint32 `json:"numGc"`
+}
 
-	for _, serverURL := range servers {
-		status := ServerStatus{URL: serverURL}
-		resp, err := http.Get(fmt.Sprintf("%s/ping", serverURL))
-		if err != nil {
-			// Если запрос не удался, сервер считается неактивным
-			status.Running = false
-			status.Error = err.Error()
-		} else {
-			defer resp.Body.Close()
-			// Если сервер ответил успешно, читаем ответ
-			if resp.StatusCode == http.StatusOK {
-				var serverResponse struct {
-					Status            string `json:"status"`
-					MaxGoroutines     int    `json:"maxGoroutines"`
-					CurrentGoroutines int    `json:"currentGoroutines"`
-				}
-				if err := json.NewDecoder(resp.Body).Decode(&serverResponse); err != nil {
-					// Если не удалось декодировать ответ, записываем ошибку
-					status.Error = "Failed to decode response"
-				} else {
-					// Если декодирование успешно, обновляем статус сервера
-					status.Running = serverResponse.Status == "running"
-					status.MaxGoroutines = serverResponse.MaxGoroutines
-					status.CurrentGoroutines = serverResponse.CurrentGoroutines
-				}
-			} else {
-				// Если статус ответа не OK, сервер считается неактивным
-				status.Running = false
-				status.Error = fmt.Sprintf("Unexpected status code: %d", resp.StatusCode)
-			}
-		}
-		statuses = append(statuses, status) // Добавляем статус в список
+// Функция для проверки статуса всех зарегистрированных серверов калькуляторов.
+// Агенты опрашиваются одновременно через gRPC CheckStatus, каждый с ограничением agentStatusTimeout.
+func pingServers() []ServerStatus {
+	agents := liveAgents(time.Now())
+	statuses := make([]ServerStatus, len(agents)) // Список статусов серверов в порядке реестра
+
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		wg.Add(1)
+		go func(i int, agent registeredAgent) {
+			defer wg.Done()
+			statuses[i] = checkServerStatus(agent)
+		}(i, agent)
 	}
+	wg.Wait()
 
 	return statuses
 }
 
+// checkServerStatus запрашивает статус зарегистрированного агента.
+// Состояние соединения записывается после запросов, чтобы отразить их результат.
+func checkServerStatus(agent registeredAgent) (status ServerStatus) {
+	status = ServerStatus{
+		URL:           agent.URL,
+		AgentID:       agent.ID,
+		Labels:        agent.Labels,
+		LastHeartbeat: agent.LastHeartbeat,
+	}
+
+	// К агенту, который сам запрашивает задачи, оркестратор не подключается: он может быть недоступен по сети
+	if agent.Pull {
+		status.Pull = true
+		status.Running = true
+		status.MaxGoroutines = agent.MaxConcurrency
+		status.CurrentGoroutines = agent.Running
+		status.QueueDepth = agent.QueueDepth
+		status.QueueSize = agent.QueueSize
+		status.Version = agent.Version
+		return status
+	}
+
+	conn, err := agentConns.get(agent.GRPCAddr)
+	if err != nil {
+		// Если подключиться не удалось, сервер считается неактивным
+		status.Error = err.Error()
+		return status
+	}
+	defer func() { status.Connection = conn.GetState().String() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), agentStatusTimeout)
+	defer cancel()
+	resp, err := pb.NewCalculatorServiceClient(conn).CheckStatus(ctx, &pb.StatusRequest{})
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	status.Running = resp.Running
+	status.MaxGoroutines = int(resp.MaxGoroutines)
+	status.CurrentGoroutines = int(resp.CurrentGoroutines)
+	status.QueueDepth = int(resp.QueueDepth)
+	status.QueueSize = int(resp.QueueSize)
+	status.EstimatedWait = int(math.Ceil(resp.EstimatedWaitSeconds))
+	status.AgentID = resp.AgentId
+	status.Version = resp.Version
+	status.UptimeSeconds = resp.UptimeSeconds
+	status.Completed = resp.Completed
+	status.Failed = resp.Failed
+	if rt := resp.Runtime; rt != nil {
+		status.Runtime = &RuntimeStatus{
+			GoVersion:      rt.GoVersion,
+			NumCPU:         int(rt.NumCpu),
+			Goroutines:     int(rt.Goroutines),
+			HeapAllocBytes: rt.HeapAllocBytes,
+			SysBytes:       rt.SysBytes,
+			NumGC:          rt.NumGc,
+		}
+	}
+	return status
+}
+
 // Миддлвар для добавления заголовков CORS к ответам сервера
 func enableCORS(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Устанавливаем заголовки CORS
 		w.Header().Set("Access-Control-Allow-Origin", "*") // or you can specify the exact origin instead of "*"
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key")
 
 		// Если запрос является предварительным запросом CORS, отправляем ответ 200 OK
 		if r.Method == "OPTIONS" {
@@ -133,31 +222,73 @@ func enableCORS(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// Функция для отправки калькуляций на серверы калькуляторов
+// Функция для отправки калькуляций на серверы калькуляторов.
+// За проход из очереди выбирается столько вычислений, сколько агенты могут принять.
+// Порядок, в котором агентам предлагается каждое вычисление, определяет стратегия selector.
 func submitCalculations(db *sql.DB) {
-	calculations, err := database.FetchCalculationsToProcess(db)
+	limit := dispatchCapacity(pushAgents(liveAgents(time.Now())), time.Now())
+	if limit == 0 {
+		return // Агентов нет или все заняты, проход повторится, когда место освободится
+	}
+	calculations, err := database.FetchCalculationsToProcess(db, limit, quotas.MaxRunning, priorityAging)
 	if err != nil {
 		log.Printf("Error fetching calculations to process: %v", err)
 		return
 	}
 
+	dispatched := 0
 	for _, calc := range calculations {
-		submitted := false
-		for _, serverURL := range servers {
-			if trySubmitCalculation(serverURL, calc) {
-				submitted = true
-				break // Прекращаем попытки, если успешно отправлено
+		if dispatchCalculation(db, calc) {
+			dispatched++
+		} else {
+			log.Printf("Failed to submit calculation ID %d to any server", calc.ID)
+		}
+	}
+
+	// Выбрана полная партия и вся отправлена: в очереди может остаться работа для освободившихся агентов
+	if len(calculations) == limit && dispatched == limit {
+		dispatch.notify()
+	}
+}
+
+// dispatchCalculation предлагает следующую попытку вычисления calc агентам в порядке стратегии selector.
+// Попытка записывается в базу данных до отправки, чтобы отчеты агента сверялись с ней; если ни один агент
+// вычисление не принял, запись отменяется. Возвращает true, если вычисление принято агентом.
+func dispatchCalculation(db *sql.DB, calc models.CalculationRequest) bool {
+	attempt := calc.Attempts + 1
+	recordedAgent := "" // Агент, которому попытка записана в базе данных
+	for _, agent := range selector.order(pushAgents(liveAgents(time.Now()))) {
+		if busyAgents.busy(agent.URL, time.Now()) {
+			continue // Очередь агента заполнена, ждем указанного им времени
+		}
+		if agentConns.failing(agent.GRPCAddr) {
+			continue // Агент недоступен или не проходит проверку состояния
+		}
+		if !recordDispatch(db, calc.ID, attempt, agent, recordedAgent) {
+			return false
+		}
+		recordedAgent = agent.ID
+		if trySubmitCalculation(agent, calc, attempt) {
+			registry.noteDispatch(agent.ID)
+			if err := database.MarkCalculationQueued(db, calc.ID, attempt, agent.ID); err != nil {
+				log.Printf("Error marking calculation ID %d as queued: %v", calc.ID, err)
 			}
+			log.Printf("Calculation ID %d dispatched to server %s, attempt %d", calc.ID, agent.URL, attempt)
+			return true // Прекращаем попытки, если успешно отправлено
 		}
-		if !submitted {
-			log.Printf("Failed to submit calculation ID %d to any server", calc.ID)
+	}
+	if recordedAgent != "" {
+		if err := database.ReleaseDispatch(db, calc.ID, attempt); err != nil {
+			log.Printf("Error releasing dispatch of calculation ID %d: %v", calc.ID, err)
 		}
 	}
+	return false
 }
 
-func trySubmitCalculation(serverURL string, calc models.CalculationRequest) bool {
-	// Create a gRPC request from the CalculationRequest
-	req := &pb.CalculationRequest{
+// calculationRequest формирует gRPC запрос попытки attempt вычисления calc.
+func calculationRequest(calc models.CalculationRequest, attempt int) *pb.CalculationRequest {
+	operationTime := calculateTotalOperationTime(calc.Operation, calc.AddDuration, calc.SubtractDuration, calc.MultiplyDuration, calc.DivideDuration)
+	return &pb.CalculationRequest{
 		Id:        int32(calc.ID),
 		Operation: calc.Operation,
 		Times: map[string]int32{
@@ -166,30 +297,35 @@ func trySubmitCalculation(serverURL string, calc models.CalculationRequest) bool
 			"multiply_duration": int32(calc.MultiplyDuration),
 			"divide_duration":   int32(calc.DivideDuration),
 		},
+		// После этого срока оркестратор переназначит вычисление, поэтому агенту нет смысла его продолжать
+		Deadline: timestamppb.New(calculationDeadline(time.Now().UTC(), operationTime, calc.InactiveServerTime)),
+		Attempt:  int32(attempt),
 	}
+}
 
-	// Get the index of the serverURL in the servers list
-	index := -1
-	for i, s := range servers {
-		if s == serverURL {
-			index = i
-			break
-		}
-	}
-
-	if index == -1 {
-		log.Printf("Server URL %s not found in the servers list", serverURL)
+// recordDispatch запоминает, какому агенту и в какой попытке отправляется вычисление.
+// previousAgentID - агент, которому эта попытка уже была записана, или пустая строка для новой попытки.
+// Возвращает false, если вычисление отправлять уже не нужно или запись не удалась.
+func recordDispatch(db *sql.DB, id, attempt int, agent registeredAgent, previousAgentID string) bool {
+	recorded, err := database.RecordDispatch(db, id, attempt, agent.URL, agent.ID, previousAgentID)
+	if err != nil {
+		log.Printf("Error recording dispatch of calculation ID %d to server %s: %v", id, agent.URL, err)
 		return false
 	}
+	if !recorded {
+		log.Printf("Calculation ID %d is already finished, cancelled or dispatched elsewhere, not dispatching it", id)
+	}
+	return recorded
+}
 
-	// Get the corresponding gRPC server URL from the GRPCservers list
-	grpcServerURL := GRPCservers[index]
-
-	// Remove the "http://" part from the grpcServerURL
-	grpcServerURL = strings.TrimPrefix(grpcServerURL, "http://")
-
+// trySubmitCalculation отправляет попытку attempt вычисления зарегистрированному агенту.
+func trySubmitCalculation(agent registeredAgent, calc models.CalculationRequest, attempt int) bool {
 	// Call the startCalculationGRPC function to start the calculation via gRPC
-	return startCalculationGRPC(grpcServerURL, req)
+	started, retryAfter := startCalculationGRPC(agent.GRPCAddr, calculationRequest(calc, attempt))
+	if retryAfter > 0 {
+		busyAgents.hold(agent.URL, time.Now().Add(retryAfter))
+	}
+	return started
 }
 
 // // Попытка отправить калькуляцию на указанный сервер
@@ -235,6 +371,17 @@ func trySubmitCalculation(serverURL string, calc models.CalculationRequest) bool
 //     return false
 // }
 
+// calculationDeadline возвращает момент, после которого начатое в start вычисление считается зависшим:
+// ожидаемое время выполнения operationTime плюс время ожидания неактивного сервера inactiveTime (в секундах).
+// Если время ожидания не задано, используется запас retries.Grace.
+func calculationDeadline(start time.Time, operationTime, inactiveTime int) time.Time {
+	grace := time.Duration(inactiveTime) * time.Second
+	if inactiveTime <= 0 {
+		grace = retries.Grace
+	}
+	return start.Add(time.Duration(operationTime)*time.Second + grace)
+}
+
 // calculateTotalOperationTime рассчитывает общее время выполнения операции.
 // Входные данные: строка операции и время выполнения для каждого типа операций.
 // Возвращает общее время выполнения операции в секундах.
@@ -259,13 +406,15 @@ func calculateTotalOperationTime(operation string, addDuration, subtractDuration
 	return totalDuration
 }
 
-// checkAndRestartFailedOperations проверяет и перезапускает операции, которые не были завершены в ожидаемое время.
+// checkAndRestartFailedOperations проверяет операции, которые не были завершены в ожидаемое время,
+// и повторяет их или переводит в статус 'failed' по политике повторов retries.
 func checkAndRestartFailedOperations(db *sql.DB) {
 	log.Println("Starting checkAndRestartFailedOperations")
 
 	// SQL-запрос для получения операций со статусом 'work'
 	query := `
-        SELECT id, userId, operation, start_time, add_duration, subtract_duration, multiply_duration, divide_duration
+        SELECT id, userId, operation, start_time, add_duration, subtract_duration, multiply_duration, divide_duration,
+            COALESCE(inactive_server_time, 0), attempts - attempts_base
         FROM calculations
         WHERE status = 'work'
     `
@@ -291,33 +440,26 @@ func checkAndRestartFailedOperations(db *sql.DB) {
 			subtractDuration int
 			multiplyDuration int
 			divideDuration   int
+			inactiveTime     int
+			attempts         int // Попытки после последнего возврата в очередь администратором
 		)
 
-		if err := rows.Scan(&id, &userId, &operation, &startTime, &addDuration, &subtractDuration, &multiplyDuration, &divideDuration); err != nil {
+		if err := rows.Scan(&id, &userId, &operation, &startTime, &addDuration, &subtractDuration, &multiplyDuration, &divideDuration, &inactiveTime, &attempts); err != nil {
 			log.Printf("Error scanning 'work' status operation: %v", err)
 			continue
 		}
 
+		// Вычисление переназначается, если агент не ответил за время вычисления плюс время ожидания неактивного сервера.
+		// Время отсчитывается от начала выполнения, а пока вычисление ждет в очереди агента - от его принятия
 		operationTime := calculateTotalOperationTime(operation, addDuration, subtractDuration, multiplyDuration, divideDuration)
-		expectedEndTime := startTime.Add(time.Duration(operationTime) * time.Second).Add(3 * time.Minute)
+		expectedEndTime := calculationDeadline(startTime, operationTime, inactiveTime)
 
 		log.Printf("Operation ID %d, User Id: %d Start time: %v, Operation time: %d seconds, Expected end time: %v", id, userId, startTime, operationTime, expectedEndTime)
 
-		// Если текущее время превышает ожидаемое время завершения операции, обновляем статус на 'created'
+		// Если текущее время превышает ожидаемое время завершения операции, попытка считается неудачной
 		if now.After(expectedEndTime) {
-			log.Printf("Operation ID %d exceeded expected end time. Resetting status to 'created'.", id)
-
-			resetQuery := `
-                UPDATE calculations
-                SET status = 'created', start_time = NULL
-                WHERE id = $1
-            `
-
-			if _, err := db.Exec(resetQuery, id); err != nil {
-				log.Printf("Error resetting operation ID %d to 'created': %v", id, err)
-			} else {
-				log.Printf("Operation ID %d has been reset to 'created' due to timeout.", id)
-			}
+			log.Printf("Operation ID %d exceeded expected end time after attempt %d.", id, attempts)
+			handleFailedAttempt(db, id, userId, operation, attempts, "timed out", now)
 		} else {
 			log.Printf("Operation ID %d is still within the expected time frame.", id)
 		}
@@ -330,6 +472,91 @@ func checkAndRestartFailedOperations(db *sql.DB) {
 	log.Println("Completed checkAndRestartFailedOperations")
 }
 
+// submitCalculationHandler обрабатывает запросы на добавление новых вычислений от имени пользователя из JWT токена.
+// Повтор запроса с тем же заголовком Idempotency-Key возвращает исходное вычисление.
+func submitCalculationHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Возвращаем ошибку, если метод запроса не POST
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Ограничения применяются к пользователю из токена, а не к userId из тела запроса
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var req CalculationRequest
+		// Декодирование тела запроса в структуру CalculationRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			// В случае ошибки декодирования возвращаем ошибку Bad Request
+			sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := req.validate(); err != nil {
+			var exprErr invalidExpressionError
+			if errors.As(err, &exprErr) {
+				sendExpressionError(w, exprErr)
+				return
+			}
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.UserId != 0 && req.UserId != claims.UserID {
+			sendJSONError(w, "userId does not match the authenticated user", http.StatusForbidden)
+			return
+		}
+		req.UserId = claims.UserID
+
+		// Повтор с тем же ключом получает сохраненный ответ и не расходует лимит запросов
+		claim, ok := claimIdempotencyKey(db, w, r, req.UserId, body)
+		if !ok {
+			return
+		}
+		defer claim.release()
+
+		if !allowSubmission(w, req.UserId) {
+			return
+		}
+
+		// Вставка данных о вычислении в базу данных
+		id, err := createCalculation(db, req)
+		var quotaErr quotaExceededError
+		if errors.As(err, &quotaErr) {
+			sendJSONError(w, quotaErr.Error(), http.StatusTooManyRequests)
+			return
+		}
+		// В случае ошибки при записи в базу данных возвращаем ошибку сервера
+		if err != nil {
+			log.Printf("Error creating calculation: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type CalculationResponse struct {
+			ID        int    `json:"id"`
+			UserId    int    `json:"userId"`
+			Status    string `json:"status"`
+			Operation string `json:"operation"`
+			Priority  int    `json:"priority"`
+		}
+
+		// Создаем ответ сервера с ID созданного вычисления
+		resp := CalculationResponse{ID: id, UserId: req.UserId, Status: "created", Operation: req.Operation, Priority: withDefault(req.Priority, models.DefaultPriority)}
+		claim.respond(w, http.StatusOK, resp)
+	}
+}
+
 // Функция для отправки JSON ошибок
 func sendJSONError(w http.ResponseWriter, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -337,117 +564,83 @@ func sendJSONError(w http.ResponseWriter, message string, statusCode int) {
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
-func startCalculationGRPC(serverURL string, req *pb.CalculationRequest) bool {
-	// Create a gRPC connection to the server
-	conn, err := grpc.Dial(serverURL, grpc.WithInsecure())
+// startCalculationGRPC отправляет вычисление агенту по соединению из пула. Если очередь агента заполнена,
+// вторым значением возвращается время, через которое агент готов принять вычисление.
+func startCalculationGRPC(serverURL string, req *pb.CalculationRequest) (bool, time.Duration) {
+	conn, err := agentConns.get(serverURL)
 	if err != nil {
 		log.Printf("Failed to dial server %s: %v", serverURL, err)
-		return false
+		return false, 0
 	}
-	defer conn.Close()
 
 	// Create a gRPC client
 	client := pb.NewCalculatorServiceClient(conn)
 
 	// Call the PerformCalculation RPC method
-	resp, err := client.PerformCalculation(context.Background(), req)
+	ctx, cancel := context.WithTimeout(context.Background(), agentDispatchTimeout)
+	defer cancel()
+	var header metadata.MD
+	resp, err := client.PerformCalculation(ctx, req, grpc.Header(&header))
+	if status.Code(err) == codes.ResourceExhausted {
+		retryAfter := agentRetryAfter(header)
+		log.Printf("Server %s queue is full, retrying in %v", serverURL, retryAfter)
+		return false, retryAfter
+	}
 	if err != nil {
 		log.Printf("Failed to start calculation on server %s: %v", serverURL, err)
-		return false
+		return false, 0
 	}
 
 	// Check if the response indicates success
 	if resp != nil && resp.Id == req.Id {
 		log.Printf("Successfully started calculation ID %d on server %s", req.Id, serverURL)
-		return true
+		return true, 0
 	}
 
 	log.Printf("Failed to start calculation ID %d on server %s", req.Id, serverURL)
-	return false
+	return false, 0
 }
 
-// Основная функция, запускающая сервер
-func main() {
-	// Инициализация соединения с базой данных на старте приложения
-	database.InitializeDB()
-	database.SetupDatabase()
+// callerID возвращает идентификатор пользователя из токена запроса или 0, если действительного токена нет.
+func callerID(r *http.Request) int {
+	claims, err := authenticateRequest(r)
+	if err != nil {
+		return 0
+	}
+	return claims.UserID
+}
 
-	// Определение канала для управления выключением
-	shutdownCh := make(chan struct{})
+// hideAgentDetails убирает из сведений о выполнении идентификатор агента и номер попытки.
+// Обработчики без обязательной авторизации показывают их только владельцу вычисления.
+func hideAgentDetails(execution *models.CalculationExecution) {
+	execution.AgentID, execution.Attempts = "", 0
+}
 
-	// Горутина периодической отправки задач на калькуляторы
-	go func() {
-		db := database.GetDB()                     // Получение глобального объекта базы данных
-		ticker := time.NewTicker(30 * time.Second) // Таймер для периодической проверки
-		defer ticker.Stop()
+// registerRoutes регистрирует обработчики HTTP API оркестратора и возвращает их шаблоны путей.
+// Каждый обработчик получает заголовки CORS и проверку тела запроса по спецификации OpenAPI.
+func registerRoutes(mux *http.ServeMux, db *sql.DB) []string {
+	var patterns []string
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, enableCORS(validateRequest(handler)))
+		patterns = append(patterns, pattern)
+	}
 
-		for {
-			select {
-			case <-ticker.C:
-				submitCalculations(db) // Отправка вычислений на обработку
-			case <-shutdownCh:
-				log.Println("Stopping submission of new calculations.")
-				return
-			}
-		}
-	}()
+	// Спецификация OpenAPI всех эндпоинтов.
+	handle("/api/v1/openapi.json", openAPIHandler)
 
 	// Обработчик для эндпоинта /submit-calculation.
 	// Принимает запросы на добавление новых вычислений.
-	http.HandleFunc("/submit-calculation", enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		// Возвращаем ошибку, если метод запроса не POST
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
-		var req CalculationRequest
-		// Декодирование тела запроса в структуру CalculationRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			// В случае ошибки декодирования возвращаем ошибку Bad Request
-			http.Error(w, "Bad request", http.StatusBadRequest)
-			return
-		}
-
-		// fmt.Println("AddDuration:", req.AddDuration)
-		// fmt.Println("SubtractDuration:", req.SubtractDuration)
-		// fmt.Println("MultiplyDuration:", req.MultiplyDuration)
-		// fmt.Println("DivideDuration:", req.DivideDuration)
-		// fmt.Println("InactiveServerTime:", req.InactiveServerTime)
-
-		// Вставка данных о вычислении в базу данных
-		db := database.GetDB()
-		id, err := database.InsertCalculation(db, req.UserId, req.Operation, req.AddDuration, req.SubtractDuration, req.MultiplyDuration, req.DivideDuration, req.InactiveServerTime)
-		// В случае ошибки при записи в базу данных возвращаем ошибку сервера
-		if err != nil {
-			log.Fatal("Error writing data to database:", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
-		}
-
-		type CalculationResponse struct {
-			ID        int    `json:"id"`
-			UserId    int    `json:"userId"`
-			Status    string `json:"status"`
-			Operation string `json:"operation"`
-		}
-
-		// Создаем ответ сервера с ID созданного вычисления
-		status := "created"
-		resp := CalculationResponse{ID: id, UserId: req.UserId, Status: status, Operation: req.Operation}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
-	}))
+	handle("/submit-calculation", submitCalculationHandler(db))
 
 	// Обработчик для проверки статуса серверов калькуляторов.
-	http.HandleFunc("/ping-servers", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	handle("/ping-servers", func(w http.ResponseWriter, r *http.Request) {
 		statuses := pingServers() // Получение статусов серверов
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(statuses)
-	}))
+	})
 
 	// Обработчик для получения статуса оркестратора.
-	http.HandleFunc("/orchestrator-status", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	handle("/orchestrator-status", func(w http.ResponseWriter, r *http.Request) {
 		status := struct {
 			Running bool   `json:"running"`
 			Message string `json:"message"`
@@ -458,10 +651,12 @@ func main() {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(status)
-	}))
+	})
 
 	// Обработчик для получения результата вычисления по ID.
-	http.HandleFunc("/get-calculation-result", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	// Параметр ?wait=30s включает long-polling: ответ откладывается до следующей смены статуса
+	// незавершенного вычисления, но не дольше указанного времени. Ждать изменений может только владелец вычисления.
+	handle("/get-calculation-result", func(w http.ResponseWriter, r *http.Request) {
 		// Parse query parameters
 		idParam := r.URL.Query().Get("id")
 		if idParam == "" {
@@ -475,42 +670,126 @@ func main() {
 			return
 		}
 
-		db := database.GetDB()
+		wait, err := parseWaitParam(r.URL.Query().Get("wait"))
 		if err != nil {
-			log.Printf("Error setting up the database: %v", err)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
+		// Подписываемся до чтения результата, чтобы не пропустить смену статуса
+		var (
+			sub    *eventSubscriber
+			claims *Claims
+		)
+		if wait > 0 {
+			if claims, err = authenticateRequest(r); err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			sub = events.subscribe(id, 0)
+			defer events.unsubscribe(sub)
+		}
+
 		result, err := database.GetCalculationResultByID(db, id)
 		if err != nil {
 			log.Printf("Error fetching calculation result: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if claims != nil && result.UserId != claims.UserID {
+			http.Error(w, "Calculation not found", http.StatusNotFound)
+			return
+		}
+
+		if sub != nil && !isTerminalStatus(result.Status) {
+			events.remember(result.ID, result.Status)
+			if waitForStatusChange(r.Context(), sub, result.Status, wait) {
+				result, err = database.GetCalculationResultByID(db, id)
+				if err != nil {
+					log.Printf("Error fetching calculation result: %v", err)
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		if result.UserId != callerID(r) {
+			hideAgentDetails(&result.CalculationExecution)
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(result)
-	}))
+	})
 
-	// Обработчик для получения всех вычислений из базы данных.
-	http.HandleFunc("/get-all-calculations", enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		db := database.GetDB()
+	// SSE поток изменений статуса одного вычисления.
+	handle("/api/v1/expressions/{id}/events", expressionEventsHandler(db))
+
+	// SSE поток изменений статусов всех вычислений авторизованного пользователя.
+	handle("/api/v1/events", userEventsHandler(db))
+
+	// Управление вебхуками пользователя и журналом их доставки.
+	handle("/api/v1/webhooks", webhooksHandler(db))
+	handle("/api/v1/webhooks/{id}", webhookHandler(db))
+	handle("/api/v1/webhooks/deliveries", webhookDeliveriesHandler(db))
+	handle("/api/v1/webhooks/deliveries/{id}/redeliver", webhookRedeliverHandler(db))
+
+	// Пакетная отправка вычислений, сводный статус и отмена пакета.
+	handle("/api/v1/batches", batchesHandler(db))
+	handle("/api/v1/batches/{id}", batchHandler(db))
+	handle("/api/v1/batches/{id}/cancel", batchCancelHandler(db))
+
+	// Настройки пользователя: длительности операций по умолчанию и параметры отображения.
+	handle("/api/v1/settings", settingsHandler(db))
+
+	// Ограничения пользователя и их текущее использование.
+	handle("/api/v1/quota", quotaHandler(db))
+
+	// Расписания однократных и повторяющихся вычислений.
+	handle("/api/v1/schedules", schedulesHandler(db))
+	handle("/api/v1/schedules/{id}", scheduleHandler(db))
+	handle("/api/v1/schedules/{id}/calculations", scheduleCalculationsHandler(db))
 
+	// Изменение приоритета вычисления в очереди администратором.
+	handle("/api/v1/admin/calculations/{id}/priority", calculationPriorityHandler(db))
+
+	// Удаление вычислений в корзину, восстановление и очистка корзины.
+	handle("/api/v1/calculations", calculationsHandler(db))
+	handle("/api/v1/calculations/{id}", calculationHandler(db))
+	handle("/api/v1/trash", trashHandler(db))
+	handle("/api/v1/trash/{id}/restore", trashRestoreHandler(db))
+
+	// Экспорт и импорт истории вычислений пользователя.
+	handle("/api/v1/calculations/export", historyExportHandler(db))
+	handle("/api/v1/calculations/import", historyImportHandler(db))
+
+	// Просмотр и повторный запуск неудавшихся вычислений администратором.
+	handle("/api/v1/admin/calculations/failed", failedCalculationsHandler(db))
+	handle("/api/v1/admin/calculations/{id}/requeue", calculationRequeueHandler(db))
+
+	// Проверка выражения без сохранения.
+	handle("/api/v1/validate", validateHandler)
+
+	// Обработчик для получения всех вычислений из базы данных.
+	handle("/get-all-calculations", func(w http.ResponseWriter, r *http.Request) {
 		calculations, err := database.FetchAllCalculations(db)
 		if err != nil {
 			log.Printf("Error fetching all calculations: %v", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
+		caller := callerID(r)
+		for i := range calculations {
+			if calculations[i].UserId != caller {
+				hideAgentDetails(&calculations[i].CalculationExecution)
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(calculations)
-	}))
+	})
 
 	// Обработчик для получения всех вычислений по userId.
-	http.HandleFunc("/get-calculations-by-user", enableCORS(func(w http.ResponseWriter, r *http.Request) {
-		db := database.GetDB()
+	handle("/get-calculations-by-user", func(w http.ResponseWriter, r *http.Request) {
 		userIdParam := r.URL.Query().Get("userId") // Получение userId из параметров запроса.
 
 		if userIdParam == "" {
@@ -530,19 +809,28 @@ func main() {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
+		if userId != callerID(r) {
+			for i := range calculations {
+				hideAgentDetails(&calculations[i].CalculationExecution)
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(calculations)
-	}))
+	})
 
 	// Обработчик для очистки всех вычислений из базы данных.
-	http.HandleFunc("/clear-all-calculations", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	handle("/clear-all-calculations", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		db := database.GetDB()
+		// Удаление вычислений всех пользователей доступно только администраторам.
+		claims, ok := authenticateAdmin(w, r)
+		if !ok {
+			return
+		}
 
 		if err := database.ClearAllCalculations(db); err != nil {
 			log.Printf("Error clearing all calculations: %v", err)
@@ -550,12 +838,13 @@ func main() {
 			return
 		}
 
+		log.Printf("Admin %s cleared all calculations", claims.Login)
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "All calculations have been cleared successfully.")
-	}))
+	})
 
 	// Обработчик для регистрации нового пользователя по логину и паролю.
-	http.HandleFunc("/api/v1/register", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/register", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -569,7 +858,7 @@ func main() {
 		}
 
 		// Call the database function to insert the new user
-		err = database.RegisterUser(database.GetDB(), newUser.Login, newUser.Password)
+		err = database.RegisterUser(db, newUser.Login, newUser.Password)
 		if err != nil {
 			log.Printf("Error registering user: %v", err)
 			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
@@ -578,10 +867,10 @@ func main() {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]bool{"success": true})
-	}))
+	})
 
 	// Обработчик для получения пользователя по логину через POST-запрос.
-	http.HandleFunc("/get-user", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	handle("/get-user", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
@@ -604,7 +893,6 @@ func main() {
 			return
 		}
 
-		db := database.GetDB()
 		user, err := database.GetUserByLogin(db, requestData.Login)
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -618,10 +906,10 @@ func main() {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(user)
-	}))
+	})
 
 	// Обработчик для процесса входа в систему, логина
-	http.HandleFunc("/api/v1/login", enableCORS(func(w http.ResponseWriter, r *http.Request) {
+	handle("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
 		var creds Credentials
 		err := json.NewDecoder(r.Body).Decode(&creds)
 		if err != nil {
@@ -630,7 +918,6 @@ func main() {
 		}
 
 		// Получение пользователя из базы данных
-		db := database.GetDB()
 		user, err := database.GetUserByLogin(db, creds.Login)
 		if err != nil {
 			sendJSONError(w, "Login failed", http.StatusUnauthorized)
@@ -667,7 +954,64 @@ func main() {
 		// устанавливаем время истечения, которое совпадает с временем истечения токена
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"jwt": tokenString})
-	}))
+	})
+
+	return patterns
+}
+
+// Основная функция, запускающая сервер
+func main() {
+	// Без реестра агентов вычисления некому отправлять, поэтому оркестратор не запускается без токена агентов
+	// или если адрес реестра занят
+	registryListener, err := listenAgentRegistry(agentRegistryAddr)
+	if err != nil {
+		log.Fatalf("Error starting agent registry: %v", err)
+	}
+
+	// Инициализация соединения с базой данных на старте приложения
+	database.InitializeDB()
+	database.SetupDatabase()
+
+	// Определение канала для управления выключением
+	shutdownCh := make(chan struct{})
+
+	// Горутина отправки задач на калькуляторы: по уведомлениям о новых вычислениях, в том числе
+	// от других экземпляров оркестратора через LISTEN/NOTIFY, и периодически на случай пропущенных уведомлений
+	var notifications <-chan *pq.Notification
+	if listener, err := database.ListenDispatch(); err != nil {
+		log.Printf("Error listening for new calculations, falling back to polling: %v", err)
+	} else {
+		defer listener.Close()
+		notifications = listener.Notify
+	}
+	go dispatch.run(database.GetDB(), notifications, shutdownCh)
+	dispatch.notify() // Первый проход сразу после запуска
+
+	// Горутина брокера событий, отслеживающая изменения статусов вычислений для SSE и long-polling
+	go events.run(database.GetDB(), shutdownCh)
+
+	// Горутина отправки уведомлений о завершенных вычислениях на вебхуки
+	go newWebhookDispatcher().run(database.GetDB(), shutdownCh)
+
+	// Горутина запуска вычислений по расписаниям
+	go runScheduler(database.GetDB(), shutdownCh)
+
+	// Регистрация обработчиков HTTP API
+	registerRoutes(http.DefaultServeMux, database.GetDB())
+
+	// gRPC API для клиентов на порту 9090
+	go func() {
+		if err := serveClientGRPC(database.GetDB(), clientGRPCAddr); err != nil {
+			log.Printf("Error starting client gRPC server: %v", err)
+		}
+	}()
+
+	// gRPC реестр агентов на порту 9091
+	go func() {
+		if err := serveAgentRegistry(database.GetDB(), registryListener); err != nil {
+			log.Fatalf("Agent registry stopped: %v", err)
+		}
+	}()
 
 	// Горутина для периодической проверки и перезапуска неудачных операций.
 	go func() {
@@ -686,6 +1030,38 @@ func main() {
 		}
 	}()
 
+	// Горутина для периодической очистки устаревших ключей идемпотентности.
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				purgeIdempotencyKeys(database.GetDB())
+			case <-shutdownCh:
+				log.Println("Stopping idempotency keys cleanup.")
+				return
+			}
+		}
+	}()
+
+	// Горутина для периодического окончательного удаления вычислений из корзины.
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				purgeTrash(database.GetDB())
+			case <-shutdownCh:
+				log.Println("Stopping trash cleanup.")
+				return
+			}
+		}
+	}()
+
 	// Запуск HTTP-сервера на порту 8080.
 	fmt.Println("Server is running on port 8080...")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
@@ -1,33 +1,61 @@
 package main
 
 import (
-	"calculatorapi/utility/models"
-	"encoding/json"
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"database/sql/driver"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
 func TestPingServers(t *testing.T) {
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/ping" {
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"status":            "running",
-				"maxGoroutines":     10,
-				"currentGoroutines": 5,
-			})
-		}
-	}))
-	defer server.Close()
+	oldTimeout := agentStatusTimeout
+	t.Cleanup(func() { agentStatusTimeout = oldTimeout })
+	agentStatusTimeout = 200 * time.Millisecond
 
-	servers = []string{server.URL}
+	healthy := startFakeAgent(t, statusAgent{status: &pb.StatusResponse{
+		Running: true, MaxGoroutines: 10, CurrentGoroutines: 5, AgentId: "agent-1", Version: "1.2.0",
+		UptimeSeconds: 60, Completed: 7, Failed: 1, QueueDepth: 3, QueueSize: 20, EstimatedWaitSeconds: 11.5,
+		Runtime: &pb.RuntimeStats{GoVersion: "go1.22", NumCpu: 4, Goroutines: 12},
+	}})
+	slow := startFakeAgent(t, statusAgent{delay: time.Second})
+	slowToo := startFakeAgent(t, statusAgent{delay: time.Second})
+	withRegistry(t,
+		registeredAgent{ID: "agent-1", URL: "http://localhost:8081", GRPCAddr: healthy, MaxConcurrency: 10, Labels: map[string]string{"region": "eu"}},
+		registeredAgent{ID: "agent-2", URL: "http://localhost:8082", GRPCAddr: slow, MaxConcurrency: 1},
+		registeredAgent{ID: "agent-3", URL: "http://localhost:8083", GRPCAddr: slowToo, MaxConcurrency: 1},
+	)
 
+	start := time.Now()
 	statuses := pingServers()
+	if elapsed := time.Since(start); elapsed > 2*agentStatusTimeout {
+		t.Errorf("Expected agents to be probed concurrently, took %v", elapsed)
+	}
 
-	if len(statuses) != 1 || !statuses[0].Running {
-		t.Errorf("Expected the server to be running but got %v", statuses[0])
+	if len(statuses) != 3 || !statuses[0].Running || statuses[0].URL != "http://localhost:8081" {
+		t.Fatalf("Expected the first server to be running but got %v", statuses)
+	}
+	got := statuses[0]
+	if got.Labels["region"] != "eu" || got.LastHeartbeat.IsZero() {
+		t.Errorf("Expected registry details in the status, got %+v", got)
+	}
+	if got.AgentID != "agent-1" || got.Version != "1.2.0" || got.UptimeSeconds != 60 || got.Completed != 7 || got.Failed != 1 {
+		t.Errorf("Unexpected agent status %+v", got)
+	}
+	if got.QueueDepth != 3 || got.QueueSize != 20 || got.EstimatedWait != 12 {
+		t.Errorf("Unexpected queue status %+v", got)
+	}
+	if got.Runtime == nil || got.Runtime.GoVersion != "go1.22" || got.Runtime.NumCPU != 4 || got.Runtime.Goroutines != 12 {
+		t.Errorf("Unexpected runtime stats %+v", got.Runtime)
+	}
+	for _, status := range statuses[1:] {
+		if status.Running || status.Error == "" {
+			t.Errorf("Expected a timed out agent to be reported as not running, got %+v", status)
+		}
 	}
 }
 
@@ -38,27 +66,140 @@ func TestSubmitCalculations(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "userId", "operation", "add_duration", "subtract_duration", "multiply_duration", "divide_duration"}).
-		AddRow(1, 1, "2+2", 10, 10, 10, 10)
-	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(rows)
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(queueRows().AddRow(1, 1, "2+2", 10, 10, 10, 10, 60, 2))
+	// Третья попытка записывается до отправки каждому агенту и подтверждается, когда агент ее принял
+	mock.ExpectExec("SET operation_server = \\$1").WithArgs("http://localhost:8081", "full", 3, 1, "").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET operation_server = \\$1").WithArgs("http://localhost:8082", "free", 3, 1, "full").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET status = 'work'").WithArgs(1, 3, "free").WillReturnResult(sqlmock.NewResult(0, 1))
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/calculate" {
-			var req models.CalculationRequest
-			json.NewDecoder(r.Body).Decode(&req)
-			if req.Operation != "2+2" {
-				t.Errorf("Expected operation '2+2', got '%s'", req.Operation)
-			}
-			w.WriteHeader(http.StatusOK)
+	received := make(chan *pb.CalculationRequest, 1)
+	withRegistry(t,
+		registeredAgent{ID: "full", URL: "http://localhost:8081", GRPCAddr: startFakeAgent(t, fullAgent{retryAfter: "30"}), MaxConcurrency: 1},
+		registeredAgent{ID: "free", URL: "http://localhost:8082", GRPCAddr: startFakeAgent(t, acceptingAgent{received: received}), MaxConcurrency: 1},
+	)
+	t.Cleanup(func() { busyAgents = newAgentBackoff() })
+
+	submitCalculations(db)
+
+	select {
+	case req := <-received:
+		if req.Operation != "2+2" || req.Times["add_duration"] != 10 || req.Attempt != 3 {
+			t.Errorf("Unexpected calculation request %+v", req)
 		}
-	}))
-	defer server.Close()
+	default:
+		t.Error("Expected the calculation to be sent to the agent with a free queue")
+	}
+	if !busyAgents.busy("http://localhost:8081", time.Now()) {
+		t.Error("Expected the agent with a full queue to be put on hold")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
 
-	servers = []string{server.URL}
+func TestCalculationDeadline(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	if got, want := calculationDeadline(start, 30, 60), start.Add(90*time.Second); !got.Equal(want) {
+		t.Errorf("calculationDeadline() = %v, want %v", got, want)
+	}
+	if got, want := calculationDeadline(start, 30, 0), start.Add(30*time.Second+retries.Grace); !got.Equal(want) {
+		t.Errorf("calculationDeadline() without inactive server time = %v, want %v", got, want)
+	}
+}
 
-	submitCalculations(db)
+func TestRecordDispatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	agent := registeredAgent{ID: "agent-1", URL: "http://localhost:8081"}
+	mock.ExpectExec("SET operation_server = \\$1, server_status = 'dispatched', agent_id = \\$2, attempts = \\$3(.+)attempts = \\$3 - 1").
+		WithArgs("http://localhost:8081", "agent-1", 2, 7, "").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET operation_server = \\$1").
+		WithArgs("http://localhost:8082", "agent-2", 2, 7, "agent-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET operation_server = \\$1").
+		WithArgs("http://localhost:8081", "agent-1", 1, 8, "").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if !recordDispatch(db, 7, 2, agent, "") {
+		t.Error("Expected the dispatch to be recorded")
+	}
+	if !recordDispatch(db, 7, 2, registeredAgent{ID: "agent-2", URL: "http://localhost:8082"}, "agent-1") {
+		t.Error("Expected the attempt to be moved to the next agent")
+	}
+	// Вычисление завершено или уже отправлено другим оркестратором
+	if recordDispatch(db, 8, 1, agent, "") {
+		t.Error("Expected a finished calculation not to be dispatched")
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("There were unfulfilled expectations: %s", err)
 	}
 }
+
+var (
+	resultColumns = []string{"operation", "result", "status", "userId"}       // Столбцы выборки одного вычисления
+	listColumns   = []string{"id", "userId", "operation", "result", "status"} // Столбцы выборки списка вычислений
+)
+
+// calculationRows возвращает строки вычислений со столбцами columns, дополненные пустыми сведениями о выполнении.
+func calculationRows(columns []string, rows ...[]interface{}) *sqlmock.Rows {
+	result := sqlmock.NewRows(append(append([]string{}, columns...),
+		"created_time", "start_time", "end_time", "operation_server", "server_status", "agent_id", "attempts"))
+	for _, row := range rows {
+		values := make([]driver.Value, 0, len(row)+7)
+		for _, v := range row {
+			values = append(values, v)
+		}
+		result.AddRow(append(values, nil, nil, nil, nil, nil, nil, 0)...)
+	}
+	return result
+}
+
+func TestCalculationResultHidesAgentDetailsFromOthers(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    int // Пользователь, токеном которого подписан запрос; 0 - без токена
+		wantAgent bool
+	}{
+		{"anonymous", 0, false},
+		{"another user", 5, false},
+		{"owner", 4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			started := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+			mock.ExpectQuery("SELECT operation, result, status, userId, (.+) FROM calculations").WithArgs(12).
+				WillReturnRows(sqlmock.NewRows(append(resultColumns, "created_time", "start_time", "end_time", "operation_server", "server_status", "agent_id", "attempts")).
+					AddRow("2+2", 4.0, "completed", 4, started, started, started, "http://localhost:8081", "completed", "calc-1:8081", 2))
+
+			mux := http.NewServeMux()
+			registerRoutes(mux, db)
+			req := httptest.NewRequest(http.MethodGet, "/get-calculation-result?id=12", nil)
+			if tt.userID != 0 {
+				req.Header.Set("Authorization", "Bearer "+newTestToken(t, tt.userID))
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			body := rr.Body.String()
+			if rr.Code != http.StatusOK || !strings.Contains(body, `"server_status":"completed"`) {
+				t.Fatalf("Unexpected response %d: %s", rr.Code, body)
+			}
+			if got := strings.Contains(body, `"agent_id":"calc-1:8081"`) && strings.Contains(body, `"attempts":2`); got != tt.wantAgent {
+				t.Errorf("Expected agent details shown %v, got %s", tt.wantAgent, body)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
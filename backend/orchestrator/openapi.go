@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	_ "embed" // Для встраивания спецификации в бинарный файл
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"calculatorapi/utility/openapi" // Пакет для проверки запросов по спецификации OpenAPI
+)
+
+const maxValidatedBodySize = 10 << 20 // Тела большего размера передаются обработчику без проверки
+
+// Спецификация OpenAPI всех эндпоинтов оркестратора
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Разобранная спецификация, по которой проверяются тела запросов
+var apiSpec = mustParseSpec(openAPIDocument)
+
+// Тело ответа 400 на запрос, не прошедший проверку по спецификации
+type validationErrorResponse struct {
+	Error  string               `json:"error"`
+	Fields []openapi.FieldError `json:"fields"`
+}
+
+func mustParseSpec(data []byte) *openapi.Document {
+	spec, err := openapi.Parse(data)
+	if err != nil {
+		log.Fatalf("Error parsing OpenAPI specification: %v", err)
+	}
+	return spec
+}
+
+// validateRequest - миддлвар, проверяющий тело запроса по спецификации.
+// При ошибках отвечает 400 со списком полей и сообщений, обработчик не вызывается.
+func validateRequest(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		op, _, found := apiSpec.FindOperation(r.Method, r.URL.Path)
+		if !found || op == nil || op.RequestBody == nil {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBodySize+1))
+		if err != nil {
+			sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxValidatedBodySize {
+			// Ограничение размера остается за обработчиком
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			next(w, r)
+			return
+		}
+
+		if fields := apiSpec.ValidateRequestBody(op, r.Header.Get("Content-Type"), body); len(fields) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(validationErrorResponse{Error: "Validation failed", Fields: fields})
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+// openAPIHandler обрабатывает GET /api/v1/openapi.json.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
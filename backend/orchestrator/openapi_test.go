@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	patterns := registerRoutes(http.NewServeMux(), nil)
+
+	registered := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		registered[pattern] = true
+		if _, ok := apiSpec.Paths[pattern]; !ok {
+			t.Errorf("Route %s is not described in openapi.json", pattern)
+		}
+	}
+
+	var described []string
+	for path := range apiSpec.Paths {
+		described = append(described, path)
+	}
+	sort.Strings(described)
+	for _, path := range described {
+		if !registered[path] {
+			t.Errorf("Path %s from openapi.json has no handler", path)
+		}
+	}
+}
+
+func TestValidateRequestRejectsInvalidBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		fields []string
+	}{
+		{"negative duration", http.MethodPost, "/submit-calculation", `{"userId":1,"operation":"2+2","add_duration":-1}`, []string{"add_duration"}},
+		{"missing operation", http.MethodPost, "/submit-calculation", `{"userId":1}`, []string{"operation"}},
+		{"wrong type", http.MethodPost, "/submit-calculation", `{"userId":"1","operation":"2+2"}`, []string{"userId"}},
+		{"batch item", http.MethodPost, "/api/v1/batches", `[{"operation":"2+2"},{"operation":""}]`, []string{"[1].operation"}},
+		{"settings", http.MethodPut, "/api/v1/settings", `{"precision":20,"output_format":"roman"}`, []string{"output_format", "precision"}},
+		{"empty body", http.MethodPost, "/api/v1/login", ``, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := validateRequest(func(w http.ResponseWriter, r *http.Request) { called = true })
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if called || rr.Code != http.StatusBadRequest {
+				t.Fatalf("Expected 400 without calling the handler, got %d", rr.Code)
+			}
+			var resp validationErrorResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var fields []string
+			for _, f := range resp.Fields {
+				fields = append(fields, f.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("Expected errors for fields %v, got %+v", tt.fields, resp.Fields)
+			}
+		})
+	}
+}
+
+func TestValidateRequestPassesValidBody(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		// Ключи сопоставляются без учета регистра, null длительности берутся из настроек
+		{"application/json", `{"UserId":1,"operation":"2+2","add_duration":null}`},
+		{"", `{"userId":1,"operation":"2+2","add_duration":0}`},
+	}
+
+	for _, tt := range tests {
+		var got string
+		handler := validateRequest(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got = string(body)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/submit-calculation", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		handler(httptest.NewRecorder(), req)
+
+		if got != tt.body {
+			t.Errorf("Expected handler to receive %q, got %q", tt.body, got)
+		}
+	}
+}
+
+// Ответы обработчиков должны соответствовать схемам из openapi.json
+func TestHandlerResponsesMatchOpenAPISpec(t *testing.T) {
+	token := newTestToken(t, 4)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		auth   bool
+		mock   func(mock sqlmock.Sqlmock)
+		status int
+	}{
+		{name: "openapi", method: http.MethodGet, target: "/api/v1/openapi.json", status: http.StatusOK},
+		{name: "orchestrator status", method: http.MethodGet, target: "/orchestrator-status", status: http.StatusOK},
+		{
+			name: "submit", method: http.MethodPost, target: "/submit-calculation", auth: true, status: http.StatusOK,
+			body: `{"userId":4,"operation":"2+2","add_duration":1,"subtract_duration":1,"multiply_duration":1,"divide_duration":1,"inactive_server_time":60}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				expectQueueReserve(mock, 4, 0)
+				mock.ExpectQuery("INSERT INTO calculations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "submit unauthorized", method: http.MethodPost, target: "/submit-calculation", status: http.StatusUnauthorized,
+			body: `{"userId":4,"operation":"2+2"}`,
+		},
+		{
+			name: "submit for another user", method: http.MethodPost, target: "/submit-calculation", auth: true, status: http.StatusForbidden,
+			body: `{"userId":5,"operation":"2+2"}`,
+		},
+		{
+			name: "submit invalid", method: http.MethodPost, target: "/submit-calculation", status: http.StatusBadRequest,
+			body: `{"userId":4,"operation":"2+2","divide_duration":-5}`,
+		},
+		{
+			name: "calculation result", method: http.MethodGet, target: "/get-calculation-result?id=12", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+				mock.ExpectQuery("SELECT operation, result, status, userId, (.+) FROM calculations").WithArgs(12).
+					WillReturnRows(sqlmock.NewRows(append(resultColumns, "created_time", "start_time", "end_time", "operation_server", "server_status", "agent_id", "attempts")).
+						AddRow("2+2", 4.0, "completed", 4, created, created.Add(time.Second), created.Add(3*time.Second), "http://localhost:8081", "completed", "calc-1:8081", 2))
+			},
+		},
+		{name: "calculation result without id", method: http.MethodGet, target: "/get-calculation-result", status: http.StatusBadRequest},
+		{name: "calculation result long-poll unauthorized", method: http.MethodGet, target: "/get-calculation-result?id=12&wait=1s", status: http.StatusUnauthorized},
+		{
+			name: "calculation result long-poll of another user", method: http.MethodGet, target: "/get-calculation-result?id=12&wait=1s", auth: true, status: http.StatusNotFound,
+			mock: func(mock sqlmock.Sqlmock) {
+				created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+				mock.ExpectQuery("SELECT operation, result, status, userId, (.+) FROM calculations").WithArgs(12).
+					WillReturnRows(sqlmock.NewRows(append(resultColumns, "created_time", "start_time", "end_time", "operation_server", "server_status", "agent_id", "attempts")).
+						AddRow("2+2", nil, "work", 5, created, created, nil, "http://localhost:8081", "work", "calc-1:8081", 1))
+			},
+		},
+		{
+			name: "calculations by user", method: http.MethodGet, target: "/get-calculations-by-user?userId=4", status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE userId").WithArgs(4).
+					WillReturnRows(calculationRows(listColumns,
+						[]interface{}{12, 4, "2+2", 4.0, "completed"}, []interface{}{13, 4, "3*3", nil, "created"}))
+			},
+		},
+		{
+			name: "settings", method: http.MethodGet, target: "/api/v1/settings", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM user_settings").WithArgs(4).WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{name: "settings unauthorized", method: http.MethodGet, target: "/api/v1/settings", status: http.StatusUnauthorized},
+		{
+			name: "webhooks", method: http.MethodGet, target: "/api/v1/webhooks", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM webhooks WHERE userId").WithArgs(4).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "url", "secret", "created_time"}).
+						AddRow(1, 4, "https://example.com/hook", "s3cret", time.Now()))
+			},
+		},
+		{
+			name: "submit invalid expression", method: http.MethodPost, target: "/submit-calculation", auth: true, status: http.StatusUnprocessableEntity,
+			body: `{"userId":4,"operation":"2++"}`,
+		},
+		{
+			name: "schedule", method: http.MethodGet, target: "/api/v1/schedules/3", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				next := time.Now().Add(time.Hour)
+				mock.ExpectQuery("FROM schedules WHERE id").WithArgs(3, 4).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "operation", "cron", "run_at", "timezone", "add_duration", "subtract_duration", "multiply_duration",
+						"divide_duration", "inactive_server_time", "priority", "enabled", "next_run_time", "last_run_time", "last_calculation_id", "created_time"}).
+						AddRow(3, 4, "2+2", "@hourly", nil, "UTC", 1, 1, 1, 1, 30, 5, true, next, nil, nil, time.Now()))
+			},
+		},
+		{
+			name: "priority forbidden", method: http.MethodPut, target: "/api/v1/admin/calculations/12/priority", auth: true,
+			body: `{"priority":9}`, status: http.StatusForbidden,
+		},
+		{name: "validate", method: http.MethodPost, target: "/api/v1/validate", body: `{"operation":"2+2"}`, status: http.StatusOK},
+		{name: "validate invalid", method: http.MethodPost, target: "/api/v1/validate", body: `{"operation":"2 3"}`, status: http.StatusOK},
+		{
+			name: "submit over quota", method: http.MethodPost, target: "/submit-calculation", auth: true, status: http.StatusTooManyRequests,
+			body: `{"userId":4,"operation":"2+2"}`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM user_settings").WithArgs(4).WillReturnError(sql.ErrNoRows)
+				expectQueueReserve(mock, 4, quotas.MaxQueued)
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name: "quota", method: http.MethodGet, target: "/api/v1/quota", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				expectQueueCount(mock, 4, 2, 1)
+			},
+		},
+		{
+			name: "batch", method: http.MethodGet, target: "/api/v1/batches/9", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM batches WHERE id").WithArgs(9, 4).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "total", "created_time"}).AddRow(9, 4, 1, time.Now()))
+				mock.ExpectQuery("FROM calculations WHERE batch_id").WithArgs(9).
+					WillReturnRows(calculationRows(listColumns, []interface{}{12, 4, "2+2", 4.0, "completed"}))
+			},
+		},
+		{
+			name: "export", method: http.MethodGet, target: "/api/v1/calculations/export?format=json&from=2026-01-01", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "operation", "result", "status", "created_time", "end_time"}).
+						AddRow(12, "2+2", 4.0, "completed", time.Now(), time.Now()).
+						AddRow(13, "2*2", nil, "created", time.Now(), nil))
+			},
+		},
+		{
+			name: "import", method: http.MethodPost, target: "/api/v1/calculations/import", auth: true, status: http.StatusOK,
+			body: `[{"operation":"2+2","result":4,"status":"completed","created_time":"2026-01-01T10:00:00Z"}]`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare("INSERT INTO calculations").ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "trash", method: http.MethodGet, target: "/api/v1/trash", auth: true, status: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE userId").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "operation", "result", "status", "deleted_time"}).
+						AddRow(12, 4, "2+2", 4.0, "completed", time.Now()))
+			},
+		},
+		{
+			name: "delete running", method: http.MethodDelete, target: "/api/v1/calculations/12", auth: true, status: http.StatusConflict,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(12).
+					WillReturnRows(calculationRows(resultColumns, []interface{}{"2+2", nil, "work", 4}))
+			},
+		},
+		{
+			name: "batch not found", method: http.MethodGet, target: "/api/v1/batches/10", auth: true, status: http.StatusNotFound,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM batches WHERE id").WithArgs(10, 4).WillReturnError(sql.ErrNoRows)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			if tt.mock != nil {
+				tt.mock(mock)
+			}
+
+			mux := http.NewServeMux()
+			registerRoutes(mux, db)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rr.Code, rr.Body.String())
+			}
+			op, _, _ := apiSpec.FindOperation(tt.method, req.URL.Path)
+			if errs := apiSpec.ValidateResponse(op, rr.Code, rr.Header().Get("Content-Type"), rr.Body.Bytes()); len(errs) > 0 {
+				t.Errorf("Response does not match the spec: %+v\n%s", errs, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // Клиентская проверка состояния агентов по протоколу grpc.health.v1
+	"google.golang.org/grpc/keepalive"
+)
+
+// Время ожидания ответа агента на отправку вычисления
+var agentDispatchTimeout = time.Duration(envInt("CALCULATOR_AGENT_DISPATCH_TIMEOUT_MS", 5000)) * time.Millisecond
+
+// Параметры keepalive соединений с агентами. Агенты разрешают пинги не чаще раза в 20 секунд.
+var agentKeepalive = keepalive.ClientParameters{
+	Time:                30 * time.Second, // Пинг после 30 секунд без активности
+	Timeout:             10 * time.Second, // Соединение разрывается, если ответа на пинг нет 10 секунд
+	PermitWithoutStream: true,
+}
+
+// Конфигурация клиента: соединение считается готовым, только пока агент отвечает SERVING по протоколу проверки состояния.
+// Клиентскую проверку состояния поддерживает балансировщик round_robin; у агента один адрес, так что балансировки нет.
+var agentServiceConfig = fmt.Sprintf(`{"loadBalancingConfig": [{"round_robin": {}}], "healthCheckConfig": {"serviceName": %q}}`,
+	pb.CalculatorService_ServiceDesc.ServiceName)
+
+// agentConnPool хранит по одному долгоживущему соединению с каждым агентом.
+type agentConnPool struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn // Соединения по gRPC адресу агента
+}
+
+// Соединения с зарегистрированными агентами
+var agentConns = newAgentConnPool()
+
+func newAgentConnPool() *agentConnPool {
+	return &agentConnPool{conns: make(map[string]*grpc.ClientConn)}
+}
+
+// get возвращает соединение с агентом addr, создавая его при первом обращении.
+func (p *agentConnPool) get(addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(agentKeepalive),
+		grpc.WithDefaultServiceConfig(agentServiceConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.Connect() // Подключаемся сразу, чтобы состояние соединения было видно до первого вычисления
+	p.conns[addr] = conn
+	return conn, nil
+}
+
+// state возвращает состояние соединения с агентом addr или пустую строку, если соединения нет.
+func (p *agentConnPool) state(addr string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.conns[addr]; ok {
+		return conn.GetState().String()
+	}
+	return ""
+}
+
+// failing сообщает, что соединение с агентом addr не удается установить
+// или агент не проходит проверку состояния. gRPC продолжает переподключаться в фоне.
+func (p *agentConnPool) failing(addr string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conn, ok := p.conns[addr]
+	return ok && conn.GetState() == connectivity.TransientFailure
+}
+
+// retain закрывает соединения с агентами, которых нет среди agents.
+func (p *agentConnPool) retain(agents []registeredAgent) {
+	keep := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		keep[agent.GRPCAddr] = true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for addr, conn := range p.conns {
+		if !keep[addr] {
+			conn.Close()
+			delete(p.conns, addr)
+		}
+	}
+}
+
+// liveAgents возвращает активных агентов из реестра и закрывает соединения с удаленными.
+func liveAgents(now time.Time) []registeredAgent {
+	agents := registry.live(now)
+	agentConns.retain(agents)
+	return agents
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+)
+
+// waitForState ожидает, пока соединение с агентом addr перейдет в состояние state.
+func waitForState(t *testing.T, addr string, state connectivity.State) {
+	t.Helper()
+	conn, err := agentConns.get(addr)
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for current := conn.GetState(); current != state; current = conn.GetState() {
+		if !conn.WaitForStateChange(ctx, current) {
+			t.Fatalf("Connection to %s stayed %v, expected %v", addr, current, state)
+		}
+	}
+}
+
+func TestAgentConnPool(t *testing.T) {
+	withRegistry(t)
+	addr := startFakeAgent(t, acceptingAgent{})
+
+	first, err := agentConns.get(addr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	second, _ := agentConns.get(addr)
+	if first != second {
+		t.Error("Expected the connection to be reused")
+	}
+	waitForState(t, addr, connectivity.Ready)
+	if got := agentConns.state(addr); got != "READY" {
+		t.Errorf("Expected a ready connection, got %q", got)
+	}
+
+	agentConns.retain([]registeredAgent{{GRPCAddr: "localhost:1"}})
+	if got := agentConns.state(addr); got != "" || first.GetState() != connectivity.Shutdown {
+		t.Errorf("Expected the connection to a removed agent to be closed, got %q", got)
+	}
+}
+
+func TestCheckServerStatusHealth(t *testing.T) {
+	addr, healthServer := startFakeAgentWithHealth(t, statusAgent{status: &pb.StatusResponse{Running: true, MaxGoroutines: 1}})
+	withRegistry(t, registeredAgent{ID: "agent-1", URL: "http://localhost:8081", GRPCAddr: addr, MaxConcurrency: 1})
+
+	statuses := pingServers()
+	if len(statuses) != 1 || !statuses[0].Running || statuses[0].Connection != "READY" {
+		t.Fatalf("Expected a ready and serving agent, got %+v", statuses)
+	}
+	if agentConns.failing(addr) {
+		t.Error("Expected a serving agent to accept calculations")
+	}
+
+	// Агент, отвечающий NOT_SERVING, не получает вычислений, пока не восстановится
+	healthServer.SetServingStatus(pb.CalculatorService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	waitForState(t, addr, connectivity.TransientFailure)
+	if !agentConns.failing(addr) {
+		t.Error("Expected a not serving agent to be skipped")
+	}
+	statuses = pingServers()
+	if statuses[0].Running || statuses[0].Connection != "TRANSIENT_FAILURE" || !strings.Contains(statuses[0].Error, "NOT_SERVING") {
+		t.Errorf("Expected the status to show the failing health check, got %+v", statuses[0])
+	}
+
+	healthServer.SetServingStatus(pb.CalculatorService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	waitForState(t, addr, connectivity.Ready)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+)
+
+// Время ожидания в очереди, за которое приоритет вычисления растет на единицу (0 - без старения).
+// Задается переменной окружения CALCULATOR_PRIORITY_AGING_SECONDS.
+var priorityAging = time.Duration(envInt("CALCULATOR_PRIORITY_AGING_SECONDS", 60)) * time.Second
+
+// Тело запроса PUT /api/v1/admin/calculations/{id}/priority
+type priorityUpdate struct {
+	Priority *int `json:"priority"`
+}
+
+// Ответ на изменение приоритета
+type priorityResponse struct {
+	ID        int    `json:"id"`
+	UserId    int    `json:"userId"`
+	Operation string `json:"operation"`
+	Status    string `json:"status"`
+	Priority  int    `json:"priority"`
+}
+
+// calculationPriorityHandler обрабатывает PUT /api/v1/admin/calculations/{id}/priority:
+// администратор меняет приоритет вычисления, еще ожидающего в очереди.
+func calculationPriorityHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, ok := authenticateAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid calculation id", http.StatusBadRequest)
+			return
+		}
+
+		var update priorityUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil || update.Priority == nil {
+			sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := validatePriority(*update.Priority); err != nil {
+			sendJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		calc, err := database.GetCalculationResultByID(db, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			sendJSONError(w, errCalculationNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		updated, err := database.SetCalculationPriority(db, id, *update.Priority)
+		if err != nil {
+			log.Printf("Error updating priority of calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !updated {
+			sendJSONError(w, "Calculation is no longer queued", http.StatusConflict)
+			return
+		}
+		log.Printf("Admin %s set priority of calculation %d to %d", claims.Login, id, *update.Priority)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(priorityResponse{
+			ID:        id,
+			UserId:    calc.UserId,
+			Operation: calc.Operation,
+			Status:    "created",
+			Priority:  *update.Priority,
+		})
+	}
+}
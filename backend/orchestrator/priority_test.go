@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"calculatorapi/utility/models"
+)
+
+// withAdmins подменяет список администраторов на время теста.
+func withAdmins(t *testing.T, logins string) {
+	t.Helper()
+	old := adminLogins
+	adminLogins = parseAdminLogins(logins)
+	t.Cleanup(func() { adminLogins = old })
+}
+
+func newPriorityRequest(t *testing.T, id, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/calculations/"+id+"/priority", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 1))
+	req.SetPathValue("id", id)
+	return req
+}
+
+func TestCalculationPriorityHandler(t *testing.T) {
+	resultRows := func(status string) *sqlmock.Rows {
+		return calculationRows(resultColumns, []interface{}{"2+2", nil, status, 3})
+	}
+
+	tests := []struct {
+		name       string
+		admins     string
+		id         string
+		body       string
+		mock       func(mock sqlmock.Sqlmock)
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "not admin", admins: "root", id: "7", body: `{"priority":9}`, wantStatus: http.StatusForbidden},
+		{name: "out of range", admins: "tester", id: "7", body: `{"priority":10}`, wantStatus: http.StatusBadRequest},
+		{
+			name: "not found", admins: "tester", id: "7", body: `{"priority":9}`, wantStatus: http.StatusNotFound,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(calculationRows(resultColumns))
+			},
+		},
+		{
+			name: "already running", admins: "tester", id: "7", body: `{"priority":9}`, wantStatus: http.StatusConflict,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("work"))
+				mock.ExpectExec("UPDATE calculations").WithArgs(9, 7).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "updated", admins: "root, tester", id: "7", body: `{"priority":9}`, wantStatus: http.StatusOK, wantBody: `"priority":9`,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("created"))
+				mock.ExpectExec("UPDATE calculations").WithArgs(9, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withAdmins(t, tt.admins)
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			if tt.mock != nil {
+				tt.mock(mock)
+			}
+
+			rr := httptest.NewRecorder()
+			calculationPriorityHandler(db)(rr, newPriorityRequest(t, tt.id, tt.body))
+
+			if rr.Code != tt.wantStatus || !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("Expected %d with %q, got %d: %s", tt.wantStatus, tt.wantBody, rr.Code, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestCalculationRequestPriority(t *testing.T) {
+	high, invalid := 9, -1
+	if err := (CalculationRequest{Operation: "1+1", Priority: &invalid}).validate(); err == nil {
+		t.Error("Expected priority -1 to be rejected")
+	}
+	if got := (CalculationRequest{Operation: "1+1", Priority: &high}).withDefaults(models.DefaultUserSettings(1)).Priority; got != 9 {
+		t.Errorf("Expected priority 9, got %d", got)
+	}
+	if got := (CalculationRequest{Operation: "1+1"}).withDefaults(models.DefaultUserSettings(1)).Priority; got != 5 {
+		t.Errorf("Expected default priority 5, got %d", got)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"calculatorapi/utility/database"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Наибольшее время, которое запрос задач ожидает их появления
+const maxTaskWait = 30 * time.Second
+
+// GetTask выдает агенту, работающему в режиме pull, до max_tasks задач из очереди.
+// Задачи выдаются в том же порядке, что и при отправке агентам (FetchCalculationsToProcess), поэтому
+// агенты обоих режимов разбирают одну очередь. Если задач нет, ответ ожидает прохода диспетчера до wait_seconds.
+func (s *registryService) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.GetTaskResponse, error) {
+	if req.MaxTasks < 1 {
+		return nil, status.Error(codes.InvalidArgument, "max_tasks must be positive")
+	}
+	agent, ok := s.registry.get(req.AgentId)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "agent %q is not registered", req.AgentId)
+	}
+	if !agent.Pull {
+		return nil, status.Errorf(codes.FailedPrecondition, "agent %q is registered in push mode", req.AgentId)
+	}
+
+	limit := min(int(req.MaxTasks), maxDispatchBatch)
+	timer := time.NewTimer(min(time.Duration(req.WaitSeconds)*time.Second, maxTaskWait))
+	defer timer.Stop()
+	for {
+		pass := dispatch.nextPass()
+		tasks, err := s.leaseTasks(agent, limit)
+		if err != nil {
+			log.Printf("Error leasing tasks to agent %s: %v", agent.ID, err)
+			return nil, status.Error(codes.Unavailable, "failed to lease tasks, retry later")
+		}
+		if len(tasks) > 0 {
+			return &pb.GetTaskResponse{Tasks: tasks}, nil
+		}
+
+		select {
+		case <-pass:
+		case <-timer.C:
+			return &pb.GetTaskResponse{}, nil
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// leaseTasks арендует для агента agent до limit вычислений из очереди.
+// Вычисления, которые одновременно арендовал другой агент, пропускаются.
+func (s *registryService) leaseTasks(agent registeredAgent, limit int) ([]*pb.CalculationRequest, error) {
+	calculations, err := database.FetchCalculationsToProcess(s.db, limit, quotas.MaxRunning, priorityAging)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []*pb.CalculationRequest
+	for _, calc := range calculations {
+		attempt, leased, err := database.LeaseCalculation(s.db, calc.ID, agent.URL, agent.ID)
+		if err != nil {
+			log.Printf("Error leasing calculation ID %d to agent %s: %v", calc.ID, agent.ID, err)
+			break // Уже арендованные вычисления отдаются агенту
+		}
+		if !leased {
+			continue
+		}
+		s.registry.noteDispatch(agent.ID)
+		log.Printf("Calculation ID %d leased to agent %s, attempt %d", calc.ID, agent.ID, attempt)
+		tasks = append(tasks, calculationRequest(calc, attempt))
+	}
+	return tasks, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// withDispatcher подменяет диспетчер на время теста.
+func withDispatcher(t *testing.T) {
+	t.Helper()
+	old := dispatch
+	dispatch = newDispatcher()
+	t.Cleanup(func() { dispatch = old })
+}
+
+func TestGetTask(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	withDispatcher(t)
+	withRegistry(t,
+		registeredAgent{ID: "pull-1", Pull: true, MaxConcurrency: 2},
+		registeredAgent{ID: "push-1", URL: "http://localhost:8081", GRPCAddr: "localhost:50051", MaxConcurrency: 2},
+	)
+	s := &registryService{registry: registry, db: db}
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		req  *pb.GetTaskRequest
+		code codes.Code
+	}{
+		"no tasks requested": {&pb.GetTaskRequest{AgentId: "pull-1"}, codes.InvalidArgument},
+		"unknown agent":      {&pb.GetTaskRequest{AgentId: "pull-2", MaxTasks: 1}, codes.NotFound},
+		"push agent":         {&pb.GetTaskRequest{AgentId: "push-1", MaxTasks: 1}, codes.FailedPrecondition},
+	} {
+		if _, err := s.GetTask(ctx, tc.req); status.Code(err) != tc.code {
+			t.Errorf("%s: expected %v, got %v", name, tc.code, err)
+		}
+	}
+
+	// Второе вычисление успел арендовать другой агент
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WithArgs(2, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(queueRows().AddRow(1, 1, "2+2", 1, 1, 1, 1, 60, 0).AddRow(2, 1, "3+3", 1, 1, 1, 1, 60, 0))
+	mock.ExpectQuery("SET status = 'work'").WithArgs("", "pull-1", 1).WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(1))
+	mock.ExpectQuery("SET status = 'work'").WithArgs("", "pull-1", 2).WillReturnRows(sqlmock.NewRows([]string{"attempts"}))
+
+	resp, err := s.GetTask(ctx, &pb.GetTaskRequest{AgentId: "pull-1", MaxTasks: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Tasks) != 1 || resp.Tasks[0].Id != 1 || resp.Tasks[0].Attempt != 1 || resp.Tasks[0].Deadline == nil {
+		t.Fatalf("Expected one leased task, got %v", resp.Tasks)
+	}
+	if agent, _ := registry.get("pull-1"); agent.QueueDepth != 1 {
+		t.Errorf("Expected the leased task to count towards the agent load, got %+v", agent)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	// Без ожидания пустая очередь дает пустой ответ
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(queueRows())
+	if resp, err := s.GetTask(ctx, &pb.GetTaskRequest{AgentId: "pull-1", MaxTasks: 1}); err != nil || len(resp.Tasks) != 0 {
+		t.Errorf("Expected an empty response, got %v, %v", resp, err)
+	}
+}
+
+func TestGetTaskWaitsForWork(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	withDispatcher(t)
+	withRegistry(t, registeredAgent{ID: "pull-1", Pull: true, MaxConcurrency: 1})
+	s := &registryService{registry: registry, db: db}
+
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(queueRows())
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(queueRows().AddRow(5, 1, "2+2", 1, 1, 1, 1, 60, 2))
+	mock.ExpectQuery("SET status = 'work'").WithArgs("", "pull-1", 5).WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(3))
+
+	done := make(chan *pb.GetTaskResponse)
+	go func() {
+		resp, err := s.GetTask(context.Background(), &pb.GetTaskRequest{AgentId: "pull-1", MaxTasks: 1, WaitSeconds: 10})
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		done <- resp
+	}()
+
+	// Новое вычисление будит ожидающих агентов на проходе диспетчера
+	time.Sleep(50 * time.Millisecond)
+	dispatch.broadcast()
+	select {
+	case resp := <-done:
+		if len(resp.GetTasks()) != 1 || resp.Tasks[0].Id != 5 || resp.Tasks[0].Attempt != 3 {
+			t.Errorf("Expected the new task, got %v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("GetTask did not return after the dispatcher pass")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCheckServerStatusPullAgent(t *testing.T) {
+	status := checkServerStatus(registeredAgent{ID: "pull-1", Pull: true, MaxConcurrency: 4, QueueSize: 8, Running: 3, QueueDepth: 1, Version: "1.2.0"})
+	if !status.Pull || !status.Running || status.MaxGoroutines != 4 || status.CurrentGoroutines != 3 ||
+		status.QueueDepth != 1 || status.QueueSize != 8 || status.Version != "1.2.0" || status.Connection != "" {
+		t.Errorf("Unexpected pull agent status %+v", status)
+	}
+}
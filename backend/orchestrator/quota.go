@@ -0,0 +1,220 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+)
+
+// quotaConfig определяет ограничения, действующие для каждого пользователя.
+// Нулевое значение отключает соответствующее ограничение.
+type quotaConfig struct {
+	RequestsPerMinute int // Частота запросов на отправку вычислений
+	Burst             int // Количество запросов, которые можно отправить подряд без ожидания
+	MaxQueued         int // Максимальное количество вычислений в очереди ('created')
+	MaxRunning        int // Максимальное количество одновременно выполняемых вычислений ('work')
+}
+
+// Ограничения пользователей, задаются переменными окружения при запуске оркестратора
+var quotas = loadQuotaConfig()
+
+// Ограничение частоты отправки вычислений
+var submitLimiter = newRateLimiter(quotas.RequestsPerMinute, quotas.Burst)
+
+// envInt читает целое неотрицательное значение переменной окружения name или возвращает def.
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid value %q of %s, using %d", value, name, def)
+		return def
+	}
+	return n
+}
+
+// loadQuotaConfig читает ограничения из переменных окружения.
+func loadQuotaConfig() quotaConfig {
+	return quotaConfig{
+		RequestsPerMinute: envInt("CALCULATOR_RATE_LIMIT_PER_MINUTE", 60),
+		Burst:             envInt("CALCULATOR_RATE_LIMIT_BURST", 10),
+		MaxQueued:         envInt("CALCULATOR_MAX_QUEUED", 100),
+		MaxRunning:        envInt("CALCULATOR_MAX_RUNNING", 5),
+	}
+}
+
+// rateLimiter ограничивает частоту запросов каждого пользователя алгоритмом token bucket.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // Скорость пополнения, токенов в секунду
+	burst     float64 // Емкость корзины
+	buckets   map[int]*tokenBucket
+	lastPrune time.Time
+}
+
+// Корзина токенов одного пользователя
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+const rateLimiterPruneInterval = 10 * time.Minute // Период удаления заполненных корзин неактивных пользователей
+
+// newRateLimiter создает ограничитель на perMinute запросов в минуту с запасом burst.
+// При perMinute равном 0 ограничение не действует.
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[int]*tokenBucket),
+	}
+}
+
+// refill возвращает корзину пользователя, пополненную на момент now. Вызывается под мьютексом.
+func (l *rateLimiter) refill(userId int, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[userId]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[userId] = bucket
+	}
+	if elapsed := now.Sub(bucket.updated).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+		bucket.updated = now
+	}
+	return bucket
+}
+
+// allow расходует токен пользователя. Если токенов нет, возвращает false и время до появления следующего.
+func (l *rateLimiter) allow(userId int, now time.Time) (bool, time.Duration) {
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > rateLimiterPruneInterval {
+		l.prune(now)
+	}
+
+	bucket := l.refill(userId, now)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// remaining возвращает количество запросов, которые пользователь может отправить прямо сейчас.
+func (l *rateLimiter) remaining(userId int, now time.Time) int {
+	if l.rate <= 0 {
+		return -1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.refill(userId, now).tokens)
+}
+
+// prune удаляет заполненные корзины: они ничем не отличаются от новых. Вызывается под мьютексом.
+func (l *rateLimiter) prune(now time.Time) {
+	for userId := range l.buckets {
+		if l.refill(userId, now).tokens >= l.burst {
+			delete(l.buckets, userId)
+		}
+	}
+	l.lastPrune = now
+}
+
+// retryAfterSeconds округляет время ожидания вверх до целых секунд для заголовка Retry-After.
+func retryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+// allowSubmission применяет ограничение частоты запросов пользователя.
+// При превышении сам отвечает 429 Too Many Requests с заголовком Retry-After и возвращает false.
+func allowSubmission(w http.ResponseWriter, userId int) bool {
+	allowed, wait := submitLimiter.allow(userId, time.Now())
+	if allowed {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(wait)))
+	sendJSONError(w, "Rate limit exceeded, retry later", http.StatusTooManyRequests)
+	return false
+}
+
+// quotaExceededError - превышение квоты пользователя на количество вычислений в очереди.
+type quotaExceededError struct {
+	limit int
+}
+
+func (e quotaExceededError) Error() string {
+	return fmt.Sprintf("queued calculations quota of %d exceeded", e.limit)
+}
+
+// quotaUsage описывает использование одной квоты. Limit равный 0 означает отсутствие ограничения.
+type quotaUsage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+// Ответ на GET /api/v1/quota
+type quotaResponse struct {
+	UserId    int `json:"userId"`
+	RateLimit struct {
+		RequestsPerMinute int `json:"requests_per_minute"`
+		Burst             int `json:"burst"`
+		Remaining         int `json:"remaining"` // -1, если ограничение отключено
+	} `json:"rate_limit"`
+	Queued  quotaUsage `json:"queued"`
+	Running quotaUsage `json:"running"`
+}
+
+// quotaHandler обрабатывает GET /api/v1/quota: ограничения пользователя и их текущее использование.
+func quotaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		queued, running, err := database.CountActiveCalculations(db, claims.UserID)
+		if err != nil {
+			log.Printf("Error counting calculations of user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		var resp quotaResponse
+		resp.UserId = claims.UserID
+		resp.RateLimit.RequestsPerMinute = quotas.RequestsPerMinute
+		resp.RateLimit.Burst = quotas.Burst
+		resp.RateLimit.Remaining = submitLimiter.remaining(claims.UserID, time.Now())
+		resp.Queued = quotaUsage{Used: queued, Limit: quotas.MaxQueued}
+		resp.Running = quotaUsage{Used: running, Limit: quotas.MaxRunning}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
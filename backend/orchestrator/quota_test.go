@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectQueueCount ожидает запрос количества вычислений пользователя в очереди и в работе.
+func expectQueueCount(mock sqlmock.Sqlmock, userId, queued, running int) {
+	mock.ExpectQuery("COUNT\\(\\*\\) FILTER").WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{"queued", "running"}).AddRow(queued, running))
+}
+
+// expectQueueReserve ожидает начало транзакции вставки и проверку места в очереди пользователя с queued вычислениями.
+func expectQueueReserve(mock sqlmock.Sqlmock, userId, queued int) {
+	mock.ExpectBegin()
+	expectQueueLock(mock, userId, queued)
+}
+
+// expectQueueLock ожидает блокировку очереди пользователя с queued вычислениями и подсчет места в ней.
+func expectQueueLock(mock sqlmock.Sqlmock, userId, queued int) {
+	mock.ExpectExec("pg_advisory_xact_lock").WithArgs(1, userId).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM calculations WHERE userId").WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(queued))
+}
+
+// withQuotas подменяет ограничения на время теста.
+func withQuotas(t *testing.T, config quotaConfig) {
+	t.Helper()
+	oldQuotas, oldLimiter := quotas, submitLimiter
+	quotas, submitLimiter = config, newRateLimiter(config.RequestsPerMinute, config.Burst)
+	t.Cleanup(func() { quotas, submitLimiter = oldQuotas, oldLimiter })
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(60, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.allow(1, now); !allowed {
+			t.Fatalf("Request %d within burst was rejected", i+1)
+		}
+	}
+	allowed, wait := limiter.allow(1, now)
+	if allowed || wait != time.Second {
+		t.Errorf("Expected rejection with 1s wait, got %v, %v", allowed, wait)
+	}
+	if allowed, _ := limiter.allow(2, now); !allowed {
+		t.Error("Another user's request was rejected")
+	}
+	if allowed, _ := limiter.allow(1, now.Add(time.Second)); !allowed {
+		t.Error("Request after refill was rejected")
+	}
+	if got := limiter.remaining(1, now.Add(time.Hour)); got != 2 {
+		t.Errorf("Expected bucket to refill up to burst, got %d", got)
+	}
+
+	unlimited := newRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if allowed, _ := unlimited.allow(1, now); !allowed {
+			t.Fatal("Disabled limiter rejected a request")
+		}
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	if got := retryAfterSeconds(1500 * time.Millisecond); got != 2 {
+		t.Errorf("Expected 2, got %d", got)
+	}
+}
+
+func TestSubmitCalculationRateLimited(t *testing.T) {
+	withQuotas(t, quotaConfig{RequestsPerMinute: 1, Burst: 1})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	body := `{"userId":1,"operation":"2+2","add_duration":1,"subtract_duration":1,"multiply_duration":1,"divide_duration":1,"inactive_server_time":60}`
+	mock.ExpectBegin() // Без квоты на очередь она не блокируется
+	mock.ExpectQuery("INSERT INTO calculations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	rr := httptest.NewRecorder()
+	submitCalculationHandler(db)(rr, newSubmitRequest(t, body, ""))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	submitCalculationHandler(db)(rr, newSubmitRequest(t, body, ""))
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected 429 with Retry-After 60, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSubmitCalculationQueueFull(t *testing.T) {
+	withQuotas(t, quotaConfig{MaxQueued: 3})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM user_settings").WithArgs(1).WillReturnError(sql.ErrNoRows)
+	expectQueueReserve(mock, 1, 3)
+	mock.ExpectRollback()
+
+	rr := httptest.NewRecorder()
+	submitCalculationHandler(db)(rr, newSubmitRequest(t, `{"userId":1,"operation":"2+2"}`, ""))
+	if rr.Code != http.StatusTooManyRequests || !strings.Contains(rr.Body.String(), "quota of 3") {
+		t.Errorf("Expected 429 quota error, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestQuotaHandler(t *testing.T) {
+	withQuotas(t, quotaConfig{RequestsPerMinute: 60, Burst: 10, MaxQueued: 100, MaxRunning: 5})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectQueueCount(mock, 4, 7, 2)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/quota", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr := httptest.NewRecorder()
+	quotaHandler(db)(rr, req)
+
+	var resp quotaResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.UserId != 4 || resp.RateLimit.Remaining != 10 || resp.Queued != (quotaUsage{7, 100}) || resp.Running != (quotaUsage{2, 5}) {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
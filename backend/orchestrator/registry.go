@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"calculatorapi/utility/database"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const agentRegistryAddr = ":9091" // Адрес gRPC реестра агентов
+
+// Период сигналов активности агентов и количество пропущенных сигналов, после которого агент удаляется из реестра
+var (
+	heartbeatInterval = time.Duration(max(envInt("CALCULATOR_AGENT_HEARTBEAT_SECONDS", 5), 1)) * time.Second
+	missedHeartbeats  = max(envInt("CALCULATOR_AGENT_MISSED_HEARTBEATS", 3), 1)
+)
+
+// Общий токен агентов: без него реестр не принимает ни регистрацию, ни отчеты о вычислениях
+var agentToken = os.Getenv("CALCULATOR_AGENT_TOKEN")
+
+// Зарегистрированные агенты
+var registry = newAgentRegistry(heartbeatInterval * time.Duration(missedHeartbeats))
+
+// registeredAgent - агент, зарегистрированный в оркестраторе.
+type registeredAgent struct {
+	ID             string
+	URL            string // URL HTTP сервера агента, он же operation_server отправленных ему вычислений
+	GRPCAddr       string // Адрес gRPC сервера агента
+	Pull           bool   // Агент сам запрашивает задачи через GetTask; адреса у него может не быть
+	MaxConcurrency int
+	QueueSize      int
+	Labels         map[string]string
+	Version        string
+	RegisteredAt   time.Time
+	LastHeartbeat  time.Time
+	Running        int // Количество выполняемых вычислений по последнему сигналу активности
+	QueueDepth     int // Количество ожидающих вычислений по последнему сигналу активности
+}
+
+// agentRegistry хранит агентов, от которых приходят сигналы активности.
+type agentRegistry struct {
+	mu     sync.Mutex
+	ttl    time.Duration // Время без сигналов активности, после которого агент удаляется
+	agents map[string]*registeredAgent
+}
+
+func newAgentRegistry(ttl time.Duration) *agentRegistry {
+	return &agentRegistry{ttl: ttl, agents: make(map[string]*registeredAgent)}
+}
+
+// register добавляет агента в реестр или заменяет сведения об агенте с тем же идентификатором.
+func (r *agentRegistry) register(agent registeredAgent, now time.Time) {
+	agent.RegisteredAt, agent.LastHeartbeat = now, now
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agents[agent.ID] = &agent
+}
+
+// heartbeat отмечает сигнал активности агента id. Возвращает ok = false, если агента нет в реестре,
+// и freed = true, если с прошлого сигнала агент завершил часть принятых вычислений.
+func (r *agentRegistry) heartbeat(id string, running, queueDepth int, now time.Time) (ok, freed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agent, ok := r.agents[id]
+	if !ok {
+		return false, false
+	}
+	freed = running+queueDepth < agent.Running+agent.QueueDepth
+	agent.LastHeartbeat = now
+	agent.Running, agent.QueueDepth = running, queueDepth
+	return true, freed
+}
+
+// noteDispatch учитывает вычисление, отправленное агенту id, в его загрузке до следующего сигнала активности.
+func (r *agentRegistry) noteDispatch(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if agent, ok := r.agents[id]; ok {
+		agent.QueueDepth++
+	}
+}
+
+// noteFinished учитывает вычисление, о завершении которого сообщил агент id, до следующего сигнала активности.
+func (r *agentRegistry) noteFinished(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if agent, ok := r.agents[id]; ok {
+		if agent.Running > 0 {
+			agent.Running--
+		} else if agent.QueueDepth > 0 {
+			agent.QueueDepth--
+		}
+	}
+}
+
+// deregister удаляет агента id из реестра.
+func (r *agentRegistry) deregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.agents, id)
+}
+
+// get возвращает агента id из реестра.
+func (r *agentRegistry) get(id string) (registeredAgent, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if agent, ok := r.agents[id]; ok {
+		return *agent, true
+	}
+	return registeredAgent{}, false
+}
+
+// live удаляет агентов, пропустивших сигналы активности, и возвращает остальных в порядке URL и идентификаторов.
+func (r *agentRegistry) live(now time.Time) []registeredAgent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agents := make([]registeredAgent, 0, len(r.agents))
+	for id, agent := range r.agents {
+		if now.Sub(agent.LastHeartbeat) > r.ttl {
+			log.Printf("Agent %s at %s missed heartbeats since %s, removing it", id, agent.URL, agent.LastHeartbeat.Format(time.RFC3339))
+			delete(r.agents, id)
+			continue
+		}
+		agents = append(agents, *agent)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].URL != agents[j].URL {
+			return agents[i].URL < agents[j].URL
+		}
+		return agents[i].ID < agents[j].ID
+	})
+	return agents
+}
+
+// registryService реализует gRPC сервис AgentRegistryService.
+// Через него агенты сообщают и результаты вычислений, поэтому в базу данных пишет только оркестратор.
+type registryService struct {
+	pb.UnimplementedAgentRegistryServiceServer
+	registry *agentRegistry
+	db       *sql.DB
+}
+
+func (s *registryService) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	if req.AgentId == "" {
+		return nil, status.Error(codes.InvalidArgument, "agent_id is required")
+	}
+	if !req.Pull && (req.HttpUrl == "" || req.GrpcAddr == "") {
+		return nil, status.Error(codes.InvalidArgument, "http_url and grpc_addr are required unless the agent pulls tasks")
+	}
+	if req.MaxConcurrency < 1 {
+		return nil, status.Error(codes.InvalidArgument, "max_concurrency must be positive")
+	}
+
+	s.registry.register(registeredAgent{
+		ID:             req.AgentId,
+		URL:            req.HttpUrl,
+		GRPCAddr:       req.GrpcAddr,
+		Pull:           req.Pull,
+		MaxConcurrency: int(req.MaxConcurrency),
+		QueueSize:      int(req.QueueSize),
+		Labels:         req.Labels,
+		Version:        req.Version,
+	}, time.Now())
+	if req.Pull {
+		log.Printf("Agent %s registered in pull mode (max concurrency %d, queue size %d)", req.AgentId, req.MaxConcurrency, req.QueueSize)
+	} else {
+		log.Printf("Agent %s registered at %s (gRPC %s, max concurrency %d, queue size %d)",
+			req.AgentId, req.HttpUrl, req.GrpcAddr, req.MaxConcurrency, req.QueueSize)
+		dispatch.notify() // Новый агент может сразу принять ожидающие вычисления
+	}
+
+	return &pb.RegisterResponse{HeartbeatIntervalSeconds: int32(heartbeatInterval / time.Second)}, nil
+}
+
+func (s *registryService) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	ok, freed := s.registry.heartbeat(req.AgentId, int(req.Running), int(req.QueueDepth), time.Now())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "agent %q is not registered", req.AgentId)
+	}
+	if freed {
+		dispatch.notify()
+	}
+	return &pb.HeartbeatResponse{}, nil
+}
+
+func (s *registryService) Deregister(ctx context.Context, req *pb.DeregisterRequest) (*pb.DeregisterResponse, error) {
+	s.registry.deregister(req.AgentId)
+	log.Printf("Agent %s deregistered", req.AgentId)
+	return &pb.DeregisterResponse{}, nil
+}
+
+// abort возвращает в очередь вычисление, попытку attempt которого прервал агент agentID.
+// Не начатая попытка отменяется без расхода попыток, а прерванное выполнение считается неудачной попыткой.
+func (s *registryService) abort(id, attempt int, agentID, reason string) (bool, error) {
+	released, err := database.ReleaseAttempt(s.db, id, attempt, agentID)
+	if err != nil || released {
+		return released, err
+	}
+
+	userId, operation, attempts, aborted, err := database.AbortCalculation(s.db, id, attempt, agentID)
+	if err != nil || !aborted {
+		return aborted, err
+	}
+	if reason == "" {
+		reason = "unknown error"
+	}
+	handleFailedAttempt(s.db, id, userId, operation, attempts, "aborted by agent: "+reason, time.Now().UTC())
+	return true, nil
+}
+
+// ReportResult записывает отчет агента о попытке вычисления, если эта попытка все еще назначена агенту.
+func (s *registryService) ReportResult(ctx context.Context, req *pb.ReportResultRequest) (*pb.ReportResultResponse, error) {
+	if req.AgentId == "" || req.CalculationId < 1 || req.Attempt < 1 {
+		return nil, status.Error(codes.InvalidArgument, "agent_id, calculation_id and attempt are required")
+	}
+	id, attempt := int(req.CalculationId), int(req.Attempt)
+
+	var (
+		accepted bool
+		err      error
+	)
+	switch req.Outcome {
+	case pb.ReportResultRequest_STARTED:
+		accepted, err = database.MarkCalculationStarted(s.db, id, attempt, req.AgentId)
+	case pb.ReportResultRequest_COMPLETED:
+		accepted, err = database.CompleteCalculation(s.db, id, attempt, req.AgentId, req.Result)
+	case pb.ReportResultRequest_ABORTED:
+		accepted, err = s.abort(id, attempt, req.AgentId, req.Error)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown outcome %v", req.Outcome)
+	}
+	if err != nil {
+		log.Printf("Error recording report of agent %s on calculation ID %d: %v", req.AgentId, id, err)
+		return nil, status.Error(codes.Unavailable, "failed to record the report, retry later")
+	}
+	if !accepted {
+		log.Printf("Ignoring %v report of agent %s on calculation ID %d, attempt %d: the attempt is no longer assigned to it",
+			req.Outcome, req.AgentId, id, attempt)
+		return nil, status.Errorf(codes.FailedPrecondition, "attempt %d of calculation %d is not assigned to agent %q", attempt, id, req.AgentId)
+	}
+
+	if req.Outcome == pb.ReportResultRequest_COMPLETED {
+		log.Printf("Calculation ID %d completed by agent %s, attempt %d. Result: %.6f", id, req.AgentId, attempt, req.Result)
+	} else if req.Outcome == pb.ReportResultRequest_ABORTED {
+		log.Printf("Calculation ID %d aborted by agent %s, attempt %d: %s", id, req.AgentId, attempt, req.Error)
+	}
+	if req.Outcome != pb.ReportResultRequest_STARTED {
+		s.registry.noteFinished(req.AgentId)
+		dispatch.notify() // У агента освободилось место
+	}
+	return &pb.ReportResultResponse{}, nil
+}
+
+// agentAuthInterceptor пропускает к реестру только вызовы с токеном агентов в метаданных authorization ("Bearer <token>").
+func agentAuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	expected := []byte("Bearer " + token)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), expected) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid agent token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// newAgentRegistryServer создает gRPC сервер с сервисом AgentRegistryService и проверкой токена агентов.
+func newAgentRegistryServer(db *sql.DB, token string) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(agentAuthInterceptor(token)))
+	pb.RegisterAgentRegistryServiceServer(server, &registryService{registry: registry, db: db})
+	return server
+}
+
+// listenAgentRegistry открывает адрес addr для gRPC реестра агентов. Без токена агентов реестр не запускается.
+func listenAgentRegistry(addr string) (net.Listener, error) {
+	if agentToken == "" {
+		return nil, errors.New("CALCULATOR_AGENT_TOKEN is not set")
+	}
+	return net.Listen("tcp", addr)
+}
+
+// serveAgentRegistry обслуживает gRPC реестр агентов на адресе, открытом listenAgentRegistry.
+func serveAgentRegistry(db *sql.DB, lis net.Listener) error {
+	log.Printf("Agent registry is running on %s...", lis.Addr())
+	return newAgentRegistryServer(db, agentToken).Serve(lis)
+}
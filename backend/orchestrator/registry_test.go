@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+	"calculatorapi/utility/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// withRegistry подменяет реестр агентов и пул соединений с ними на время теста реестром с агентами agents.
+func withRegistry(t *testing.T, agents ...registeredAgent) {
+	t.Helper()
+	oldRegistry, oldConns := registry, agentConns
+	registry, agentConns = newAgentRegistry(time.Minute), newAgentConnPool()
+	for _, agent := range agents {
+		registry.register(agent, time.Now())
+	}
+	t.Cleanup(func() {
+		agentConns.retain(nil)
+		registry, agentConns = oldRegistry, oldConns
+	})
+}
+
+func TestAgentRegistry(t *testing.T) {
+	r := newAgentRegistry(15 * time.Second)
+	now := time.Now()
+
+	r.register(registeredAgent{ID: "b", URL: "http://localhost:8082"}, now)
+	r.register(registeredAgent{ID: "a", URL: "http://localhost:8081"}, now)
+	if agents := r.live(now); len(agents) != 2 || agents[0].ID != "a" || agents[1].ID != "b" {
+		t.Fatalf("Expected both agents ordered by URL, got %+v", agents)
+	}
+
+	if ok, freed := r.heartbeat("a", 2, 1, now.Add(10*time.Second)); !ok || freed {
+		t.Errorf("Expected the heartbeat to be accepted without freed capacity, got %v, %v", ok, freed)
+	}
+	if ok, _ := r.heartbeat("c", 0, 0, now); ok {
+		t.Error("Heartbeat of an unknown agent was accepted")
+	}
+
+	// Агент b пропустил сигналы активности и удаляется
+	agents := r.live(now.Add(20 * time.Second))
+	if len(agents) != 1 || agents[0].ID != "a" || agents[0].Running != 2 || agents[0].QueueDepth != 1 {
+		t.Fatalf("Expected only the agent with a recent heartbeat, got %+v", agents)
+	}
+	if ok, _ := r.heartbeat("b", 0, 0, now.Add(20*time.Second)); ok {
+		t.Error("Expected the dropped agent to register again")
+	}
+	if _, freed := r.heartbeat("a", 1, 0, now.Add(20*time.Second)); !freed {
+		t.Error("Expected completed calculations to be reported as freed capacity")
+	}
+
+	r.deregister("a")
+	if agents := r.live(now.Add(20 * time.Second)); len(agents) != 0 {
+		t.Errorf("Expected an empty registry after deregistration, got %+v", agents)
+	}
+}
+
+func TestListenAgentRegistry(t *testing.T) {
+	oldToken := agentToken
+	t.Cleanup(func() { agentToken = oldToken })
+
+	agentToken = ""
+	if _, err := listenAgentRegistry("127.0.0.1:0"); err == nil {
+		t.Error("Expected the registry not to start without the agent token")
+	}
+
+	agentToken = "agent-secret"
+	lis, err := listenAgentRegistry("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start the registry: %v", err)
+	}
+	defer lis.Close()
+
+	// Занятый адрес - тоже ошибка запуска
+	if _, err := listenAgentRegistry(lis.Addr().String()); err == nil {
+		t.Error("Expected an error for an address in use")
+	}
+}
+
+func TestRegistryService(t *testing.T) {
+	withRegistry(t)
+
+	lis := bufconn.Listen(1 << 20)
+	server := newAgentRegistryServer(nil, "agent-secret")
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewAgentRegistryServiceClient(conn)
+
+	// Без токена агентов или с неверным токеном реестр не принимает ни регистрацию, ни отчеты, ни запросы задач
+	for _, ctx := range []context.Context{
+		context.Background(),
+		metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"),
+	} {
+		if _, err := client.Register(ctx, &pb.RegisterRequest{AgentId: "agent-1", GrpcAddr: "localhost:50051"}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated registration, got %v", err)
+		}
+		if _, err := client.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 7, Attempt: 1}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated report, got %v", err)
+		}
+		if _, err := client.GetTask(ctx, &pb.GetTaskRequest{AgentId: "agent-1", MaxTasks: 1}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated task request, got %v", err)
+		}
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer agent-secret")
+	if _, err := client.Register(ctx, &pb.RegisterRequest{AgentId: "agent-1", HttpUrl: "http://localhost:8081"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without a gRPC address, got %v", err)
+	}
+	if _, err := client.Heartbeat(ctx, &pb.HeartbeatRequest{AgentId: "agent-1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unregistered agent, got %v", err)
+	}
+
+	resp, err := client.Register(ctx, &pb.RegisterRequest{
+		AgentId: "agent-1", HttpUrl: "http://localhost:8081", GrpcAddr: "localhost:50051",
+		MaxConcurrency: 4, QueueSize: 10, Labels: map[string]string{"region": "eu"}, Version: "1.2.0",
+	})
+	if err != nil || resp.HeartbeatIntervalSeconds != int32(heartbeatInterval/time.Second) {
+		t.Fatalf("Unexpected registration result %v, %v", resp, err)
+	}
+	if _, err := client.Heartbeat(ctx, &pb.HeartbeatRequest{AgentId: "agent-1", Running: 3, QueueDepth: 2}); err != nil {
+		t.Errorf("Unexpected heartbeat error: %v", err)
+	}
+
+	agents := registry.live(time.Now())
+	if len(agents) != 1 {
+		t.Fatalf("Expected one registered agent, got %+v", agents)
+	}
+	if got := agents[0]; got.GRPCAddr != "localhost:50051" || got.MaxConcurrency != 4 || got.QueueSize != 10 ||
+		got.Labels["region"] != "eu" || got.Version != "1.2.0" || got.Running != 3 || got.QueueDepth != 2 {
+		t.Errorf("Unexpected registered agent %+v", got)
+	}
+
+	// Агенту, который сам запрашивает задачи, адреса не нужны
+	if _, err := client.Register(ctx, &pb.RegisterRequest{AgentId: "agent-2", MaxConcurrency: 2, Pull: true}); err != nil {
+		t.Fatalf("Unexpected error registering a pull agent: %v", err)
+	}
+	if agent, ok := registry.get("agent-2"); !ok || !agent.Pull || agent.URL != "" {
+		t.Errorf("Unexpected pull agent %+v", agent)
+	}
+
+	for _, id := range []string{"agent-1", "agent-2"} {
+		if _, err := client.Deregister(ctx, &pb.DeregisterRequest{AgentId: id}); err != nil {
+			t.Errorf("Unexpected deregistration error: %v", err)
+		}
+	}
+	if agents := registry.live(time.Now()); len(agents) != 0 {
+		t.Errorf("Expected the agent to be removed, got %+v", agents)
+	}
+}
+
+func TestReportResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	withRegistry(t, registeredAgent{ID: "agent-1", URL: "http://localhost:8081", MaxConcurrency: 2, Running: 1, QueueDepth: 1})
+	s := &registryService{registry: registry, db: db}
+	ctx := context.Background()
+
+	if _, err := s.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 7}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument without an attempt, got %v", err)
+	}
+
+	mock.ExpectExec("SET server_status = 'work'").WithArgs(7, 2, "agent-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET result = \\$4, status = 'completed'").WithArgs(7, 2, "agent-1", 4.0, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, outcome := range []pb.ReportResultRequest_Outcome{pb.ReportResultRequest_STARTED, pb.ReportResultRequest_COMPLETED} {
+		if _, err := s.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 7, Attempt: 2, Outcome: outcome, Result: 4}); err != nil {
+			t.Errorf("Unexpected error reporting %v: %v", outcome, err)
+		}
+	}
+	if agents := registry.live(time.Now()); agents[0].Running+agents[0].QueueDepth != 1 {
+		t.Errorf("Expected the completed calculation to free a slot, got %+v", agents[0])
+	}
+
+	// Аренда, которую агент не смог поставить в очередь, возвращается в очередь без расхода попыток
+	mock.ExpectExec("SET status = 'created'(.+)attempts = attempts - 1").WithArgs(9, 3, "agent-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := s.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 9, Attempt: 3, Outcome: pb.ReportResultRequest_ABORTED}); err != nil {
+		t.Errorf("Unexpected error reporting an unstarted attempt: %v", err)
+	}
+
+	// Прерванное выполнение считается неудачной попыткой и возвращается в очередь с задержкой
+	mock.ExpectExec("SET status = 'created'(.+)attempts = attempts - 1").WithArgs(10, 1, "agent-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SET server_status = 'aborted'").WithArgs(10, 1, "agent-1").
+		WillReturnRows(sqlmock.NewRows([]string{"userId", "operation", "attempts"}).AddRow(4, "2/0", 1))
+	mock.ExpectExec("SET status = 'created', start_time = NULL, server_status = NULL, next_attempt_time").
+		WithArgs(sqlmock.AnyArg(), "aborted by agent: context deadline exceeded", 10).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := s.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 10, Attempt: 1, Outcome: pb.ReportResultRequest_ABORTED,
+		Error: "context deadline exceeded"}); err != nil {
+		t.Errorf("Unexpected error reporting an aborted run: %v", err)
+	}
+
+	// Попытка переназначена другому агенту или вычисление отменено
+	mock.ExpectExec("attempts = attempts - 1").WithArgs(8, 1, "agent-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SET server_status = 'aborted'").WithArgs(8, 1, "agent-1").WillReturnRows(sqlmock.NewRows([]string{"userId", "operation", "attempts"}))
+	_, err = s.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 8, Attempt: 1, Outcome: pb.ReportResultRequest_ABORTED})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a stale attempt, got %v", err)
+	}
+
+	// Запоздалый отчет о начале попытки, после которой вычисление вернулось в очередь, не меняет его состояние
+	mock.ExpectExec("SET server_status = 'work'(.+)status = 'work' OR \\(status = 'created' AND server_status = 'dispatched'\\)").
+		WithArgs(10, 1, "agent-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	_, err = s.ReportResult(ctx, &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 10, Attempt: 1, Outcome: pb.ReportResultRequest_STARTED})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a start of a requeued attempt, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAbortBeforeQueued(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	withRegistry(t, registeredAgent{ID: "agent-1", URL: "http://localhost:8081", MaxConcurrency: 2, Running: 1})
+	s := &registryService{registry: registry, db: db}
+
+	// Агент принял отправленную попытку, начал и прервал ее раньше, чем оркестратор отметил ее принятой:
+	// вычисление еще в статусе 'created', но прерванная попытка все равно учитывается и возвращается в очередь
+	mock.ExpectExec("attempts = attempts - 1").WithArgs(11, 1, "agent-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SET server_status = 'aborted'").WithArgs(11, 1, "agent-1").
+		WillReturnRows(sqlmock.NewRows([]string{"userId", "operation", "attempts"}).AddRow(4, "2+2", 1))
+	mock.ExpectExec("SET status = 'created', start_time = NULL(.+)status = 'created' AND server_status = 'aborted'").
+		WithArgs(sqlmock.AnyArg(), "aborted by agent: shutting down", 11).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := s.ReportResult(context.Background(), &pb.ReportResultRequest{AgentId: "agent-1", CalculationId: 11, Attempt: 1,
+		Outcome: pb.ReportResultRequest_ABORTED, Error: "shutting down"}); err != nil {
+		t.Fatalf("Unexpected error reporting an abort before the attempt was marked queued: %v", err)
+	}
+
+	// Запоздалая отметка о принятии не переводит прерванную попытку в статус 'work'
+	mock.ExpectExec("SET status = 'work'(.+)server_status IN \\('dispatched', 'work'\\)").WithArgs(11, 1, "agent-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := database.MarkCalculationQueued(db, 11, 1, "agent-1"); err != nil {
+		t.Errorf("Unexpected error marking the calculation queued: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const failedCalculationsLimit = 100 // Количество вычислений в ответе на запрос списка неудавшихся
+
+// retryPolicy определяет, сколько раз и с какими задержками повторяется неудачное выполнение вычисления.
+type retryPolicy struct {
+	MaxAttempts int           // Максимальное количество попыток, после которого вычисление переводится в 'failed'
+	BaseDelay   time.Duration // Задержка перед второй попыткой, каждая следующая вдвое больше
+	MaxDelay    time.Duration // Максимальная задержка между попытками
+	Grace       time.Duration // Запас времени сверх ожидаемой длительности для вычислений без inactive_server_time
+}
+
+// Политика повторов, задается переменными окружения при запуске оркестратора
+var retries = loadRetryPolicy()
+
+// loadRetryPolicy читает политику повторов из переменных окружения.
+func loadRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		MaxAttempts: envInt("CALCULATOR_MAX_ATTEMPTS", 3),
+		BaseDelay:   time.Duration(envInt("CALCULATOR_RETRY_BASE_DELAY_SECONDS", 10)) * time.Second,
+		MaxDelay:    time.Duration(envInt("CALCULATOR_RETRY_MAX_DELAY_SECONDS", 600)) * time.Second,
+		Grace:       time.Duration(envInt("CALCULATOR_ATTEMPT_GRACE_SECONDS", 180)) * time.Second,
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return policy
+}
+
+// backoff возвращает задержку перед попыткой, следующей за attempt неудачными.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// handleFailedAttempt обрабатывает неудачную попытку attempts выполнения вычисления: возвращает его в очередь
+// с задержкой или, если попытки исчерпаны, переводит в статус 'failed' с причиной reason.
+func handleFailedAttempt(db *sql.DB, id, userId int, operation string, attempts int, reason string, now time.Time) {
+	status := "created"
+	var (
+		updated bool
+		err     error
+	)
+	if attempts >= retries.MaxAttempts {
+		status = "failed"
+		updated, err = database.FailCalculation(db, id, fmt.Sprintf("%s after %d attempts", reason, attempts), now)
+	} else {
+		updated, err = database.RetryCalculation(db, id, reason, now.Add(retries.backoff(attempts)))
+	}
+	if err != nil {
+		log.Printf("Error handling failed attempt of operation ID %d: %v", id, err)
+		return
+	}
+	if !updated {
+		return
+	}
+
+	log.Printf("Operation ID %d %s after attempt %d, status set to '%s'.", id, reason, attempts, status)
+	events.publish(models.CalculationEvent{ID: id, UserId: userId, Operation: operation, Status: status, Time: now})
+}
+
+// failedCalculationsHandler обрабатывает GET /api/v1/admin/calculations/failed:
+// администратор получает последние вычисления, все попытки выполнения которых закончились неудачей.
+func failedCalculationsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if _, ok := authenticateAdmin(w, r); !ok {
+			return
+		}
+
+		calculations, err := database.FetchFailedCalculations(db, failedCalculationsLimit)
+		if err != nil {
+			log.Printf("Error fetching failed calculations: %v", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if calculations == nil {
+			calculations = []models.FailedCalculation{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(calculations)
+	}
+}
+
+// calculationRequeueHandler обрабатывает POST /api/v1/admin/calculations/{id}/requeue:
+// администратор возвращает вычисление из статуса 'failed' в очередь с новым набором попыток.
+func calculationRequeueHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, ok := authenticateAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid calculation id", http.StatusBadRequest)
+			return
+		}
+
+		calc, err := database.GetCalculationResultByID(db, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			sendJSONError(w, errCalculationNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		requeued, err := database.RequeueCalculation(db, id)
+		if err != nil {
+			log.Printf("Error requeueing calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !requeued {
+			sendJSONError(w, "Calculation has not failed", http.StatusConflict)
+			return
+		}
+		log.Printf("Admin %s requeued calculation %d", claims.Login, id)
+		dispatch.notify()
+
+		calc.Status = "created"
+		calc.Result = 0
+		events.publish(models.CalculationEvent{ID: id, UserId: calc.UserId, Operation: calc.Operation, Status: calc.Status, Time: time.Now().UTC()})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(calc)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// withRetries подменяет политику повторов на время теста.
+func withRetries(t *testing.T, policy retryPolicy) {
+	t.Helper()
+	old := retries
+	retries = policy
+	t.Cleanup(func() { retries = old })
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := retryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Second, MaxDelay: time.Minute}
+	tests := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		4:  time.Minute,
+		30: time.Minute,
+	}
+	for attempt, want := range tests {
+		if got := policy.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestHandleFailedAttempt(t *testing.T) {
+	withRetries(t, retryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Second, MaxDelay: time.Minute})
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		attempts   int
+		mock       func(mock sqlmock.Sqlmock)
+		wantStatus string
+	}{
+		{
+			name: "retried with backoff", attempts: 2, wantStatus: "created",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SET status = 'created'").WithArgs(now.Add(20*time.Second), "timed out", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "attempts exhausted", attempts: 3, wantStatus: "failed",
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SET status = 'failed'").WithArgs(now, "timed out after 3 attempts", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "finished meanwhile", attempts: 3,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("SET status = 'failed'").WithArgs(now, "timed out after 3 attempts", 7).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mock(mock)
+
+			sub := events.subscribe(7, 0)
+			defer events.unsubscribe(sub)
+
+			handleFailedAttempt(db, 7, 4, "2/0", tt.attempts, "timed out", now)
+
+			select {
+			case ev := <-sub.ch:
+				if ev.Status != tt.wantStatus {
+					t.Errorf("Expected event with status %q, got %q", tt.wantStatus, ev.Status)
+				}
+			default:
+				if tt.wantStatus != "" {
+					t.Errorf("Expected event with status %q", tt.wantStatus)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestCalculationRequeueHandler(t *testing.T) {
+	resultRows := func(status string) *sqlmock.Rows {
+		return calculationRows(resultColumns, []interface{}{"2/0", nil, status, 3})
+	}
+
+	tests := []struct {
+		name       string
+		admins     string
+		mock       func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{name: "not admin", admins: "root", wantStatus: http.StatusForbidden},
+		{
+			name: "not failed", admins: "tester", wantStatus: http.StatusConflict,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("completed"))
+				mock.ExpectExec("UPDATE calculations").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "requeued", admins: "tester", wantStatus: http.StatusOK,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("failed"))
+				mock.ExpectExec("SET status = 'created', attempts_base = attempts").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withAdmins(t, tt.admins)
+			withDispatcher(t)
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			if tt.mock != nil {
+				tt.mock(mock)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/calculations/7/requeue", nil)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, 1))
+			req.SetPathValue("id", "7")
+			rr := httptest.NewRecorder()
+			calculationRequeueHandler(db).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && !strings.Contains(rr.Body.String(), `"status":"created"`) {
+				t.Errorf("Expected the requeued calculation in the response, got %s", rr.Body.String())
+			}
+			if woken := len(dispatch.wake) != 0; woken != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("Expected dispatcher wakeup %v, got %v", tt.wantStatus == http.StatusOK, woken)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestFailedCalculationsHandler(t *testing.T) {
+	withAdmins(t, "tester")
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("WHERE status = 'failed'").WithArgs(failedCalculationsLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "operation", "attempts", "failure_reason", "end_time"}).
+			AddRow(7, 3, "2/0", 3, "timed out after 3 attempts", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/calculations/failed", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 1))
+	rr := httptest.NewRecorder()
+	failedCalculationsHandler(db).ServeHTTP(rr, req)
+
+	want := `[{"id":7,"userId":3,"operation":"2/0","attempts":3,"failure_reason":"timed out after 3 attempts","end_time":"2026-03-01T10:00:00Z"}]`
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != want {
+		t.Errorf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
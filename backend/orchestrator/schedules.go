@@ -0,0 +1,392 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // База часовых поясов на случай, если в системе её нет
+
+	"calculatorapi/utility/cron"     // Пакет для разбора выражений cron
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const (
+	schedulerPollInterval = 15 * time.Second // Период проверки расписаний, время запуска которых наступило
+	schedulerBatchSize    = 50               // Количество расписаний, запускаемых за один проход
+	scheduleRunsLimit     = 100              // Количество последних вычислений расписания в ответе
+)
+
+// Тело запроса POST /api/v1/schedules и PUT /api/v1/schedules/{id}.
+// Задается ровно одно из полей cron и run_at. При изменении незаданные (nil) поля сохраняют текущее значение.
+type scheduleRequest struct {
+	Operation          *string    `json:"operation"`
+	Cron               *string    `json:"cron"`
+	RunAt              *time.Time `json:"run_at"`
+	Timezone           *string    `json:"timezone"`
+	AddDuration        *int       `json:"add_duration"`
+	SubtractDuration   *int       `json:"subtract_duration"`
+	MultiplyDuration   *int       `json:"multiply_duration"`
+	DivideDuration     *int       `json:"divide_duration"`
+	InactiveServerTime *int       `json:"inactive_server_time"`
+	Priority           *int       `json:"priority"`
+	Enabled            *bool      `json:"enabled"`
+}
+
+// newSchedule создает расписание пользователя со значениями по умолчанию из его настроек.
+func newSchedule(settings models.UserSettings) models.Schedule {
+	return models.Schedule{
+		UserId:             settings.UserId,
+		Timezone:           "UTC",
+		AddDuration:        settings.AddDuration,
+		SubtractDuration:   settings.SubtractDuration,
+		MultiplyDuration:   settings.MultiplyDuration,
+		DivideDuration:     settings.DivideDuration,
+		InactiveServerTime: settings.InactiveServerTime,
+		Priority:           models.DefaultPriority,
+		Enabled:            true,
+	}
+}
+
+// apply накладывает изменения на расписание. Задание cron отменяет run_at и наоборот.
+func (req scheduleRequest) apply(s models.Schedule) models.Schedule {
+	if req.Operation != nil {
+		s.Operation = *req.Operation
+	}
+	if req.Cron != nil {
+		s.Cron = strings.TrimSpace(*req.Cron)
+		if s.Cron != "" {
+			s.RunAt = nil
+		}
+	}
+	if req.RunAt != nil {
+		runAt := req.RunAt.UTC()
+		s.RunAt = &runAt
+		s.Cron = ""
+	}
+	if req.Timezone != nil {
+		s.Timezone = *req.Timezone
+	}
+	s.AddDuration = withDefault(req.AddDuration, s.AddDuration)
+	s.SubtractDuration = withDefault(req.SubtractDuration, s.SubtractDuration)
+	s.MultiplyDuration = withDefault(req.MultiplyDuration, s.MultiplyDuration)
+	s.DivideDuration = withDefault(req.DivideDuration, s.DivideDuration)
+	s.InactiveServerTime = withDefault(req.InactiveServerTime, s.InactiveServerTime)
+	s.Priority = withDefault(req.Priority, s.Priority)
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	return s
+}
+
+// validateSchedule проверяет расписание перед сохранением.
+// Новое время однократного запуска (runAtChanged) должно быть в будущем.
+func validateSchedule(s models.Schedule, runAtChanged bool, now time.Time) error {
+	if strings.TrimSpace(s.Operation) == "" {
+		return invalidRequestError{"operation is required"}
+	}
+	if err := validateExpression(s.Operation, 0); err != nil {
+		return err
+	}
+	if (s.Cron == "") == (s.RunAt == nil) {
+		return invalidRequestError{"exactly one of cron and run_at is required"}
+	}
+	if s.Cron != "" {
+		if _, err := cron.Parse(s.Cron); err != nil {
+			return invalidRequestError{"invalid cron: " + err.Error()}
+		}
+	}
+	if runAtChanged && s.RunAt != nil && !s.RunAt.After(now) {
+		return invalidRequestError{"run_at must be in the future"}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return invalidRequestError{fmt.Sprintf("unknown timezone %q", s.Timezone)}
+	}
+	for _, duration := range []int{s.AddDuration, s.SubtractDuration, s.MultiplyDuration, s.DivideDuration, s.InactiveServerTime} {
+		if duration < 0 {
+			return invalidRequestError{"durations must not be negative"}
+		}
+	}
+	return validatePriority(s.Priority)
+}
+
+// nextCronRun возвращает время первого срабатывания cron расписания после after или nil для однократного расписания.
+// Время считается в часовом поясе расписания, поэтому "0 9 * * *" означает 9:00 по местному времени.
+func nextCronRun(s models.Schedule, after time.Time) *time.Time {
+	if s.Cron == "" {
+		return nil
+	}
+	schedule, err := cron.Parse(s.Cron)
+	if err != nil {
+		log.Printf("Schedule %d has invalid cron %q: %v", s.ID, s.Cron, err)
+		return nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		log.Printf("Schedule %d has unknown timezone %q: %v", s.ID, s.Timezone, err)
+		return nil
+	}
+	next := schedule.Next(after.In(loc))
+	if next.IsZero() {
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+// scheduleNextRun вычисляет следующий запуск сохраняемого расписания: для cron - ближайшее срабатывание после now,
+// для однократного - run_at, если запуск в это время еще не выполнялся.
+func scheduleNextRun(s models.Schedule, now time.Time) *time.Time {
+	if s.Cron != "" {
+		return nextCronRun(s, now)
+	}
+	if s.RunAt != nil && (s.LastRunTime == nil || s.RunAt.After(*s.LastRunTime)) {
+		return s.RunAt
+	}
+	return nil
+}
+
+// runDueSchedules создает вычисления по расписаниям, время запуска которых наступило.
+// Если оркестратор был остановлен, пропущенные срабатывания cron расписания выполняются одним запуском,
+// после которого следующий запуск планируется от текущего времени. Однократные расписания,
+// время которых прошло, выполняются при первой проверке. Запуски пользователей с заполненной очередью пропускаются.
+func runDueSchedules(db *sql.DB, now time.Time) {
+	for {
+		runs, err := database.RunDueSchedules(db, now, schedulerBatchSize, quotas.MaxQueued, func(s models.Schedule) *time.Time {
+			return nextCronRun(s, now)
+		})
+		if err != nil {
+			log.Printf("Error running schedules: %v", err)
+			return
+		}
+
+		created := 0
+		for _, run := range runs {
+			if run.Skipped {
+				log.Printf("Schedule %d skipped a run: queued calculations quota of user %d is exhausted", run.ScheduleID, run.UserId)
+				continue
+			}
+			created++
+			log.Printf("Schedule %d created calculation %d", run.ScheduleID, run.CalculationID)
+			events.publish(models.CalculationEvent{ID: run.CalculationID, UserId: run.UserId, Operation: run.Operation, Status: "created", Time: run.Time})
+		}
+		if created > 0 {
+			dispatch.notify()
+		}
+		if len(runs) < schedulerBatchSize {
+			return
+		}
+	}
+}
+
+// runScheduler периодически запускает расписания до закрытия shutdownCh.
+// Первая проверка выполняется сразу, чтобы после перезапуска не ждать пропущенные запуски.
+func runScheduler(db *sql.DB, shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(schedulerPollInterval)
+	defer ticker.Stop()
+
+	runDueSchedules(db, time.Now().UTC())
+	for {
+		select {
+		case <-ticker.C:
+			runDueSchedules(db, time.Now().UTC())
+		case <-shutdownCh:
+			log.Println("Stopping scheduler.")
+			return
+		}
+	}
+}
+
+// sendScheduleError отвечает на ошибку проверки расписания.
+func sendScheduleError(w http.ResponseWriter, err error) {
+	var exprErr invalidExpressionError
+	if errors.As(err, &exprErr) {
+		sendExpressionError(w, exprErr)
+		return
+	}
+	sendJSONError(w, err.Error(), http.StatusBadRequest)
+}
+
+// schedulesHandler обрабатывает /api/v1/schedules: GET возвращает расписания пользователя, POST создает расписание.
+func schedulesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			schedules, err := database.FetchSchedulesByUser(db, claims.UserID)
+			if err != nil {
+				log.Printf("Error fetching schedules: %v", err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if schedules == nil {
+				schedules = []models.Schedule{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(schedules)
+
+		case http.MethodPost:
+			var req scheduleRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			settings, err := loadUserSettings(db, claims.UserID)
+			if err != nil {
+				log.Printf("Error fetching settings for user %d: %v", claims.UserID, err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			now := time.Now().UTC()
+			schedule := req.apply(newSchedule(settings))
+			if err := validateSchedule(schedule, true, now); err != nil {
+				sendScheduleError(w, err)
+				return
+			}
+			schedule.NextRunTime = scheduleNextRun(schedule, now)
+
+			if err := database.InsertSchedule(db, &schedule); err != nil {
+				log.Printf("Error creating schedule: %v", err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(schedule)
+
+		default:
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// loadSchedule извлекает расписание текущего пользователя по {id} из пути, отвечая ошибкой при неудаче.
+func loadSchedule(db *sql.DB, w http.ResponseWriter, r *http.Request) (*models.Schedule, bool) {
+	claims, err := authenticateRequest(r)
+	if err != nil {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid schedule id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	schedule, err := database.GetSchedule(db, id, claims.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendJSONError(w, "Schedule not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Printf("Error fetching schedule %d: %v", id, err)
+		sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return schedule, true
+}
+
+// scheduleHandler обрабатывает /api/v1/schedules/{id}: GET возвращает расписание,
+// PUT изменяет переданные поля и пересчитывает следующий запуск, DELETE удаляет расписание.
+func scheduleHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		schedule, ok := loadSchedule(db, w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			var req scheduleRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			now := time.Now().UTC()
+			*schedule = req.apply(*schedule)
+			if err := validateSchedule(*schedule, req.RunAt != nil, now); err != nil {
+				sendScheduleError(w, err)
+				return
+			}
+			schedule.NextRunTime = scheduleNextRun(*schedule, now)
+
+			found, err := database.UpdateSchedule(db, schedule)
+			if err != nil {
+				log.Printf("Error updating schedule %d: %v", schedule.ID, err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				sendJSONError(w, "Schedule not found", http.StatusNotFound)
+				return
+			}
+
+		case http.MethodDelete:
+			found, err := database.DeleteSchedule(db, schedule.ID, schedule.UserId)
+			if err != nil {
+				log.Printf("Error deleting schedule %d: %v", schedule.ID, err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				sendJSONError(w, "Schedule not found", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedule)
+	}
+}
+
+// scheduleCalculationsHandler обрабатывает GET /api/v1/schedules/{id}/calculations:
+// вычисления, созданные запусками расписания, начиная с последнего.
+func scheduleCalculationsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		schedule, ok := loadSchedule(db, w, r)
+		if !ok {
+			return
+		}
+
+		calculations, err := database.FetchScheduleCalculations(db, schedule.ID, scheduleRunsLimit)
+		if err != nil {
+			log.Printf("Error fetching calculations for schedule %d: %v", schedule.ID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if calculations == nil {
+			calculations = []models.OperationResponse{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(calculations)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"calculatorapi/utility/models"
+)
+
+// ptr возвращает указатель на значение для необязательных полей запроса.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	base := newSchedule(models.DefaultUserSettings(1))
+
+	tests := []struct {
+		name    string
+		req     scheduleRequest
+		wantErr string
+	}{
+		{name: "cron", req: scheduleRequest{Operation: ptr("2+2"), Cron: ptr("@hourly")}},
+		{name: "run at", req: scheduleRequest{Operation: ptr("2+2"), RunAt: &future}},
+		{name: "missing time", req: scheduleRequest{Operation: ptr("2+2")}, wantErr: "exactly one"},
+		{name: "invalid cron", req: scheduleRequest{Operation: ptr("2+2"), Cron: ptr("61 * * * *")}, wantErr: "invalid cron"},
+		{name: "past run at", req: scheduleRequest{Operation: ptr("2+2"), RunAt: &past}, wantErr: "future"},
+		{name: "timezone", req: scheduleRequest{Operation: ptr("2+2"), Cron: ptr("0 9 * * *"), Timezone: ptr("Mars/Olympus")}, wantErr: "timezone"},
+		{name: "expression", req: scheduleRequest{Operation: ptr("2+"), Cron: ptr("0 9 * * *")}, wantErr: "position 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedule(tt.req.apply(base), tt.req.RunAt != nil, now)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestScheduleNextRun(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 10, 30, 0, 0, time.UTC)
+
+	daily := models.Schedule{Cron: "0 9 * * *", Timezone: "Europe/Moscow"}
+	if got := scheduleNextRun(daily, now); got == nil || !got.Equal(time.Date(2024, time.March, 16, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected next run at 9:00 Moscow time, got %v", got)
+	}
+
+	runAt := now.Add(-time.Hour)
+	once := models.Schedule{RunAt: &runAt, Timezone: "UTC"}
+	if got := scheduleNextRun(once, now); got == nil || !got.Equal(runAt) {
+		t.Errorf("Expected a one-time schedule that has not run to be due, got %v", got)
+	}
+	once.LastRunTime = &now
+	if got := scheduleNextRun(once, now); got != nil {
+		t.Errorf("Expected no next run after a one-time schedule ran, got %v", got)
+	}
+}
+
+func TestRunDueSchedulesCoalescesMissedRuns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	events = newEventBroker()
+
+	// Расписание каждые 5 минут, последний запуск - три часа назад: пропущенные запуски выполняются одним
+	now := time.Date(2024, time.March, 15, 10, 32, 0, 0, time.UTC)
+	missed := now.Add(-3 * time.Hour)
+	columns := []string{"id", "userId", "operation", "cron", "run_at", "timezone", "add_duration", "subtract_duration", "multiply_duration", "divide_duration",
+		"inactive_server_time", "priority", "enabled", "next_run_time", "last_run_time", "last_calculation_id", "created_time"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM schedules").WithArgs(now, schedulerBatchSize).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 7, "2+2", "*/5 * * * *", nil, "UTC", 1, 1, 1, 1, 30, 5, true, missed, nil, nil, missed))
+	expectQueueLock(mock, 7, 0)
+	mock.ExpectQuery("INSERT INTO calculations").WithArgs(7, "2+2", now, 1, 1, 1, 1, 30, 5, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(90))
+	mock.ExpectExec("UPDATE schedules").WithArgs(time.Date(2024, time.March, 15, 10, 35, 0, 0, time.UTC), now, 90, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	runDueSchedules(db, now)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRunDueSchedulesSkipsFullQueue(t *testing.T) {
+	withQuotas(t, quotaConfig{MaxQueued: 2})
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	events = newEventBroker()
+	withDispatcher(t)
+
+	// Очередь пользователя заполнена: вычисление не создается, следующий запуск планируется как обычно
+	now := time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+	columns := []string{"id", "userId", "operation", "cron", "run_at", "timezone", "add_duration", "subtract_duration", "multiply_duration", "divide_duration",
+		"inactive_server_time", "priority", "enabled", "next_run_time", "last_run_time", "last_calculation_id", "created_time"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM schedules").WithArgs(now, schedulerBatchSize).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 7, "2+2", "* * * * *", nil, "UTC", 1, 1, 1, 1, 30, 5, true, now, nil, nil, now))
+	expectQueueLock(mock, 7, 2)
+	mock.ExpectExec("UPDATE schedules SET next_run_time = \\$1 WHERE id").WithArgs(now.Add(time.Minute), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	runDueSchedules(db, now)
+
+	if len(dispatch.wake) != 0 {
+		t.Error("Expected no dispatch for a skipped run")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSchedulesHandlerCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM user_settings").WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"add_duration", "subtract_duration", "multiply_duration", "divide_duration", "inactive_server_time", "precision", "output_format", "locale", "updated_time"}).
+			AddRow(2, 3, 4, 5, 30, 2, "decimal", "ru-RU", time.Now()))
+	mock.ExpectQuery("INSERT INTO schedules").
+		WithArgs(4, "2*3", "@daily", nil, "UTC", 2, 3, 4, 5, 30, 5, true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/schedules", strings.NewReader(`{"operation":"2*3","cron":"@daily"}`))
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr := httptest.NewRecorder()
+	schedulesHandler(db)(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var schedule models.Schedule
+	json.NewDecoder(rr.Body).Decode(&schedule)
+	if schedule.ID != 11 || schedule.NextRunTime == nil || schedule.NextRunTime.Hour() != 0 || schedule.NextRunTime.Minute() != 0 {
+		t.Errorf("Unexpected schedule %+v", schedule)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
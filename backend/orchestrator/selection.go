@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+)
+
+// agentSelector определяет порядок, в котором агентам предлагается вычисление:
+// первым пробуется первый агент, остальные - если предыдущие отказали.
+type agentSelector interface {
+	order(agents []registeredAgent) []registeredAgent
+}
+
+// Стратегия выбора агентов, задается переменной окружения CALCULATOR_AGENT_SELECTION
+var selector = loadAgentSelector()
+
+// newAgentSelector создает стратегию выбора агентов по имени:
+//   - round-robin - агенты по очереди;
+//   - least-loaded - агент с наименьшей загрузкой относительно MaxConcurrency (по умолчанию);
+//   - weighted - агенты по очереди пропорционально MaxConcurrency.
+func newAgentSelector(name string) (agentSelector, error) {
+	switch name {
+	case "round-robin":
+		return &roundRobinSelector{}, nil
+	case "least-loaded", "":
+		return leastLoadedSelector{}, nil
+	case "weighted":
+		return newWeightedSelector(), nil
+	}
+	return nil, fmt.Errorf("unknown agent selection strategy %q", name)
+}
+
+// loadAgentSelector читает стратегию выбора агентов из переменной окружения.
+func loadAgentSelector() agentSelector {
+	s, err := newAgentSelector(os.Getenv("CALCULATOR_AGENT_SELECTION"))
+	if err != nil {
+		log.Printf("%v, using least-loaded", err)
+		return leastLoadedSelector{}
+	}
+	return s
+}
+
+// roundRobinSelector начинает каждое следующее вычисление со следующего агента.
+type roundRobinSelector struct {
+	mu   sync.Mutex
+	next int
+}
+
+func (s *roundRobinSelector) order(agents []registeredAgent) []registeredAgent {
+	if len(agents) == 0 {
+		return agents
+	}
+	s.mu.Lock()
+	start := s.next % len(agents)
+	s.next = start + 1
+	s.mu.Unlock()
+	return append(append([]registeredAgent{}, agents[start:]...), agents[:start]...)
+}
+
+// leastLoadedSelector предпочитает агентов с наименьшей долей занятых исполнителей,
+// считая выполняемые и ожидающие в очереди вычисления.
+type leastLoadedSelector struct{}
+
+func (leastLoadedSelector) order(agents []registeredAgent) []registeredAgent {
+	ordered := append([]registeredAgent{}, agents...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].load() < ordered[j].load() })
+	return ordered
+}
+
+// load возвращает загрузку агента: количество принятых им вычислений на одного исполнителя.
+func (a registeredAgent) load() float64 {
+	return float64(a.Running+a.QueueDepth) / float64(max(a.MaxConcurrency, 1))
+}
+
+// weightedSelector распределяет вычисления пропорционально MaxConcurrency агентов
+// алгоритмом плавного взвешенного кругового обхода: агент с вдвое большей емкостью
+// получает вдвое больше вычислений, но не подряд.
+type weightedSelector struct {
+	mu      sync.Mutex
+	current map[string]int // Текущий вес агента по идентификатору
+}
+
+func newWeightedSelector() *weightedSelector {
+	return &weightedSelector{current: make(map[string]int)}
+}
+
+func (s *weightedSelector) order(agents []registeredAgent) []registeredAgent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	current := make(map[string]int, len(agents))
+	for _, agent := range agents {
+		weight := max(agent.MaxConcurrency, 1)
+		current[agent.ID] = s.current[agent.ID] + weight
+		total += weight
+	}
+
+	ordered := append([]registeredAgent{}, agents...)
+	sort.SliceStable(ordered, func(i, j int) bool { return current[ordered[i].ID] > current[ordered[j].ID] })
+	if len(ordered) > 0 {
+		current[ordered[0].ID] -= total
+	}
+	s.current = current // Веса агентов, удаленных из реестра, забываются
+	return ordered
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	pb "calculatorapi/proto/calculator/calculatorapi/proto/calculator"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// firstIDs возвращает идентификаторы агентов, выбранных первыми в n последовательных вызовах s.
+func firstIDs(s agentSelector, agents []registeredAgent, n int) []string {
+	var ids []string
+	for i := 0; i < n; i++ {
+		ids = append(ids, s.order(agents)[0].ID)
+	}
+	return ids
+}
+
+func TestNewAgentSelector(t *testing.T) {
+	for name, expected := range map[string]string{
+		"":             "main.leastLoadedSelector",
+		"least-loaded": "main.leastLoadedSelector",
+		"round-robin":  "*main.roundRobinSelector",
+		"weighted":     "*main.weightedSelector",
+	} {
+		s, err := newAgentSelector(name)
+		if err != nil || fmt.Sprintf("%T", s) != expected {
+			t.Errorf("%q: expected %s, got %T, %v", name, expected, s, err)
+		}
+	}
+	if _, err := newAgentSelector("random"); err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
+
+func TestAgentSelectors(t *testing.T) {
+	agents := []registeredAgent{
+		{ID: "a", MaxConcurrency: 2, Running: 2},
+		{ID: "b", MaxConcurrency: 1},
+		{ID: "c", MaxConcurrency: 4, Running: 1, QueueDepth: 1},
+	}
+
+	if got := firstIDs(&roundRobinSelector{}, agents, 4); !slices.Equal(got, []string{"a", "b", "c", "a"}) {
+		t.Errorf("round-robin: got %v", got)
+	}
+	if got := (leastLoadedSelector{}).order(agents); got[0].ID != "b" || got[1].ID != "c" || got[2].ID != "a" {
+		t.Errorf("least-loaded: expected b, c, a, got %v", got)
+	}
+	// Веса 2, 1 и 4: за 7 вызовов агенты выбираются 2, 1 и 4 раза, без серий у агента c
+	if got := firstIDs(newWeightedSelector(), agents, 7); !slices.Equal(got, []string{"c", "a", "c", "b", "c", "a", "c"}) {
+		t.Errorf("weighted: got %v", got)
+	}
+	if got := (&roundRobinSelector{}).order(nil); len(got) != 0 {
+		t.Errorf("Expected no agents, got %v", got)
+	}
+}
+
+// dispatchToFakeAgents отправляет n вычислений со стратегией strategy агентам agents
+// и возвращает количество вычислений, полученных каждым агентом.
+func dispatchToFakeAgents(t *testing.T, strategy string, n int, agents ...registeredAgent) map[string]int {
+	t.Helper()
+	s, err := newAgentSelector(strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := selector
+	selector = s
+	t.Cleanup(func() { selector = old })
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	received := make(map[string]chan *pb.CalculationRequest, len(agents))
+	for i := range agents {
+		received[agents[i].ID] = make(chan *pb.CalculationRequest, n)
+		agents[i].GRPCAddr = startFakeAgent(t, acceptingAgent{received: received[agents[i].ID]})
+	}
+	withRegistry(t, agents...)
+
+	rows := queueRows()
+	for id := 1; id <= n; id++ {
+		rows.AddRow(id, 1, "2+2", 1, 1, 1, 1, 60, 0)
+	}
+	mock.ExpectQuery("^SELECT (.+) FROM calculations").WillReturnRows(rows)
+	for id := 1; id <= n; id++ {
+		mock.ExpectExec("SET operation_server = \\$1").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, id, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SET status = 'work'").WithArgs(id, 1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	submitCalculations(db)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+	counts := map[string]int{}
+	for id, ch := range received {
+		counts[id] = len(ch)
+	}
+	return counts
+}
+
+func TestSubmitCalculationsRoundRobin(t *testing.T) {
+	counts := dispatchToFakeAgents(t, "round-robin", 5,
+		registeredAgent{ID: "a", URL: "http://localhost:8081", MaxConcurrency: 1, QueueSize: 1},
+		registeredAgent{ID: "b", URL: "http://localhost:8082", MaxConcurrency: 1, QueueSize: 1},
+		registeredAgent{ID: "c", URL: "http://localhost:8083", MaxConcurrency: 1, QueueSize: 1},
+	)
+	if counts["a"] != 2 || counts["b"] != 2 || counts["c"] != 1 {
+		t.Errorf("Expected calculations to be spread evenly, got %v", counts)
+	}
+}
+
+func TestSubmitCalculationsLeastLoaded(t *testing.T) {
+	// Загрузка: a занят полностью, b свободен, c занят наполовину.
+	// Отправленные вычисления учитываются в загрузке до следующего сигнала активности.
+	counts := dispatchToFakeAgents(t, "least-loaded", 4,
+		registeredAgent{ID: "a", URL: "http://localhost:8081", MaxConcurrency: 1, Running: 1},
+		registeredAgent{ID: "b", URL: "http://localhost:8082", MaxConcurrency: 4},
+		registeredAgent{ID: "c", URL: "http://localhost:8083", MaxConcurrency: 4, Running: 2},
+	)
+	if counts["a"] != 0 || counts["b"] != 3 || counts["c"] != 1 {
+		t.Errorf("Expected calculations to go to the least loaded agents, got %v", counts)
+	}
+}
+
+func TestSubmitCalculationsWeighted(t *testing.T) {
+	counts := dispatchToFakeAgents(t, "weighted", 5,
+		registeredAgent{ID: "small", URL: "http://localhost:8081", MaxConcurrency: 1},
+		registeredAgent{ID: "large", URL: "http://localhost:8082", MaxConcurrency: 4},
+	)
+	if counts["small"] != 1 || counts["large"] != 4 {
+		t.Errorf("Expected calculations to be spread by capacity, got %v", counts)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const maxPrecision = 15 // Максимальное количество знаков после запятой
+
+// Допустимые форматы вывода результата
+var outputFormats = map[string]bool{"decimal": true, "scientific": true}
+
+// Языковой тег вида "en" или "ru-RU"
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Тело запроса PUT /api/v1/settings. Незаданные (nil) поля сохраняют текущее значение.
+type settingsUpdate struct {
+	AddDuration        *int    `json:"add_duration"`
+	SubtractDuration   *int    `json:"subtract_duration"`
+	MultiplyDuration   *int    `json:"multiply_duration"`
+	DivideDuration     *int    `json:"divide_duration"`
+	InactiveServerTime *int    `json:"inactive_server_time"`
+	Precision          *int    `json:"precision"`
+	OutputFormat       *string `json:"output_format"`
+	Locale             *string `json:"locale"`
+}
+
+// withDefault возвращает значение value или def, если значение не передано.
+func withDefault(value *int, def int) int {
+	if value == nil {
+		return def
+	}
+	return *value
+}
+
+// loadUserSettings возвращает сохраненные настройки пользователя или настройки по умолчанию.
+func loadUserSettings(db *sql.DB, userId int) (models.UserSettings, error) {
+	settings, err := database.GetUserSettings(db, userId)
+	if err == sql.ErrNoRows {
+		return models.DefaultUserSettings(userId), nil
+	}
+	if err != nil {
+		return models.UserSettings{}, err
+	}
+	return *settings, nil
+}
+
+// apply накладывает изменения на настройки.
+func (u settingsUpdate) apply(settings models.UserSettings) models.UserSettings {
+	settings.AddDuration = withDefault(u.AddDuration, settings.AddDuration)
+	settings.SubtractDuration = withDefault(u.SubtractDuration, settings.SubtractDuration)
+	settings.MultiplyDuration = withDefault(u.MultiplyDuration, settings.MultiplyDuration)
+	settings.DivideDuration = withDefault(u.DivideDuration, settings.DivideDuration)
+	settings.InactiveServerTime = withDefault(u.InactiveServerTime, settings.InactiveServerTime)
+	settings.Precision = withDefault(u.Precision, settings.Precision)
+	if u.OutputFormat != nil {
+		settings.OutputFormat = *u.OutputFormat
+	}
+	if u.Locale != nil {
+		settings.Locale = *u.Locale
+	}
+	return settings
+}
+
+// validateSettings проверяет значения настроек перед сохранением.
+func validateSettings(settings models.UserSettings) error {
+	if settings.AddDuration < 0 || settings.SubtractDuration < 0 || settings.MultiplyDuration < 0 || settings.DivideDuration < 0 {
+		return errors.New("durations must not be negative")
+	}
+	if settings.InactiveServerTime < 0 {
+		return errors.New("inactive_server_time must not be negative")
+	}
+	if settings.Precision < 0 || settings.Precision > maxPrecision {
+		return errors.New("precision must be between 0 and 15")
+	}
+	if !outputFormats[settings.OutputFormat] {
+		return errors.New("output_format must be decimal or scientific")
+	}
+	if !localePattern.MatchString(settings.Locale) {
+		return errors.New("locale must be a language tag such as en-US")
+	}
+	return nil
+}
+
+// settingsHandler обрабатывает /api/v1/settings: GET возвращает настройки пользователя,
+// PUT изменяет переданные поля и сохраняет результат.
+func settingsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		settings, err := loadUserSettings(db, claims.UserID)
+		if err != nil {
+			log.Printf("Error fetching settings for user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var update settingsUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			settings = update.apply(settings)
+			if err := validateSettings(settings); err != nil {
+				sendJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := database.SaveUserSettings(db, &settings); err != nil {
+				log.Printf("Error saving settings: %v", err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+		default:
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(settings)
+	}
+}
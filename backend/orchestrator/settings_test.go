@@ -0,0 +1,83 @@
+package main
+
+import (
+	"calculatorapi/utility/models"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCalculationRequestWithDefaults(t *testing.T) {
+	add := 0
+	req := CalculationRequest{UserId: 3, Operation: "1+2", AddDuration: &add}
+	if req.hasAllDurations() {
+		t.Error("Request without durations should not be complete")
+	}
+
+	settings := models.DefaultUserSettings(3)
+	settings.MultiplyDuration = 7
+	got := req.withDefaults(settings)
+
+	if got.AddDuration != 0 {
+		t.Errorf("Explicit zero duration must be kept, got %d", got.AddDuration)
+	}
+	if got.MultiplyDuration != 7 || got.InactiveServerTime != settings.InactiveServerTime {
+		t.Errorf("Missing durations should come from settings, got %+v", got)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	valid := models.DefaultUserSettings(1)
+	if err := validateSettings(valid); err != nil {
+		t.Errorf("Default settings should be valid: %v", err)
+	}
+
+	invalid := []func(*models.UserSettings){
+		func(s *models.UserSettings) { s.DivideDuration = -1 },
+		func(s *models.UserSettings) { s.Precision = 16 },
+		func(s *models.UserSettings) { s.OutputFormat = "roman" },
+		func(s *models.UserSettings) { s.Locale = "not a locale" },
+	}
+	for i, mutate := range invalid {
+		settings := valid
+		mutate(&settings)
+		if err := validateSettings(settings); err == nil {
+			t.Errorf("Case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestSettingsHandlerPut(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM user_settings").WithArgs(4).WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO user_settings").
+		WithArgs(4, 1, 1, 10, 1, 60, 2, "decimal", "ru-RU", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/settings", strings.NewReader(`{"multiply_duration":10,"precision":2,"locale":"ru-RU"}`))
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr := httptest.NewRecorder()
+	settingsHandler(db)(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	var settings models.UserSettings
+	json.NewDecoder(rr.Body).Decode(&settings)
+	if settings.MultiplyDuration != 10 || settings.AddDuration != 1 || settings.UpdatedTime == nil {
+		t.Errorf("Unexpected settings %+v", settings)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
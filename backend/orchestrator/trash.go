@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+// Срок хранения вычислений в корзине, после которого они удаляются окончательно.
+// Задается переменной окружения CALCULATOR_TRASH_RETENTION_HOURS.
+var trashRetention = time.Duration(envInt("CALCULATOR_TRASH_RETENTION_HOURS", 30*24)) * time.Hour
+
+var errNotDeletable = errors.New("calculation is still queued or running; cancel it or wait for it to finish")
+
+// Ответ на удаление нескольких вычислений
+type deletedResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// trashCalculation удаляет завершенное вычисление пользователя в корзину.
+func trashCalculation(db *sql.DB, id, userId int) error {
+	calc, err := getCalculation(db, id, userId)
+	if err != nil {
+		return err
+	}
+	if !isTerminalStatus(calc.Status) {
+		return errNotDeletable
+	}
+
+	trashed, err := database.TrashCalculation(db, id, userId, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !trashed {
+		return errNotDeletable
+	}
+	return nil
+}
+
+// calculationHandler обрабатывает DELETE /api/v1/calculations/{id}: удаляет завершенное вычисление в корзину.
+func calculationHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid calculation id", http.StatusBadRequest)
+			return
+		}
+
+		err = trashCalculation(db, id, claims.UserID)
+		switch {
+		case errors.Is(err, errCalculationNotFound):
+			sendJSONError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, errNotDeletable):
+			sendJSONError(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			log.Printf("Error deleting calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// calculationsHandler обрабатывает DELETE /api/v1/calculations: удаляет в корзину всю историю пользователя.
+// Вычисления, которые еще ожидают выполнения или выполняются, не удаляются.
+func calculationsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		deleted, err := database.TrashCalculationsByUser(db, claims.UserID, time.Now().UTC())
+		if err != nil {
+			log.Printf("Error deleting calculations of user %d: %v", claims.UserID, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deletedResponse{Deleted: deleted})
+	}
+}
+
+// trashHandler обрабатывает /api/v1/trash: GET возвращает вычисления в корзине, срок хранения которых не истек,
+// DELETE окончательно удаляет все вычисления пользователя из корзины.
+func trashHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			calculations, err := database.FetchTrashByUser(db, claims.UserID, time.Now().UTC().Add(-trashRetention))
+			if err != nil {
+				log.Printf("Error fetching trash of user %d: %v", claims.UserID, err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if calculations == nil {
+				calculations = []models.TrashedCalculation{}
+			}
+			for i := range calculations {
+				calculations[i].ExpiresTime = calculations[i].DeletedTime.Add(trashRetention)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(calculations)
+
+		case http.MethodDelete:
+			deleted, err := database.EmptyTrash(db, claims.UserID)
+			if err != nil {
+				log.Printf("Error emptying trash of user %d: %v", claims.UserID, err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(deletedResponse{Deleted: deleted})
+
+		default:
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// trashRestoreHandler обрабатывает POST /api/v1/trash/{id}/restore: возвращает вычисление из корзины.
+func trashRestoreHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid calculation id", http.StatusBadRequest)
+			return
+		}
+
+		restored, err := database.RestoreCalculation(db, id, claims.UserID, time.Now().UTC().Add(-trashRetention))
+		if err != nil {
+			log.Printf("Error restoring calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !restored {
+			sendJSONError(w, "Calculation is not in the trash", http.StatusNotFound)
+			return
+		}
+
+		calc, err := getCalculation(db, id, claims.UserID)
+		if err != nil {
+			log.Printf("Error fetching restored calculation %d: %v", id, err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(calc)
+	}
+}
+
+// purgeTrash окончательно удаляет вычисления, срок хранения которых в корзине истек.
+func purgeTrash(db *sql.DB) {
+	purged, err := database.PurgeTrash(db, time.Now().UTC().Add(-trashRetention))
+	if err != nil {
+		log.Printf("Error purging trash: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d calculations from the trash", purged)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"calculatorapi/utility/models"
+)
+
+func TestCalculationHandlerDelete(t *testing.T) {
+	resultRows := func(status string, userId int) *sqlmock.Rows {
+		return calculationRows(resultColumns, []interface{}{"2+2", 4.0, status, userId})
+	}
+
+	tests := []struct {
+		name       string
+		mock       func(mock sqlmock.Sqlmock)
+		wantStatus int
+	}{
+		{
+			name: "not found", wantStatus: http.StatusNotFound,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(calculationRows(resultColumns))
+			},
+		},
+		{
+			name: "other user", wantStatus: http.StatusNotFound,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("completed", 5))
+			},
+		},
+		{
+			name: "running", wantStatus: http.StatusConflict,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("work", 4))
+			},
+		},
+		{
+			name: "deleted", wantStatus: http.StatusNoContent,
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).WillReturnRows(resultRows("completed", 4))
+				mock.ExpectExec("UPDATE calculations SET deleted_time").WithArgs(sqlmock.AnyArg(), 7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mock(mock)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/calculations/7", nil)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+			req.SetPathValue("id", "7")
+			rr := httptest.NewRecorder()
+			calculationHandler(db).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestCalculationsHandlerDeletesFinishedHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE calculations SET deleted_time = \$1 WHERE userId = \$2 AND deleted_time IS NULL AND status NOT IN \('created', 'work'\)`).
+		WithArgs(sqlmock.AnyArg(), 4).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/calculations", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr := httptest.NewRecorder()
+	calculationsHandler(db).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || strings.TrimSpace(rr.Body.String()) != `{"deleted":3}` {
+		t.Errorf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTrashHandlerList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	deleted := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	mock.ExpectQuery("FROM calculations WHERE userId = \\$1 AND deleted_time >= \\$2").WithArgs(4, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "userId", "operation", "result", "status", "deleted_time"}).
+			AddRow(7, 4, "2+2", 4.0, "completed", deleted))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/trash", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr := httptest.NewRecorder()
+	trashHandler(db).ServeHTTP(rr, req)
+
+	var calculations []models.TrashedCalculation
+	if err := json.Unmarshal(rr.Body.Bytes(), &calculations); err != nil || len(calculations) != 1 {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	if calc := calculations[0]; calc.ID != 7 || !calc.ExpiresTime.Equal(deleted.Add(trashRetention)) {
+		t.Errorf("Unexpected trashed calculation %+v", calc)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTrashRestoreHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		restored   int64
+		wantStatus int
+	}{
+		{"restored", 1, http.StatusOK},
+		{"expired or missing", 0, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec("UPDATE calculations SET deleted_time = NULL").WithArgs(7, 4, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, tt.restored))
+			if tt.restored > 0 {
+				mock.ExpectQuery("FROM calculations WHERE id").WithArgs(7).
+					WillReturnRows(calculationRows(resultColumns, []interface{}{"2+2", 4.0, "completed", 4}))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/trash/7/restore", nil)
+			req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+			req.SetPathValue("id", "7")
+			rr := httptest.NewRecorder()
+			trashRestoreHandler(db).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM calculations WHERE deleted_time < \\$1").
+		WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 2))
+	purgeTrash(db)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestClearAllCalculationsRequiresAdmin(t *testing.T) {
+	withAdmins(t, "root")
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, db)
+
+	req := httptest.NewRequest(http.MethodPost, "/clear-all-calculations", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/clear-all-calculations", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a regular user, got %d", rr.Code)
+	}
+
+	withAdmins(t, "tester")
+	mock.ExpectExec("DELETE FROM calculations").WillReturnResult(sqlmock.NewResult(0, 10))
+	req = httptest.NewRequest(http.MethodPost, "/clear-all-calculations", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestToken(t, 4))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for an admin, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
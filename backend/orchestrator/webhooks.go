@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"calculatorapi/utility/database" // Пакет для работы с базой данных
+	"calculatorapi/utility/models"   // Пакет с моделями данных
+)
+
+const (
+	webhookPollInterval   = 5 * time.Second  // Период проверки завершенных вычислений и очереди доставки
+	webhookBatchSize      = 20               // Количество вычислений и доставок, обрабатываемых за один проход
+	webhookMaxAttempts    = 6                // Количество попыток, после которого доставка считается неудачной
+	webhookBaseBackoff    = 30 * time.Second // Задержка перед второй попыткой, далее удваивается
+	webhookMaxBackoff     = 1 * time.Hour    // Максимальная задержка между попытками
+	webhookRequestTimeout = 10 * time.Second // Таймаут одного HTTP запроса к получателю
+	webhookClaimLease     = 5 * time.Minute  // Время, на которое доставка забирается для отправки; затем ее заберут снова
+
+	webhookSignatureHeader = "X-Calculator-Signature" // Заголовок с HMAC-SHA256 подписью тела запроса
+	webhookEventHeader     = "X-Calculator-Event"     // Заголовок с типом события
+	webhookDeliveryHeader  = "X-Calculator-Delivery"  // Заголовок с идентификатором доставки
+)
+
+var (
+	// Вебхуки на петлевые адреса и адреса локальной сети запрещены, чтобы через них нельзя было обращаться
+	// к внутренним сервисам. Тесты разрешают их, чтобы отправлять уведомления на локальный тестовый сервер
+	allowPrivateWebhooks = false
+	lookupWebhookHost    = net.LookupIP // Разрешение имени хоста вебхука; подменяется в тестах
+)
+
+// Общий диапазон адресов провайдеров (RFC 6598), тоже недоступный из интернета
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Тело уведомления, отправляемого на вебхук
+type webhookPayload struct {
+	Event       string             `json:"event"`
+	Calculation webhookCalculation `json:"calculation"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+type webhookCalculation struct {
+	ID        int       `json:"id"`
+	UserId    int       `json:"userId"`
+	Operation string    `json:"operation"`
+	Result    float64   `json:"result"`
+	Status    string    `json:"status"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// webhookDispatcher ставит в очередь и доставляет уведомления о завершении вычислений.
+type webhookDispatcher struct {
+	client      *http.Client
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+func newWebhookDispatcher() *webhookDispatcher {
+	// Адрес проверяется еще раз при соединении: имя хоста могло начать указывать на внутренний адрес
+	// после регистрации вебхука, а получатель может перенаправить запрос. Прокси не используется,
+	// иначе проверялся бы адрес прокси, а не получателя
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: webhookRequestTimeout, Control: webhookDialControl}).DialContext
+	return &webhookDispatcher{
+		client:      &http.Client{Timeout: webhookRequestTimeout, Transport: transport},
+		maxAttempts: webhookMaxAttempts,
+		baseBackoff: webhookBaseBackoff,
+		maxBackoff:  webhookMaxBackoff,
+	}
+}
+
+// generateWebhookSecret создает случайный ключ подписи.
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// signPayload возвращает значение заголовка подписи: "sha256=" и HMAC-SHA256 тела в hex.
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateWebhookURL проверяет, что адрес вебхука является абсолютным http(s) URL,
+// имя хоста которого указывает только на адреса, разрешенные webhookIPAllowed.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid webhook url: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http or https url")
+	}
+
+	ips, err := lookupWebhookHost(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve webhook host %s: %v", u.Hostname(), err)
+	}
+	for _, ip := range ips {
+		if !webhookIPAllowed(ip) {
+			return fmt.Errorf("webhook url must not point to a loopback, private or link-local address")
+		}
+	}
+	return nil
+}
+
+// webhookIPAllowed сообщает, можно ли отправлять вебхуки на адрес ip.
+func webhookIPAllowed(ip net.IP) bool {
+	if allowPrivateWebhooks {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip))
+}
+
+// webhookDialControl запрещает соединения с адресами, на которые нельзя отправлять вебхуки.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !webhookIPAllowed(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// backoff возвращает задержку перед следующей попыткой после attempts неудачных.
+func (d *webhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.maxBackoff {
+			return d.maxBackoff
+		}
+	}
+	return delay
+}
+
+// buildDeliveries формирует доставки уведомления о вычислении на вебхук из запроса и на вебхуки пользователя.
+func buildDeliveries(calc models.FinishedCalculation, hooks []models.Webhook) ([]models.WebhookDelivery, error) {
+	event := "calculation." + calc.Status
+	payload, err := json.Marshal(webhookPayload{
+		Event: event,
+		Calculation: webhookCalculation{
+			ID:        calc.ID,
+			UserId:    calc.UserId,
+			Operation: calc.Operation,
+			Result:    calc.Result,
+			Status:    calc.Status,
+			EndTime:   calc.EndTime,
+		},
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	if calc.WebhookURL != "" {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			UserId: calc.UserId, URL: calc.WebhookURL, Secret: calc.WebhookSecret, Event: event, Payload: string(payload),
+		})
+	}
+	for _, hook := range hooks {
+		hookID := hook.ID
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID: &hookID, UserId: calc.UserId, URL: hook.URL, Secret: hook.Secret, Event: event, Payload: string(payload),
+		})
+	}
+	return deliveries, nil
+}
+
+// enqueueFinished ставит в очередь уведомления по вычислениям, недавно перешедшим в окончательный статус.
+func (d *webhookDispatcher) enqueueFinished(db *sql.DB) {
+	hooksByUser := make(map[int][]models.Webhook)
+	_, err := database.EnqueueFinishedCalculations(db, terminalStatuses, webhookBatchSize, func(calc models.FinishedCalculation) ([]models.WebhookDelivery, error) {
+		hooks, ok := hooksByUser[calc.UserId]
+		if !ok {
+			var err error
+			if hooks, err = database.FetchWebhooksByUser(db, calc.UserId); err != nil {
+				return nil, err
+			}
+			hooksByUser[calc.UserId] = hooks
+		}
+		return buildDeliveries(calc, hooks)
+	})
+	if err != nil {
+		log.Printf("Error enqueueing webhooks for finished calculations: %v", err)
+	}
+}
+
+// send выполняет одну попытку доставки и возвращает HTTP код ответа получателя.
+func (d *webhookDispatcher) send(delivery models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookSignatureHeader, signPayload(delivery.Secret, payload))
+	req.Header.Set(webhookEventHeader, delivery.Event)
+	req.Header.Set(webhookDeliveryHeader, strconv.Itoa(delivery.ID))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// attempt доставляет уведомление и сохраняет результат попытки, планируя повтор при неудаче.
+func (d *webhookDispatcher) attempt(db *sql.DB, delivery models.WebhookDelivery) models.WebhookDelivery {
+	statusCode, err := d.send(delivery)
+	now := time.Now().UTC()
+
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		delivery.Status = "delivered"
+		delivery.LastError = ""
+		delivery.NextAttempt = nil
+		delivery.DeliveredTime = &now
+		log.Printf("Webhook delivery %d for calculation %d delivered to %s", delivery.ID, delivery.CalculationID, delivery.URL)
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= d.maxAttempts {
+			delivery.Status = "failed"
+			delivery.NextAttempt = nil
+			log.Printf("Webhook delivery %d to %s failed permanently: %v", delivery.ID, delivery.URL, err)
+		} else {
+			next := now.Add(d.backoff(delivery.Attempts))
+			delivery.Status = "pending"
+			delivery.NextAttempt = &next
+			log.Printf("Webhook delivery %d to %s failed, retrying at %v: %v", delivery.ID, delivery.URL, next, err)
+		}
+	}
+
+	if err := database.UpdateWebhookDelivery(db, delivery); err != nil {
+		log.Printf("Error saving webhook delivery %d: %v", delivery.ID, err)
+	}
+	return delivery
+}
+
+// deliverDue забирает и отправляет доставки, время которых наступило.
+func (d *webhookDispatcher) deliverDue(db *sql.DB) {
+	now := time.Now().UTC()
+	deliveries, err := database.ClaimDueWebhookDeliveries(db, now, now.Add(webhookClaimLease), webhookBatchSize)
+	if err != nil {
+		log.Printf("Error fetching due webhook deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		d.attempt(db, delivery)
+	}
+}
+
+// run периодически ставит в очередь и доставляет уведомления до закрытия канала shutdownCh.
+func (d *webhookDispatcher) run(db *sql.DB, shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.enqueueFinished(db)
+			d.deliverDue(db)
+		case <-shutdownCh:
+			log.Println("Stopping webhook dispatcher.")
+			return
+		}
+	}
+}
+
+// webhooksHandler обрабатывает /api/v1/webhooks: GET возвращает вебхуки пользователя,
+// POST регистрирует новый и единожды возвращает его ключ подписи.
+func webhooksHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			hooks, err := database.FetchWebhooksByUser(db, claims.UserID)
+			if err != nil {
+				log.Printf("Error fetching webhooks: %v", err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			for i := range hooks {
+				hooks[i].Secret = ""
+			}
+			if hooks == nil {
+				hooks = []models.Webhook{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(hooks)
+
+		case http.MethodPost:
+			var req struct {
+				URL string `json:"url"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := validateWebhookURL(req.URL); err != nil {
+				sendJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			secret, err := generateWebhookSecret()
+			if err != nil {
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			hook, err := database.InsertWebhook(db, claims.UserID, req.URL, secret)
+			if err != nil {
+				log.Printf("Error registering webhook: %v", err)
+				sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(hook)
+
+		default:
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// webhookHandler обрабатывает DELETE /api/v1/webhooks/{id}.
+func webhookHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid webhook id", http.StatusBadRequest)
+			return
+		}
+
+		found, err := database.DeleteWebhook(db, id, claims.UserID)
+		if err != nil {
+			log.Printf("Error deleting webhook: %v", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			sendJSONError(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// webhookDeliveriesHandler обрабатывает GET /api/v1/webhooks/deliveries: журнал доставок пользователя.
+// Поддерживает параметры calculationId и limit.
+func webhookDeliveriesHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		calculationID := 0
+		if param := r.URL.Query().Get("calculationId"); param != "" {
+			if calculationID, err = strconv.Atoi(param); err != nil {
+				sendJSONError(w, "Invalid calculationId parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		limit := 100
+		if param := r.URL.Query().Get("limit"); param != "" {
+			if limit, err = strconv.Atoi(param); err != nil || limit <= 0 {
+				sendJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		deliveries, err := database.FetchWebhookDeliveriesByUser(db, claims.UserID, calculationID, limit)
+		if err != nil {
+			log.Printf("Error fetching webhook deliveries: %v", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if deliveries == nil {
+			deliveries = []models.WebhookDelivery{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+// webhookRedeliverHandler обрабатывает POST /api/v1/webhooks/deliveries/{id}/redeliver:
+// доставка ставится на немедленную повторную отправку с новым счетчиком попыток.
+func webhookRedeliverHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		claims, err := authenticateRequest(r)
+		if err != nil {
+			sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			sendJSONError(w, "Invalid delivery id", http.StatusBadRequest)
+			return
+		}
+
+		found, err := database.RequeueWebhookDelivery(db, id, claims.UserID)
+		if err != nil {
+			log.Printf("Error requeueing webhook delivery: %v", err)
+			sendJSONError(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			sendJSONError(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"status": "pending"})
+	}
+}
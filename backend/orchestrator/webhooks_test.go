@@ -0,0 +1,266 @@
+package main
+
+import (
+	"calculatorapi/utility/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// withPrivateWebhooks разрешает на время теста вебхуки на локальный тестовый сервер.
+func withPrivateWebhooks(t *testing.T) {
+	t.Helper()
+	allowPrivateWebhooks = true
+	t.Cleanup(func() { allowPrivateWebhooks = false })
+}
+
+func TestWebhookDeliverySigned(t *testing.T) {
+	withPrivateWebhooks(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	payload := `{"event":"calculation.completed","calculation":{"id":1,"result":4}}`
+	received := make(chan *http.Request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != payload {
+			t.Errorf("Unexpected payload %s", body)
+		}
+		if got, want := r.Header.Get(webhookSignatureHeader), signPayload("s3cret", body); got != want {
+			t.Errorf("Expected signature %s, got %s", want, got)
+		}
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	mock.ExpectExec("UPDATE webhook_deliveries").
+		WithArgs("delivered", 1, 204, nil, nil, sqlmock.AnyArg(), 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	dispatcher := newWebhookDispatcher()
+	delivery := dispatcher.attempt(db, models.WebhookDelivery{
+		ID: 10, CalculationID: 1, URL: receiver.URL, Secret: "s3cret", Event: "calculation.completed", Payload: payload, Status: "pending",
+	})
+
+	select {
+	case r := <-received:
+		if r.Header.Get(webhookDeliveryHeader) != "10" || r.Header.Get(webhookEventHeader) != "calculation.completed" {
+			t.Errorf("Unexpected webhook headers %v", r.Header)
+		}
+	default:
+		t.Fatal("Webhook receiver was not called")
+	}
+
+	if delivery.Status != "delivered" || delivery.DeliveredTime == nil {
+		t.Errorf("Expected delivery to be marked delivered, got %+v", delivery)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookDeliveryRetries(t *testing.T) {
+	withPrivateWebhooks(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	mock.ExpectExec("UPDATE webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	dispatcher := newWebhookDispatcher()
+	dispatcher.maxAttempts = 2
+	delivery := models.WebhookDelivery{ID: 1, URL: receiver.URL, Secret: "s", Payload: "{}", Status: "pending"}
+
+	delivery = dispatcher.attempt(db, delivery)
+	if delivery.Status != "pending" || delivery.NextAttempt == nil || delivery.LastStatusCode != 500 {
+		t.Errorf("Expected a scheduled retry, got %+v", delivery)
+	}
+
+	delivery = dispatcher.attempt(db, delivery)
+	if delivery.Status != "failed" || delivery.Attempts != 2 {
+		t.Errorf("Expected delivery to fail after max attempts, got %+v", delivery)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	dispatcher := newWebhookDispatcher()
+	tests := map[int]time.Duration{
+		1:  webhookBaseBackoff,
+		2:  2 * webhookBaseBackoff,
+		3:  4 * webhookBaseBackoff,
+		20: webhookMaxBackoff,
+	}
+	for attempts, want := range tests {
+		if got := dispatcher.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestEnqueueFinishedCalculations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	calcRows := sqlmock.NewRows([]string{"id", "userId", "operation", "result", "status", "end_time", "webhook_url", "webhook_secret"}).
+		AddRow(4, 2, "2*3", 6.0, "completed", time.Now(), "http://example.com/hook", "abc")
+	// Вычисления блокируются в транзакции, чтобы другой оркестратор не поставил те же уведомления
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM calculations(.+)FOR UPDATE SKIP LOCKED").WillReturnRows(calcRows)
+
+	hookRows := sqlmock.NewRows([]string{"id", "userId", "url", "secret", "created_time"}).
+		AddRow(1, 2, "http://example.com/user-hook", "def", time.Now())
+	mock.ExpectQuery("FROM webhooks WHERE userId").WithArgs(2).WillReturnRows(hookRows)
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(nil, 2, 4, "http://example.com/hook", "abc", "calculation.completed", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(1, 2, 4, "http://example.com/user-hook", "def", "calculation.completed", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("UPDATE calculations SET notified = TRUE").WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	newWebhookDispatcher().enqueueFinished(db)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeliverDueClaimsDeliveries(t *testing.T) {
+	withPrivateWebhooks(t)
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// Доставки забираются одним запросом со статусом 'sending', поэтому другой оркестратор их не отправит
+	deliveryRows := sqlmock.NewRows([]string{"id", "webhook_id", "userId", "calculation_id", "url", "secret", "event", "payload", "status", "attempts",
+		"last_status_code", "last_error", "next_attempt_time", "created_time", "delivered_time"}).
+		AddRow(3, nil, 2, 4, receiver.URL, "s", "calculation.completed", "{}", "sending", 0, nil, nil, time.Now().Add(webhookClaimLease), time.Now(), nil)
+	mock.ExpectQuery("UPDATE webhook_deliveries SET status = 'sending'(.+)FOR UPDATE SKIP LOCKED(.+)RETURNING").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), webhookBatchSize).WillReturnRows(deliveryRows)
+	mock.ExpectExec("UPDATE webhook_deliveries").
+		WithArgs("delivered", 1, 204, nil, nil, sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	newWebhookDispatcher().deliverDue(db)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBuildDeliveriesPayload(t *testing.T) {
+	calc := models.FinishedCalculation{ID: 3, UserId: 1, Operation: "1+1", Result: 2, Status: "completed"}
+	deliveries, err := buildDeliveries(calc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 0 {
+		t.Fatalf("Expected no deliveries without webhooks, got %d", len(deliveries))
+	}
+
+	calc.WebhookURL = "http://example.com"
+	deliveries, _ = buildDeliveries(calc, nil)
+	var payload webhookPayload
+	if err := json.Unmarshal([]byte(deliveries[0].Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != "calculation.completed" || payload.Calculation.Result != 2 {
+		t.Errorf("Unexpected payload %+v", payload)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	oldLookup := lookupWebhookHost
+	t.Cleanup(func() { lookupWebhookHost = oldLookup })
+	hosts := map[string][]net.IP{
+		"example.com":    {net.ParseIP("93.184.215.14")},
+		"internal.test":  {net.ParseIP("93.184.215.14"), net.ParseIP("10.0.0.5")},
+		"localhost":      {net.ParseIP("127.0.0.1")},
+		"metadata.cloud": {net.ParseIP("169.254.169.254")},
+	}
+	lookupWebhookHost = func(host string) ([]net.IP, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IP{ip}, nil
+		}
+		if ips, ok := hosts[host]; ok {
+			return ips, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	for _, raw := range []string{"https://example.com", "http://example.com:8080/hook", "http://8.8.8.8/hook"} {
+		if err := validateWebhookURL(raw); err != nil {
+			t.Errorf("Expected %q to be valid: %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"", "ftp://example.com", "/relative", "http://unknown.test/hook",
+		"http://localhost:9000/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://10.1.2.3/hook",
+		"http://192.168.0.1/hook", "http://100.64.0.1/hook", "http://0.0.0.0/hook", "http://[fe80::1]/hook",
+		"http://metadata.cloud/latest", "http://internal.test/hook"} {
+		if err := validateWebhookURL(raw); err == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestWebhookDialRejectsPrivateAddresses(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	// Адрес проверяется при соединении, даже если вебхук прошел проверку при регистрации
+	mock.ExpectExec("UPDATE webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+	delivery := newWebhookDispatcher().attempt(db, models.WebhookDelivery{ID: 1, URL: receiver.URL, Secret: "s", Payload: "{}", Status: "sending"})
+	if called {
+		t.Error("Webhook was sent to a loopback address")
+	}
+	if delivery.Status != "pending" || !strings.Contains(delivery.LastError, "not allowed") {
+		t.Errorf("Expected a rejected attempt to be retried, got %+v", delivery)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
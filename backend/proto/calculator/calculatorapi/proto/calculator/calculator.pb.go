@@ -9,6 +9,7 @@ package calculator
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -21,11 +22,14 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Запрос на вычисление
 type CalculationRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Operation     string                 `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`
-	Times         map[string]int32       `protobuf:"bytes,3,rep,name=times,proto3" json:"times,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`                                                                                 // Идентификатор операции
+	Operation     string                 `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`                                                                    // Выражение для вычисления
+	Times         map[string]int32       `protobuf:"bytes,3,rep,name=times,proto3" json:"times,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // Время выполнения операций (например, "add_duration": 2)
+	Deadline      *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=deadline,proto3" json:"deadline,omitempty"`                                                                      // Срок, после которого оркестратор переназначит вычисление
+	Attempt       int32                  `protobuf:"varint,5,opt,name=attempt,proto3" json:"attempt,omitempty"`                                                                       // Номер попытки, который агент указывает в отчетах о вычислении
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,10 +85,25 @@ func (x *CalculationRequest) GetTimes() map[string]int32 {
 	return nil
 }
 
+func (x *CalculationRequest) GetDeadline() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Deadline
+	}
+	return nil
+}
+
+func (x *CalculationRequest) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+// Ответ с результатом вычисления
 type CalculationResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Result        float64                `protobuf:"fixed64,2,opt,name=result,proto3" json:"result,omitempty"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`          // Идентификатор операции
+	Result        float64                `protobuf:"fixed64,2,opt,name=result,proto3" json:"result,omitempty"` // Результат вычисления
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -133,6 +152,7 @@ func (x *CalculationResponse) GetResult() float64 {
 	return 0
 }
 
+// Запрос статуса сервера (пустое сообщение)
 type StatusRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -169,13 +189,23 @@ func (*StatusRequest) Descriptor() ([]byte, []int) {
 	return file_calculator_proto_rawDescGZIP(), []int{2}
 }
 
+// Ответ со статусом сервера
 type StatusResponse struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	Running           bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`
-	MaxGoroutines     int32                  `protobuf:"varint,2,opt,name=maxGoroutines,proto3" json:"maxGoroutines,omitempty"`
-	CurrentGoroutines int32                  `protobuf:"varint,3,opt,name=currentGoroutines,proto3" json:"currentGoroutines,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Running              bool                   `protobuf:"varint,1,opt,name=running,proto3" json:"running,omitempty"`                                                           // Флаг, что сервер запущен и принимает вычисления
+	MaxGoroutines        int32                  `protobuf:"varint,2,opt,name=maxGoroutines,proto3" json:"maxGoroutines,omitempty"`                                               // Максимальное число одновременно выполняемых вычислений
+	CurrentGoroutines    int32                  `protobuf:"varint,3,opt,name=currentGoroutines,proto3" json:"currentGoroutines,omitempty"`                                       // Текущее число выполняемых вычислений
+	AgentId              string                 `protobuf:"bytes,4,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                                             // Идентификатор агента
+	Version              string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`                                                            // Версия сборки агента
+	UptimeSeconds        int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`                          // Время работы агента
+	Completed            int64                  `protobuf:"varint,7,opt,name=completed,proto3" json:"completed,omitempty"`                                                       // Количество вычислений, завершенных с момента запуска
+	Failed               int64                  `protobuf:"varint,8,opt,name=failed,proto3" json:"failed,omitempty"`                                                             // Количество вычислений, прерванных или не записанных с момента запуска
+	QueueDepth           int32                  `protobuf:"varint,9,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`                                   // Количество вычислений, ожидающих исполнителя
+	QueueSize            int32                  `protobuf:"varint,10,opt,name=queue_size,json=queueSize,proto3" json:"queue_size,omitempty"`                                     // Вместимость очереди
+	EstimatedWaitSeconds float64                `protobuf:"fixed64,11,opt,name=estimated_wait_seconds,json=estimatedWaitSeconds,proto3" json:"estimated_wait_seconds,omitempty"` // Ожидаемое время до начала выполнения нового вычисления
+	Runtime              *RuntimeStats          `protobuf:"bytes,12,opt,name=runtime,proto3" json:"runtime,omitempty"`                                                           // Состояние среды выполнения Go
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
 }
 
 func (x *StatusResponse) Reset() {
@@ -229,16 +259,166 @@ func (x *StatusResponse) GetCurrentGoroutines() int32 {
 	return 0
 }
 
+func (x *StatusResponse) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetCompleted() int64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetQueueDepth() int32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetQueueSize() int32 {
+	if x != nil {
+		return x.QueueSize
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetEstimatedWaitSeconds() float64 {
+	if x != nil {
+		return x.EstimatedWaitSeconds
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetRuntime() *RuntimeStats {
+	if x != nil {
+		return x.Runtime
+	}
+	return nil
+}
+
+// Состояние среды выполнения Go агента
+type RuntimeStats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	GoVersion      string                 `protobuf:"bytes,1,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`                   // Версия Go
+	NumCpu         int32                  `protobuf:"varint,2,opt,name=num_cpu,json=numCpu,proto3" json:"num_cpu,omitempty"`                           // Количество процессоров
+	Goroutines     int32                  `protobuf:"varint,3,opt,name=goroutines,proto3" json:"goroutines,omitempty"`                                 // Количество горутин процесса
+	HeapAllocBytes uint64                 `protobuf:"varint,4,opt,name=heap_alloc_bytes,json=heapAllocBytes,proto3" json:"heap_alloc_bytes,omitempty"` // Занятая куча
+	SysBytes       uint64                 `protobuf:"varint,5,opt,name=sys_bytes,json=sysBytes,proto3" json:"sys_bytes,omitempty"`                     // Память, полученная от ОС
+	NumGc          uint32                 `protobuf:"varint,6,opt,name=num_gc,json=numGc,proto3" json:"num_gc,omitempty"`                              // Количество завершенных сборок мусора
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RuntimeStats) Reset() {
+	*x = RuntimeStats{}
+	mi := &file_calculator_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuntimeStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuntimeStats) ProtoMessage() {}
+
+func (x *RuntimeStats) ProtoReflect() protoreflect.Message {
+	mi := &file_calculator_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuntimeStats.ProtoReflect.Descriptor instead.
+func (*RuntimeStats) Descriptor() ([]byte, []int) {
+	return file_calculator_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RuntimeStats) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+func (x *RuntimeStats) GetNumCpu() int32 {
+	if x != nil {
+		return x.NumCpu
+	}
+	return 0
+}
+
+func (x *RuntimeStats) GetGoroutines() int32 {
+	if x != nil {
+		return x.Goroutines
+	}
+	return 0
+}
+
+func (x *RuntimeStats) GetHeapAllocBytes() uint64 {
+	if x != nil {
+		return x.HeapAllocBytes
+	}
+	return 0
+}
+
+func (x *RuntimeStats) GetSysBytes() uint64 {
+	if x != nil {
+		return x.SysBytes
+	}
+	return 0
+}
+
+func (x *RuntimeStats) GetNumGc() uint32 {
+	if x != nil {
+		return x.NumGc
+	}
+	return 0
+}
+
 var File_calculator_proto protoreflect.FileDescriptor
 
 const file_calculator_proto_rawDesc = "" +
 	"\n" +
 	"\x10calculator.proto\x12\n" +
-	"calculator\"\xbd\x01\n" +
+	"calculator\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8f\x02\n" +
 	"\x12CalculationRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1c\n" +
 	"\toperation\x18\x02 \x01(\tR\toperation\x12?\n" +
-	"\x05times\x18\x03 \x03(\v2).calculator.CalculationRequest.TimesEntryR\x05times\x1a8\n" +
+	"\x05times\x18\x03 \x03(\v2).calculator.CalculationRequest.TimesEntryR\x05times\x126\n" +
+	"\bdeadline\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bdeadline\x12\x18\n" +
+	"\aattempt\x18\x05 \x01(\x05R\aattempt\x1a8\n" +
 	"\n" +
 	"TimesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
@@ -246,11 +426,33 @@ const file_calculator_proto_rawDesc = "" +
 	"\x13CalculationResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
 	"\x06result\x18\x02 \x01(\x01R\x06result\"\x0f\n" +
-	"\rStatusRequest\"~\n" +
+	"\rStatusRequest\"\xba\x03\n" +
 	"\x0eStatusResponse\x12\x18\n" +
 	"\arunning\x18\x01 \x01(\bR\arunning\x12$\n" +
 	"\rmaxGoroutines\x18\x02 \x01(\x05R\rmaxGoroutines\x12,\n" +
-	"\x11currentGoroutines\x18\x03 \x01(\x05R\x11currentGoroutines2\xb4\x01\n" +
+	"\x11currentGoroutines\x18\x03 \x01(\x05R\x11currentGoroutines\x12\x19\n" +
+	"\bagent_id\x18\x04 \x01(\tR\aagentId\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12%\n" +
+	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds\x12\x1c\n" +
+	"\tcompleted\x18\a \x01(\x03R\tcompleted\x12\x16\n" +
+	"\x06failed\x18\b \x01(\x03R\x06failed\x12\x1f\n" +
+	"\vqueue_depth\x18\t \x01(\x05R\n" +
+	"queueDepth\x12\x1d\n" +
+	"\n" +
+	"queue_size\x18\n" +
+	" \x01(\x05R\tqueueSize\x124\n" +
+	"\x16estimated_wait_seconds\x18\v \x01(\x01R\x14estimatedWaitSeconds\x122\n" +
+	"\aruntime\x18\f \x01(\v2\x18.calculator.RuntimeStatsR\aruntime\"\xc4\x01\n" +
+	"\fRuntimeStats\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\x01 \x01(\tR\tgoVersion\x12\x17\n" +
+	"\anum_cpu\x18\x02 \x01(\x05R\x06numCpu\x12\x1e\n" +
+	"\n" +
+	"goroutines\x18\x03 \x01(\x05R\n" +
+	"goroutines\x12(\n" +
+	"\x10heap_alloc_bytes\x18\x04 \x01(\x04R\x0eheapAllocBytes\x12\x1b\n" +
+	"\tsys_bytes\x18\x05 \x01(\x04R\bsysBytes\x12\x15\n" +
+	"\x06num_gc\x18\x06 \x01(\rR\x05numGc2\xb4\x01\n" +
 	"\x11CalculatorService\x12W\n" +
 	"\x12PerformCalculation\x12\x1e.calculator.CalculationRequest\x1a\x1f.calculator.CalculationResponse\"\x00\x12F\n" +
 	"\vCheckStatus\x12\x19.calculator.StatusRequest\x1a\x1a.calculator.StatusResponse\"\x00B Z\x1ecalculatorapi/proto/calculatorb\x06proto3"
@@ -267,25 +469,29 @@ func file_calculator_proto_rawDescGZIP() []byte {
 	return file_calculator_proto_rawDescData
 }
 
-var file_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_calculator_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_calculator_proto_goTypes = []any{
-	(*CalculationRequest)(nil),  // 0: calculator.CalculationRequest
-	(*CalculationResponse)(nil), // 1: calculator.CalculationResponse
-	(*StatusRequest)(nil),       // 2: calculator.StatusRequest
-	(*StatusResponse)(nil),      // 3: calculator.StatusResponse
-	nil,                         // 4: calculator.CalculationRequest.TimesEntry
+	(*CalculationRequest)(nil),    // 0: calculator.CalculationRequest
+	(*CalculationResponse)(nil),   // 1: calculator.CalculationResponse
+	(*StatusRequest)(nil),         // 2: calculator.StatusRequest
+	(*StatusResponse)(nil),        // 3: calculator.StatusResponse
+	(*RuntimeStats)(nil),          // 4: calculator.RuntimeStats
+	nil,                           // 5: calculator.CalculationRequest.TimesEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
 }
 var file_calculator_proto_depIdxs = []int32{
-	4, // 0: calculator.CalculationRequest.times:type_name -> calculator.CalculationRequest.TimesEntry
-	0, // 1: calculator.CalculatorService.PerformCalculation:input_type -> calculator.CalculationRequest
-	2, // 2: calculator.CalculatorService.CheckStatus:input_type -> calculator.StatusRequest
-	1, // 3: calculator.CalculatorService.PerformCalculation:output_type -> calculator.CalculationResponse
-	3, // 4: calculator.CalculatorService.CheckStatus:output_type -> calculator.StatusResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	5, // 0: calculator.CalculationRequest.times:type_name -> calculator.CalculationRequest.TimesEntry
+	6, // 1: calculator.CalculationRequest.deadline:type_name -> google.protobuf.Timestamp
+	4, // 2: calculator.StatusResponse.runtime:type_name -> calculator.RuntimeStats
+	0, // 3: calculator.CalculatorService.PerformCalculation:input_type -> calculator.CalculationRequest
+	2, // 4: calculator.CalculatorService.CheckStatus:input_type -> calculator.StatusRequest
+	1, // 5: calculator.CalculatorService.PerformCalculation:output_type -> calculator.CalculationResponse
+	3, // 6: calculator.CalculatorService.CheckStatus:output_type -> calculator.StatusResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_calculator_proto_init() }
@@ -299,7 +505,7 @@ func file_calculator_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_calculator_proto_rawDesc), len(file_calculator_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   6,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
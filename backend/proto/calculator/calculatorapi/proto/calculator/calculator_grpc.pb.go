@@ -26,10 +26,12 @@ const (
 // CalculatorServiceClient is the client API for CalculatorService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Сервис CalculatorService с двумя RPC методами
 type CalculatorServiceClient interface {
-	// RPC to perform the calculation
+	// Выполнить вычисление
 	PerformCalculation(ctx context.Context, in *CalculationRequest, opts ...grpc.CallOption) (*CalculationResponse, error)
-	// RPC to check server status
+	// Проверить статус сервера
 	CheckStatus(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
 }
 
@@ -64,10 +66,12 @@ func (c *calculatorServiceClient) CheckStatus(ctx context.Context, in *StatusReq
 // CalculatorServiceServer is the server API for CalculatorService service.
 // All implementations must embed UnimplementedCalculatorServiceServer
 // for forward compatibility.
+//
+// Сервис CalculatorService с двумя RPC методами
 type CalculatorServiceServer interface {
-	// RPC to perform the calculation
+	// Выполнить вычисление
 	PerformCalculation(context.Context, *CalculationRequest) (*CalculationResponse, error)
-	// RPC to check server status
+	// Проверить статус сервера
 	CheckStatus(context.Context, *StatusRequest) (*StatusResponse, error)
 	mustEmbedUnimplementedCalculatorServiceServer()
 }
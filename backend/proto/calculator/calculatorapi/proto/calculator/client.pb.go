@@ -0,0 +1,677 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v6.30.2
+// source: client.proto
+
+package calculator
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Запрос на вычисление. Незаданные длительности берутся из настроек пользователя
+type SubmitRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Operation          string                 `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"`                                                      // Выражение для вычисления
+	AddDuration        *int32                 `protobuf:"varint,2,opt,name=add_duration,json=addDuration,proto3,oneof" json:"add_duration,omitempty"`                        // Длительность сложения в секундах
+	SubtractDuration   *int32                 `protobuf:"varint,3,opt,name=subtract_duration,json=subtractDuration,proto3,oneof" json:"subtract_duration,omitempty"`         // Длительность вычитания в секундах
+	MultiplyDuration   *int32                 `protobuf:"varint,4,opt,name=multiply_duration,json=multiplyDuration,proto3,oneof" json:"multiply_duration,omitempty"`         // Длительность умножения в секундах
+	DivideDuration     *int32                 `protobuf:"varint,5,opt,name=divide_duration,json=divideDuration,proto3,oneof" json:"divide_duration,omitempty"`               // Длительность деления в секундах
+	InactiveServerTime *int32                 `protobuf:"varint,6,opt,name=inactive_server_time,json=inactiveServerTime,proto3,oneof" json:"inactive_server_time,omitempty"` // Время ожидания неактивного сервера в секундах
+	WebhookUrl         string                 `protobuf:"bytes,7,opt,name=webhook_url,json=webhookUrl,proto3" json:"webhook_url,omitempty"`                                  // Вебхук, уведомляемый о завершении (необязательно)
+	WebhookSecret      string                 `protobuf:"bytes,8,opt,name=webhook_secret,json=webhookSecret,proto3" json:"webhook_secret,omitempty"`                         // Ключ HMAC подписи уведомлений, обязателен вместе с webhook_url
+	Priority           *int32                 `protobuf:"varint,9,opt,name=priority,proto3,oneof" json:"priority,omitempty"`                                                 // Приоритет от 0 до 9, по умолчанию 5
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *SubmitRequest) Reset() {
+	*x = SubmitRequest{}
+	mi := &file_client_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitRequest) ProtoMessage() {}
+
+func (x *SubmitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitRequest.ProtoReflect.Descriptor instead.
+func (*SubmitRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubmitRequest) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *SubmitRequest) GetAddDuration() int32 {
+	if x != nil && x.AddDuration != nil {
+		return *x.AddDuration
+	}
+	return 0
+}
+
+func (x *SubmitRequest) GetSubtractDuration() int32 {
+	if x != nil && x.SubtractDuration != nil {
+		return *x.SubtractDuration
+	}
+	return 0
+}
+
+func (x *SubmitRequest) GetMultiplyDuration() int32 {
+	if x != nil && x.MultiplyDuration != nil {
+		return *x.MultiplyDuration
+	}
+	return 0
+}
+
+func (x *SubmitRequest) GetDivideDuration() int32 {
+	if x != nil && x.DivideDuration != nil {
+		return *x.DivideDuration
+	}
+	return 0
+}
+
+func (x *SubmitRequest) GetInactiveServerTime() int32 {
+	if x != nil && x.InactiveServerTime != nil {
+		return *x.InactiveServerTime
+	}
+	return 0
+}
+
+func (x *SubmitRequest) GetWebhookUrl() string {
+	if x != nil {
+		return x.WebhookUrl
+	}
+	return ""
+}
+
+func (x *SubmitRequest) GetWebhookSecret() string {
+	if x != nil {
+		return x.WebhookSecret
+	}
+	return ""
+}
+
+func (x *SubmitRequest) GetPriority() int32 {
+	if x != nil && x.Priority != nil {
+		return *x.Priority
+	}
+	return 0
+}
+
+// Вычисление
+type Calculation struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`                                                 // Идентификатор вычисления
+	UserId          int32                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`                           // Идентификатор юзера
+	Operation       string                 `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`                                    // Выражение
+	Result          *float64               `protobuf:"fixed64,4,opt,name=result,proto3,oneof" json:"result,omitempty"`                                  // Результат, если вычисление завершено
+	Status          string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`                                          // Статус: "created", "work", "completed", "cancelled" или "failed"
+	CreatedTime     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_time,json=createdTime,proto3" json:"created_time,omitempty"`             // Время создания вычисления
+	StartTime       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`                   // Время начала выполнения агентом
+	EndTime         *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`                         // Время завершения вычисления
+	OperationServer string                 `protobuf:"bytes,9,opt,name=operation_server,json=operationServer,proto3" json:"operation_server,omitempty"` // Адрес агента, на который отправлена последняя попытка
+	ServerStatus    string                 `protobuf:"bytes,10,opt,name=server_status,json=serverStatus,proto3" json:"server_status,omitempty"`         // Состояние попытки: "dispatched", "queued", "work", "completed" или "aborted"
+	AgentId         string                 `protobuf:"bytes,11,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`                        // Идентификатор агента, выполнявшего последнюю попытку
+	Attempts        int32                  `protobuf:"varint,12,opt,name=attempts,proto3" json:"attempts,omitempty"`                                    // Номер последней попытки
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Calculation) Reset() {
+	*x = Calculation{}
+	mi := &file_client_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Calculation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Calculation) ProtoMessage() {}
+
+func (x *Calculation) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Calculation.ProtoReflect.Descriptor instead.
+func (*Calculation) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Calculation) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Calculation) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Calculation) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *Calculation) GetResult() float64 {
+	if x != nil && x.Result != nil {
+		return *x.Result
+	}
+	return 0
+}
+
+func (x *Calculation) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Calculation) GetCreatedTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTime
+	}
+	return nil
+}
+
+func (x *Calculation) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *Calculation) GetEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *Calculation) GetOperationServer() string {
+	if x != nil {
+		return x.OperationServer
+	}
+	return ""
+}
+
+func (x *Calculation) GetServerStatus() string {
+	if x != nil {
+		return x.ServerStatus
+	}
+	return ""
+}
+
+func (x *Calculation) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *Calculation) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+// Запрос вычисления по идентификатору
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"` // Идентификатор вычисления
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_client_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// Запрос списка вычислений
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // Вернуть только вычисления с этим статусом (необязательно)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_client_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+// Список вычислений
+type ListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calculations  []*Calculation         `protobuf:"bytes,1,rep,name=calculations,proto3" json:"calculations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_client_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListResponse) GetCalculations() []*Calculation {
+	if x != nil {
+		return x.Calculations
+	}
+	return nil
+}
+
+// Запрос на отмену вычисления
+type CancelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"` // Идентификатор вычисления
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_client_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancelRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// Запрос на слежение за вычислениями
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"` // Идентификатор вычисления; 0 - все вычисления пользователя
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_client_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// Событие об изменении статуса вычисления
+type CalculationEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calculation   *Calculation           `protobuf:"bytes,1,opt,name=calculation,proto3" json:"calculation,omitempty"` // Вычисление в новом статусе
+	Time          *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`               // Время, когда изменение было обнаружено
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CalculationEvent) Reset() {
+	*x = CalculationEvent{}
+	mi := &file_client_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalculationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalculationEvent) ProtoMessage() {}
+
+func (x *CalculationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_client_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalculationEvent.ProtoReflect.Descriptor instead.
+func (*CalculationEvent) Descriptor() ([]byte, []int) {
+	return file_client_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CalculationEvent) GetCalculation() *Calculation {
+	if x != nil {
+		return x.Calculation
+	}
+	return nil
+}
+
+func (x *CalculationEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_client_proto protoreflect.FileDescriptor
+
+const file_client_proto_rawDesc = "" +
+	"\n" +
+	"\fclient.proto\x12\n" +
+	"calculator\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfe\x03\n" +
+	"\rSubmitRequest\x12\x1c\n" +
+	"\toperation\x18\x01 \x01(\tR\toperation\x12&\n" +
+	"\fadd_duration\x18\x02 \x01(\x05H\x00R\vaddDuration\x88\x01\x01\x120\n" +
+	"\x11subtract_duration\x18\x03 \x01(\x05H\x01R\x10subtractDuration\x88\x01\x01\x120\n" +
+	"\x11multiply_duration\x18\x04 \x01(\x05H\x02R\x10multiplyDuration\x88\x01\x01\x12,\n" +
+	"\x0fdivide_duration\x18\x05 \x01(\x05H\x03R\x0edivideDuration\x88\x01\x01\x125\n" +
+	"\x14inactive_server_time\x18\x06 \x01(\x05H\x04R\x12inactiveServerTime\x88\x01\x01\x12\x1f\n" +
+	"\vwebhook_url\x18\a \x01(\tR\n" +
+	"webhookUrl\x12%\n" +
+	"\x0ewebhook_secret\x18\b \x01(\tR\rwebhookSecret\x12\x1f\n" +
+	"\bpriority\x18\t \x01(\x05H\x05R\bpriority\x88\x01\x01B\x0f\n" +
+	"\r_add_durationB\x14\n" +
+	"\x12_subtract_durationB\x14\n" +
+	"\x12_multiply_durationB\x12\n" +
+	"\x10_divide_durationB\x17\n" +
+	"\x15_inactive_server_timeB\v\n" +
+	"\t_priority\"\xcc\x03\n" +
+	"\vCalculation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x05R\x06userId\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12\x1b\n" +
+	"\x06result\x18\x04 \x01(\x01H\x00R\x06result\x88\x01\x01\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12=\n" +
+	"\fcreated_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedTime\x129\n" +
+	"\n" +
+	"start_time\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
+	"\bend_time\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12)\n" +
+	"\x10operation_server\x18\t \x01(\tR\x0foperationServer\x12#\n" +
+	"\rserver_status\x18\n" +
+	" \x01(\tR\fserverStatus\x12\x19\n" +
+	"\bagent_id\x18\v \x01(\tR\aagentId\x12\x1a\n" +
+	"\battempts\x18\f \x01(\x05R\battemptsB\t\n" +
+	"\a_result\"\x1c\n" +
+	"\n" +
+	"GetRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"%\n" +
+	"\vListRequest\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"K\n" +
+	"\fListResponse\x12;\n" +
+	"\fcalculations\x18\x01 \x03(\v2\x17.calculator.CalculationR\fcalculations\"\x1f\n" +
+	"\rCancelRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\x1e\n" +
+	"\fWatchRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"}\n" +
+	"\x10CalculationEvent\x129\n" +
+	"\vcalculation\x18\x01 \x01(\v2\x17.calculator.CalculationR\vcalculation\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time2\xd5\x02\n" +
+	"\x17CalculatorClientService\x12>\n" +
+	"\x06Submit\x12\x19.calculator.SubmitRequest\x1a\x17.calculator.Calculation\"\x00\x128\n" +
+	"\x03Get\x12\x16.calculator.GetRequest\x1a\x17.calculator.Calculation\"\x00\x12;\n" +
+	"\x04List\x12\x17.calculator.ListRequest\x1a\x18.calculator.ListResponse\"\x00\x12>\n" +
+	"\x06Cancel\x12\x19.calculator.CancelRequest\x1a\x17.calculator.Calculation\"\x00\x12C\n" +
+	"\x05Watch\x12\x18.calculator.WatchRequest\x1a\x1c.calculator.CalculationEvent\"\x000\x01B Z\x1ecalculatorapi/proto/calculatorb\x06proto3"
+
+var (
+	file_client_proto_rawDescOnce sync.Once
+	file_client_proto_rawDescData []byte
+)
+
+func file_client_proto_rawDescGZIP() []byte {
+	file_client_proto_rawDescOnce.Do(func() {
+		file_client_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_client_proto_rawDesc), len(file_client_proto_rawDesc)))
+	})
+	return file_client_proto_rawDescData
+}
+
+var file_client_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_client_proto_goTypes = []any{
+	(*SubmitRequest)(nil),         // 0: calculator.SubmitRequest
+	(*Calculation)(nil),           // 1: calculator.Calculation
+	(*GetRequest)(nil),            // 2: calculator.GetRequest
+	(*ListRequest)(nil),           // 3: calculator.ListRequest
+	(*ListResponse)(nil),          // 4: calculator.ListResponse
+	(*CancelRequest)(nil),         // 5: calculator.CancelRequest
+	(*WatchRequest)(nil),          // 6: calculator.WatchRequest
+	(*CalculationEvent)(nil),      // 7: calculator.CalculationEvent
+	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+}
+var file_client_proto_depIdxs = []int32{
+	8,  // 0: calculator.Calculation.created_time:type_name -> google.protobuf.Timestamp
+	8,  // 1: calculator.Calculation.start_time:type_name -> google.protobuf.Timestamp
+	8,  // 2: calculator.Calculation.end_time:type_name -> google.protobuf.Timestamp
+	1,  // 3: calculator.ListResponse.calculations:type_name -> calculator.Calculation
+	1,  // 4: calculator.CalculationEvent.calculation:type_name -> calculator.Calculation
+	8,  // 5: calculator.CalculationEvent.time:type_name -> google.protobuf.Timestamp
+	0,  // 6: calculator.CalculatorClientService.Submit:input_type -> calculator.SubmitRequest
+	2,  // 7: calculator.CalculatorClientService.Get:input_type -> calculator.GetRequest
+	3,  // 8: calculator.CalculatorClientService.List:input_type -> calculator.ListRequest
+	5,  // 9: calculator.CalculatorClientService.Cancel:input_type -> calculator.CancelRequest
+	6,  // 10: calculator.CalculatorClientService.Watch:input_type -> calculator.WatchRequest
+	1,  // 11: calculator.CalculatorClientService.Submit:output_type -> calculator.Calculation
+	1,  // 12: calculator.CalculatorClientService.Get:output_type -> calculator.Calculation
+	4,  // 13: calculator.CalculatorClientService.List:output_type -> calculator.ListResponse
+	1,  // 14: calculator.CalculatorClientService.Cancel:output_type -> calculator.Calculation
+	7,  // 15: calculator.CalculatorClientService.Watch:output_type -> calculator.CalculationEvent
+	11, // [11:16] is the sub-list for method output_type
+	6,  // [6:11] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_client_proto_init() }
+func file_client_proto_init() {
+	if File_client_proto != nil {
+		return
+	}
+	file_client_proto_msgTypes[0].OneofWrappers = []any{}
+	file_client_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_client_proto_rawDesc), len(file_client_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_client_proto_goTypes,
+		DependencyIndexes: file_client_proto_depIdxs,
+		MessageInfos:      file_client_proto_msgTypes,
+	}.Build()
+	File_client_proto = out.File
+	file_client_proto_goTypes = nil
+	file_client_proto_depIdxs = nil
+}
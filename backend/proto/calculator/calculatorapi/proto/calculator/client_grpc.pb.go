@@ -0,0 +1,294 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.30.2
+// source: client.proto
+
+package calculator
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CalculatorClientService_Submit_FullMethodName = "/calculator.CalculatorClientService/Submit"
+	CalculatorClientService_Get_FullMethodName    = "/calculator.CalculatorClientService/Get"
+	CalculatorClientService_List_FullMethodName   = "/calculator.CalculatorClientService/List"
+	CalculatorClientService_Cancel_FullMethodName = "/calculator.CalculatorClientService/Cancel"
+	CalculatorClientService_Watch_FullMethodName  = "/calculator.CalculatorClientService/Watch"
+)
+
+// CalculatorClientServiceClient is the client API for CalculatorClientService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Публичный API оркестратора для клиентов.
+// Каждый вызов должен передавать JWT токен из /api/v1/login в метаданных "authorization: Bearer <token>".
+type CalculatorClientServiceClient interface {
+	// Отправить выражение на вычисление
+	Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*Calculation, error)
+	// Получить вычисление по идентификатору
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Calculation, error)
+	// Получить список вычислений пользователя
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Отменить вычисление, еще не отправленное на калькулятор
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*Calculation, error)
+	// Следить за изменениями статусов вычисления или всех вычислений пользователя
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CalculationEvent], error)
+}
+
+type calculatorClientServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCalculatorClientServiceClient(cc grpc.ClientConnInterface) CalculatorClientServiceClient {
+	return &calculatorClientServiceClient{cc}
+}
+
+func (c *calculatorClientServiceClient) Submit(ctx context.Context, in *SubmitRequest, opts ...grpc.CallOption) (*Calculation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Calculation)
+	err := c.cc.Invoke(ctx, CalculatorClientService_Submit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClientServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Calculation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Calculation)
+	err := c.cc.Invoke(ctx, CalculatorClientService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClientServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, CalculatorClientService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClientServiceClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*Calculation, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Calculation)
+	err := c.cc.Invoke(ctx, CalculatorClientService_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorClientServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CalculationEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorClientService_ServiceDesc.Streams[0], CalculatorClientService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, CalculationEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorClientService_WatchClient = grpc.ServerStreamingClient[CalculationEvent]
+
+// CalculatorClientServiceServer is the server API for CalculatorClientService service.
+// All implementations must embed UnimplementedCalculatorClientServiceServer
+// for forward compatibility.
+//
+// Публичный API оркестратора для клиентов.
+// Каждый вызов должен передавать JWT токен из /api/v1/login в метаданных "authorization: Bearer <token>".
+type CalculatorClientServiceServer interface {
+	// Отправить выражение на вычисление
+	Submit(context.Context, *SubmitRequest) (*Calculation, error)
+	// Получить вычисление по идентификатору
+	Get(context.Context, *GetRequest) (*Calculation, error)
+	// Получить список вычислений пользователя
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Отменить вычисление, еще не отправленное на калькулятор
+	Cancel(context.Context, *CancelRequest) (*Calculation, error)
+	// Следить за изменениями статусов вычисления или всех вычислений пользователя
+	Watch(*WatchRequest, grpc.ServerStreamingServer[CalculationEvent]) error
+	mustEmbedUnimplementedCalculatorClientServiceServer()
+}
+
+// UnimplementedCalculatorClientServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCalculatorClientServiceServer struct{}
+
+func (UnimplementedCalculatorClientServiceServer) Submit(context.Context, *SubmitRequest) (*Calculation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Submit not implemented")
+}
+func (UnimplementedCalculatorClientServiceServer) Get(context.Context, *GetRequest) (*Calculation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCalculatorClientServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedCalculatorClientServiceServer) Cancel(context.Context, *CancelRequest) (*Calculation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedCalculatorClientServiceServer) Watch(*WatchRequest, grpc.ServerStreamingServer[CalculationEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedCalculatorClientServiceServer) mustEmbedUnimplementedCalculatorClientServiceServer() {
+}
+func (UnimplementedCalculatorClientServiceServer) testEmbeddedByValue() {}
+
+// UnsafeCalculatorClientServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CalculatorClientServiceServer will
+// result in compilation errors.
+type UnsafeCalculatorClientServiceServer interface {
+	mustEmbedUnimplementedCalculatorClientServiceServer()
+}
+
+func RegisterCalculatorClientServiceServer(s grpc.ServiceRegistrar, srv CalculatorClientServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCalculatorClientServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CalculatorClientService_ServiceDesc, srv)
+}
+
+func _CalculatorClientService_Submit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorClientServiceServer).Submit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorClientService_Submit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorClientServiceServer).Submit(ctx, req.(*SubmitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorClientService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorClientServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorClientService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorClientServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorClientService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorClientServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorClientService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorClientServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorClientService_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorClientServiceServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalculatorClientService_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorClientServiceServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorClientService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CalculatorClientServiceServer).Watch(m, &grpc.GenericServerStream[WatchRequest, CalculationEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CalculatorClientService_WatchServer = grpc.ServerStreamingServer[CalculationEvent]
+
+// CalculatorClientService_ServiceDesc is the grpc.ServiceDesc for CalculatorClientService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CalculatorClientService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.CalculatorClientService",
+	HandlerType: (*CalculatorClientServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Submit",
+			Handler:    _CalculatorClientService_Submit_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _CalculatorClientService_Get_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _CalculatorClientService_List_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _CalculatorClientService_Cancel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _CalculatorClientService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "client.proto",
+}
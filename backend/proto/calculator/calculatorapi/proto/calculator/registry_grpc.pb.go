@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.30.2
+// source: registry.proto
+
+package calculator
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AgentRegistryService_Register_FullMethodName     = "/calculator.AgentRegistryService/Register"
+	AgentRegistryService_Heartbeat_FullMethodName    = "/calculator.AgentRegistryService/Heartbeat"
+	AgentRegistryService_Deregister_FullMethodName   = "/calculator.AgentRegistryService/Deregister"
+	AgentRegistryService_ReportResult_FullMethodName = "/calculator.AgentRegistryService/ReportResult"
+	AgentRegistryService_GetTask_FullMethodName      = "/calculator.AgentRegistryService/GetTask"
+)
+
+// AgentRegistryServiceClient is the client API for AgentRegistryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Реестр агентов оркестратора. Агент регистрируется при запуске, затем периодически
+// отправляет сигналы активности; агент, пропустивший несколько сигналов, удаляется из реестра.
+// Вычисления агент либо получает от оркестратора по своему адресу (push), либо запрашивает сам через GetTask (pull).
+type AgentRegistryServiceClient interface {
+	// Зарегистрировать агента или обновить его сведения
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
+	// Сообщить, что агент активен. Возвращает NOT_FOUND, если агента нет в реестре: агенту нужно зарегистрироваться заново
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
+	// Удалить агента из реестра при остановке
+	Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error)
+	// Сообщить о ходе выполнения или результате вычисления. Возвращает FAILED_PRECONDITION, если попытка
+	// уже не назначена агенту, например вычисление отменено или переназначено; повторять такой отчет не нужно
+	ReportResult(ctx context.Context, in *ReportResultRequest, opts ...grpc.CallOption) (*ReportResultResponse, error)
+	// Получить задачи для свободных исполнителей агента, работающего в режиме pull. Каждая задача - аренда
+	// попытки вычисления до ее срока deadline, результат сообщается через ReportResult. Если задач нет,
+	// ответ ожидает их до wait_seconds и возвращается пустым
+	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*GetTaskResponse, error)
+}
+
+type agentRegistryServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAgentRegistryServiceClient(cc grpc.ClientConnInterface) AgentRegistryServiceClient {
+	return &agentRegistryServiceClient{cc}
+}
+
+func (c *agentRegistryServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterResponse)
+	err := c.cc.Invoke(ctx, AgentRegistryService_Register_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, AgentRegistryService_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryServiceClient) Deregister(ctx context.Context, in *DeregisterRequest, opts ...grpc.CallOption) (*DeregisterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeregisterResponse)
+	err := c.cc.Invoke(ctx, AgentRegistryService_Deregister_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryServiceClient) ReportResult(ctx context.Context, in *ReportResultRequest, opts ...grpc.CallOption) (*ReportResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportResultResponse)
+	err := c.cc.Invoke(ctx, AgentRegistryService_ReportResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentRegistryServiceClient) GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*GetTaskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTaskResponse)
+	err := c.cc.Invoke(ctx, AgentRegistryService_GetTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AgentRegistryServiceServer is the server API for AgentRegistryService service.
+// All implementations must embed UnimplementedAgentRegistryServiceServer
+// for forward compatibility.
+//
+// Реестр агентов оркестратора. Агент регистрируется при запуске, затем периодически
+// отправляет сигналы активности; агент, пропустивший несколько сигналов, удаляется из реестра.
+// Вычисления агент либо получает от оркестратора по своему адресу (push), либо запрашивает сам через GetTask (pull).
+type AgentRegistryServiceServer interface {
+	// Зарегистрировать агента или обновить его сведения
+	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	// Сообщить, что агент активен. Возвращает NOT_FOUND, если агента нет в реестре: агенту нужно зарегистрироваться заново
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
+	// Удалить агента из реестра при остановке
+	Deregister(context.Context, *DeregisterRequest) (*DeregisterResponse, error)
+	// Сообщить о ходе выполнения или результате вычисления. Возвращает FAILED_PRECONDITION, если попытка
+	// уже не назначена агенту, например вычисление отменено или переназначено; повторять такой отчет не нужно
+	ReportResult(context.Context, *ReportResultRequest) (*ReportResultResponse, error)
+	// Получить задачи для свободных исполнителей агента, работающего в режиме pull. Каждая задача - аренда
+	// попытки вычисления до ее срока deadline, результат сообщается через ReportResult. Если задач нет,
+	// ответ ожидает их до wait_seconds и возвращается пустым
+	GetTask(context.Context, *GetTaskRequest) (*GetTaskResponse, error)
+	mustEmbedUnimplementedAgentRegistryServiceServer()
+}
+
+// UnimplementedAgentRegistryServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAgentRegistryServiceServer struct{}
+
+func (UnimplementedAgentRegistryServiceServer) Register(context.Context, *RegisterRequest) (*RegisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedAgentRegistryServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
+func (UnimplementedAgentRegistryServiceServer) Deregister(context.Context, *DeregisterRequest) (*DeregisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deregister not implemented")
+}
+func (UnimplementedAgentRegistryServiceServer) ReportResult(context.Context, *ReportResultRequest) (*ReportResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportResult not implemented")
+}
+func (UnimplementedAgentRegistryServiceServer) GetTask(context.Context, *GetTaskRequest) (*GetTaskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedAgentRegistryServiceServer) mustEmbedUnimplementedAgentRegistryServiceServer() {}
+func (UnimplementedAgentRegistryServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeAgentRegistryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AgentRegistryServiceServer will
+// result in compilation errors.
+type UnsafeAgentRegistryServiceServer interface {
+	mustEmbedUnimplementedAgentRegistryServiceServer()
+}
+
+func RegisterAgentRegistryServiceServer(s grpc.ServiceRegistrar, srv AgentRegistryServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAgentRegistryServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AgentRegistryService_ServiceDesc, srv)
+}
+
+func _AgentRegistryService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistryService_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistryService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistryService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistryService_Deregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServiceServer).Deregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistryService_Deregister_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServiceServer).Deregister(ctx, req.(*DeregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistryService_ReportResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServiceServer).ReportResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistryService_ReportResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServiceServer).ReportResult(ctx, req.(*ReportResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentRegistryService_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentRegistryServiceServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AgentRegistryService_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentRegistryServiceServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AgentRegistryService_ServiceDesc is the grpc.ServiceDesc for AgentRegistryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AgentRegistryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "calculator.AgentRegistryService",
+	HandlerType: (*AgentRegistryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _AgentRegistryService_Register_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _AgentRegistryService_Heartbeat_Handler,
+		},
+		{
+			MethodName: "Deregister",
+			Handler:    _AgentRegistryService_Deregister_Handler,
+		},
+		{
+			MethodName: "ReportResult",
+			Handler:    _AgentRegistryService_ReportResult_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _AgentRegistryService_GetTask_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "registry.proto",
+}
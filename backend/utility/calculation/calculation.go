@@ -1,6 +1,7 @@
 package calculation
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,6 +11,13 @@ import (
 type OperationTimes map[string]time.Duration
 
 func EvaluateOperation(operation string, operationTimes OperationTimes) ([]string, float64) {
+	operations, result, _ := EvaluateOperationContext(context.Background(), operation, operationTimes)
+	return operations, result
+}
+
+// EvaluateOperationContext вычисляет выражение так же, как EvaluateOperation, но прерывает вычисление
+// с ошибкой контекста, если ctx отменен или его крайний срок истек.
+func EvaluateOperationContext(ctx context.Context, operation string, operationTimes OperationTimes) ([]string, float64, error) {
 	var operations []string
 	operands, operators := parseOperation(operation)
 
@@ -25,7 +33,10 @@ func EvaluateOperation(operation string, operationTimes OperationTimes) ([]strin
 		if expression[i] == "*" || expression[i] == "/" {
 			left, _ := strconv.ParseFloat(expression[i-1], 64)
 			right, _ := strconv.ParseFloat(expression[i+1], 64)
-			result := performOperation(left, right, expression[i], operationTimes)
+			result, err := performOperation(ctx, left, right, expression[i], operationTimes)
+			if err != nil {
+				return operations, 0, err
+			}
 			operations = append(operations, fmt.Sprintf("%s %s %s = %.6f", expression[i-1], expression[i], expression[i+1], result))
 
 			expression[i+1] = fmt.Sprintf("%.6f", result)
@@ -40,17 +51,33 @@ func EvaluateOperation(operation string, operationTimes OperationTimes) ([]strin
 	}
 	for i := 1; i < len(expression); i += 2 {
 		right, _ := strconv.ParseFloat(expression[i+1], 64)
-		result = performOperation(result, right, expression[i], operationTimes)
+		var err error
+		if result, err = performOperation(ctx, result, right, expression[i], operationTimes); err != nil {
+			return operations, 0, err
+		}
 		operations = append(operations, fmt.Sprintf("%.6f %s %s = %.6f", result, expression[i], expression[i+1], result))
 	}
 
-	return operations, result
+	return operations, result, nil
+}
+
+// EstimateDuration возвращает ожидаемую длительность вычисления operation - сумму задержек всех его операций.
+func EstimateDuration(operation string, operationTimes OperationTimes) time.Duration {
+	_, operators := parseOperation(operation)
+	var total time.Duration
+	for _, operator := range operators {
+		total += operationTimes[operator]
+	}
+	return total
 }
 
 func parseOperation(operation string) ([]string, []string) {
 	operands := strings.FieldsFunc(operation, func(c rune) bool {
 		return c == '+' || c == '-' || c == '*' || c == '/'
 	})
+	for i, operand := range operands {
+		operands[i] = strings.TrimSpace(operand)
+	}
 
 	operators := make([]string, 0)
 	for _, c := range operation {
@@ -62,29 +89,38 @@ func parseOperation(operation string) ([]string, []string) {
 	return operands, operators
 }
 
-func performOperation(left, right float64, operator string, operationTimes OperationTimes) float64 {
+func performOperation(ctx context.Context, left, right float64, operator string, operationTimes OperationTimes) (float64, error) {
 	if duration, ok := operationTimes[operator]; ok {
 		fmt.Printf("Performing %s operation, waiting for %v\n", operator, duration)
-		time.Sleep(duration)
+		timer := time.NewTimer(duration)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		}
 	} else {
 		fmt.Println("Unknown operation, no delay applied")
 	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 
 	switch operator {
 	case "+":
-		return left + right
+		return left + right, nil
 	case "-":
-		return left - right
+		return left - right, nil
 	case "*":
-		return left * right
+		return left * right, nil
 	case "/":
 		if right == 0 {
 			fmt.Println("Error: Division by zero")
-			return 0
+			return 0, nil
 		}
-		return left / right
+		return left / right, nil
 	default:
 		fmt.Println("Unknown operator", operator)
-		return 0
+		return 0, nil
 	}
 }
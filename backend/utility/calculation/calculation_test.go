@@ -1,8 +1,11 @@
 package calculation
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseOperation(t *testing.T) {
@@ -46,6 +49,35 @@ func TestParseOperation(t *testing.T) {
 	}
 }
 
+func TestEvaluateOperationContextDeadline(t *testing.T) {
+	times := OperationTimes{"+": time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := EvaluateOperationContext(ctx, "2+2", times); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Evaluation was not aborted at the deadline, took %v", elapsed)
+	}
+
+	_, result, err := EvaluateOperationContext(context.Background(), "2+2*3", OperationTimes{})
+	if err != nil || result != 8 {
+		t.Errorf("EvaluateOperationContext() = %v, %v; want 8, nil", result, err)
+	}
+}
+
+func TestEstimateDuration(t *testing.T) {
+	times := OperationTimes{"+": time.Second, "*": 3 * time.Second}
+	if got := EstimateDuration("2+2*3+1", times); got != 5*time.Second {
+		t.Errorf("EstimateDuration() = %v, want %v", got, 5*time.Second)
+	}
+	if got := EstimateDuration("8/2", times); got != 0 {
+		t.Errorf("EstimateDuration() without a delay for the operator = %v, want 0", got)
+	}
+}
+
 // Helper function to compare slices
 func equalSlices(a, b []string) bool {
 	if len(a) != len(b) {
@@ -0,0 +1,77 @@
+package calculation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Лексемы, которые ожидает разбор выражения
+const (
+	ExpectedNumber   = "number"
+	ExpectedOperator = "operator"
+)
+
+// SyntaxError описывает ошибку разбора выражения.
+type SyntaxError struct {
+	Position int    `json:"position"` // Позиция ошибки в символах от начала выражения, начиная с 0
+	Expected string `json:"expected"` // Ожидаемая лексема: number или operator
+	Found    string `json:"found"`    // Найденная лексема, пустая в конце выражения
+	Message  string `json:"message"`
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Position, e.Message)
+}
+
+func newSyntaxError(position int, expected, found string) *SyntaxError {
+	message := fmt.Sprintf("expected %s, found %q", expected, found)
+	if found == "" {
+		message = fmt.Sprintf("expected %s, found end of expression", expected)
+	}
+	return &SyntaxError{Position: position, Expected: expected, Found: found, Message: message}
+}
+
+// Validate проверяет, что выражение может быть вычислено EvaluateOperation: числа, разделенные
+// операторами + - * /, с пробелами между ними. Скобки и унарный минус не поддерживаются.
+// Возвращает *SyntaxError с позицией первой ошибки.
+func Validate(operation string) error {
+	runes := []rune(operation)
+	expectNumber := true
+	pos := 0
+
+	for {
+		for pos < len(runes) && unicode.IsSpace(runes[pos]) {
+			pos++
+		}
+		if pos == len(runes) {
+			break
+		}
+
+		if expectNumber {
+			start := pos
+			for pos < len(runes) && (unicode.IsDigit(runes[pos]) || runes[pos] == '.') {
+				pos++
+			}
+			if pos == start {
+				return newSyntaxError(start, ExpectedNumber, string(runes[start]))
+			}
+			number := string(runes[start:pos])
+			if _, err := strconv.ParseFloat(number, 64); err != nil {
+				return &SyntaxError{Position: start, Expected: ExpectedNumber, Found: number, Message: fmt.Sprintf("invalid number %q", number)}
+			}
+		} else {
+			if !strings.ContainsRune("+-*/", runes[pos]) {
+				return newSyntaxError(pos, ExpectedOperator, string(runes[pos]))
+			}
+			pos++
+		}
+		expectNumber = !expectNumber
+	}
+
+	if expectNumber {
+		return newSyntaxError(pos, ExpectedNumber, "")
+	}
+	return nil
+}
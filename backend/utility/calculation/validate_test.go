@@ -0,0 +1,51 @@
+package calculation
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		wantErr   *SyntaxError
+	}{
+		{name: "Simple", operation: "2+2"},
+		{name: "Spaces And Decimals", operation: " 12.5 /  4 - .5 "},
+		{name: "Double Operator", operation: "2++", wantErr: &SyntaxError{Position: 2, Expected: ExpectedNumber, Found: "+"}},
+		{name: "Trailing Operator", operation: "2 *", wantErr: &SyntaxError{Position: 3, Expected: ExpectedNumber, Found: ""}},
+		{name: "Empty", operation: "   ", wantErr: &SyntaxError{Position: 3, Expected: ExpectedNumber, Found: ""}},
+		{name: "Missing Operator", operation: "12 4", wantErr: &SyntaxError{Position: 3, Expected: ExpectedOperator, Found: "4"}},
+		{name: "Parentheses", operation: "(1+2)", wantErr: &SyntaxError{Position: 0, Expected: ExpectedNumber, Found: "("}},
+		{name: "Unary Minus", operation: "-3+1", wantErr: &SyntaxError{Position: 0, Expected: ExpectedNumber, Found: "-"}},
+		{name: "Invalid Number", operation: "1+1.2.3", wantErr: &SyntaxError{Position: 2, Expected: ExpectedNumber, Found: "1.2.3"}},
+		{name: "Unicode Position", operation: "1 × 2", wantErr: &SyntaxError{Position: 2, Expected: ExpectedOperator, Found: "×"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.operation)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate(%q) = %v, want nil", tt.operation, err)
+				}
+				return
+			}
+
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("Validate(%q) = %v, want *SyntaxError", tt.operation, err)
+			}
+			if syntaxErr.Position != tt.wantErr.Position || syntaxErr.Expected != tt.wantErr.Expected || syntaxErr.Found != tt.wantErr.Found {
+				t.Errorf("Validate(%q) = %+v, want %+v", tt.operation, syntaxErr, tt.wantErr)
+			}
+			if syntaxErr.Message == "" {
+				t.Error("Expected a message")
+			}
+		})
+	}
+}
+
+func TestEvaluateOperationWithSpaces(t *testing.T) {
+	if _, result := EvaluateOperation("12 / 4 - 1", OperationTimes{}); result != 2 {
+		t.Errorf("EvaluateOperation() = %v, want 2", result)
+	}
+}
@@ -0,0 +1,172 @@
+// Package cron разбирает расписания в формате cron и вычисляет время следующего запуска.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Макросы, заменяющие стандартные выражения
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Допустимые значения полей выражения
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var fields = []bounds{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 и 7 - воскресенье
+}
+
+// Schedule - разобранное выражение cron. Каждое поле хранит набор допустимых значений битами.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // Поле задано как "*": при ограничении обоих полей дня достаточно совпадения любого
+}
+
+// Максимальный горизонт поиска следующего запуска: выражение вроде "0 0 30 2 *" не срабатывает никогда
+const searchYears = 5
+
+// Parse разбирает выражение из пяти полей "минута час день месяц день_недели" или макрос вида @hourly.
+// Поля поддерживают "*", списки через запятую, диапазоны "a-b" и шаг "/n".
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(fields), len(parts))
+	}
+
+	values := make([]uint64, len(fields))
+	for i, part := range parts {
+		bits, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = bits
+	}
+
+	// Воскресенье может быть задано как 7
+	if values[4]&(1<<7) != 0 {
+		values[4] |= 1
+	}
+
+	s := &Schedule{
+		minute: values[0],
+		hour:   values[1],
+		dom:    values[2],
+		month:  values[3],
+		dow:    values[4],
+		domAny: parts[2] == "*" || parts[2] == "?",
+		dowAny: parts[4] == "*" || parts[4] == "?",
+	}
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("expression %q never matches", expr)
+	}
+	return s, nil
+}
+
+// parseField разбирает одно поле выражения в набор битов.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			rangePart = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", b.name, item)
+			}
+		}
+
+		low, high := b.min, b.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(ends[0])
+			high, err2 = strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil || low > high {
+				return 0, fmt.Errorf("invalid range in %s field %q", b.name, item)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", b.name, item)
+			}
+			low = value
+			// "5/15" означает "с 5 до конца диапазона с шагом 15"
+			if strings.Contains(item, "/") {
+				high = b.max
+			} else {
+				high = value
+			}
+		}
+
+		if low < b.min || high > b.max {
+			return 0, fmt.Errorf("%s field %q is out of range %d-%d", b.name, item, b.min, b.max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next возвращает первое время срабатывания строго после after в часовом поясе after.
+// Возвращает нулевое время, если такого времени нет в ближайшие годы.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), after.Minute()+1, 0, 0, after.Location())
+	limit := t.AddDate(searchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches проверяет день месяца и день недели по правилам cron:
+// если оба поля ограничены, достаточно совпадения любого из них.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
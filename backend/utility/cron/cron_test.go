@@ -0,0 +1,56 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "0 0 30 2 *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected an error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 30, 20, 0, time.UTC) // Пятница
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"5/20 9-17 * * *", time.Date(2024, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, time.March, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,20 * *", time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// День месяца или день недели: 1 число или понедельник
+		{"0 0 1 * 1", time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) unexpected error: %v", tt.expr, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+5:30", 5*3600+1800)
+	schedule, err := Parse("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, time.March, 15, 10, 10, 0, 0, loc)
+	if got, want := schedule.Next(base), time.Date(2024, time.March, 15, 11, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Next() = %v, want %v", got, want)
+	}
+}
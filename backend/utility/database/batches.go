@@ -0,0 +1,142 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// CreateBatchTableIfNotExists создает таблицу пакетов вычислений.
+func CreateBatchTableIfNotExists(db *sql.DB) error {
+	return createTableIfNotExists(db, "batches", `
+		CREATE TABLE batches (
+			id SERIAL PRIMARY KEY,
+			userId INTEGER NOT NULL,
+			total INTEGER NOT NULL,
+			created_time TIMESTAMP NOT NULL
+		)
+	`)
+}
+
+// InsertBatch в одной транзакции создает пакет и все его вычисления.
+// Возвращает пакет и идентификаторы вычислений в порядке запросов или ErrQueueFull,
+// если вычисления пакета не помещаются в очередь пользователя из maxQueued вычислений (0 отключает ограничение).
+func InsertBatch(db *sql.DB, userId int, requests []models.CalculationRequest, maxQueued int) (*models.Batch, []int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := reserveQueue(tx, userId, len(requests), maxQueued); err != nil {
+		return nil, nil, err
+	}
+
+	batch := &models.Batch{UserId: userId, Total: len(requests), CreatedTime: time.Now().UTC()}
+	err = tx.QueryRow(`INSERT INTO batches (userId, total, created_time) VALUES ($1, $2, $3) RETURNING id`,
+		userId, batch.Total, batch.CreatedTime).Scan(&batch.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inserting batch: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+        INSERT INTO calculations (userId, operation, status, created_time, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, batch_id, priority)
+        VALUES ($1, $2, 'created', $3, $4, $5, $6, $7, $8, $9, $10)
+        RETURNING id
+    `)
+	if err != nil {
+		return nil, nil, fmt.Errorf("preparing batch insert: %w", err)
+	}
+	defer stmt.Close()
+
+	ids := make([]int, 0, len(requests))
+	for _, req := range requests {
+		var id int
+		err := stmt.QueryRow(userId, req.Operation, batch.CreatedTime, req.AddDuration, req.SubtractDuration, req.MultiplyDuration, req.DivideDuration, req.InactiveServerTime, batch.ID, req.Priority).Scan(&id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("inserting batch calculation: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("committing batch: %w", err)
+	}
+
+	fmt.Printf("Batch %d with %d calculations inserted successfully.\n", batch.ID, len(ids))
+	return batch, ids, nil
+}
+
+// GetBatch извлекает пакет пользователя по ID. Возвращает sql.ErrNoRows, если пакет не найден.
+func GetBatch(db *sql.DB, id, userId int) (*models.Batch, error) {
+	batch := &models.Batch{}
+	query := `SELECT id, userId, total, created_time FROM batches WHERE id = $1 AND userId = $2`
+	if err := db.QueryRow(query, id, userId).Scan(&batch.ID, &batch.UserId, &batch.Total, &batch.CreatedTime); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// FetchBatchCalculations извлекает все вычисления пакета в порядке их создания.
+// Вычисления в корзине также возвращаются, чтобы прогресс пакета не менялся при их удалении.
+func FetchBatchCalculations(db *sql.DB, batchID int) ([]models.OperationResponse, error) {
+	var calculations []models.OperationResponse
+
+	query := `SELECT id, userId, operation, result, status, ` + executionColumns + ` FROM calculations WHERE batch_id = $1 ORDER BY id`
+	rows, err := db.Query(query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("querying calculations for batch %d: %w", batchID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var calc models.OperationResponse
+		var result sql.NullFloat64 // Для обработки NULL значений.
+		var execution executionRow
+
+		dest := append([]interface{}{&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status}, execution.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scanning calculation: %w", err)
+		}
+		calc.CalculationExecution = execution.execution()
+		if result.Valid {
+			calc.Result = result.Float64
+		}
+		calculations = append(calculations, calc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over calculations results: %w", err)
+	}
+	return calculations, nil
+}
+
+// CancelBatch переводит еще не отправленные вычисления пакета в статус 'cancelled'.
+// Возвращает идентификаторы отмененных вычислений.
+func CancelBatch(db *sql.DB, batchID int) ([]int, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'cancelled', end_time = $1
+		WHERE batch_id = $2 AND status = 'created'
+		RETURNING id
+	`
+	rows, err := db.Query(query, time.Now().UTC(), batchID)
+	if err != nil {
+		return nil, fmt.Errorf("cancelling batch %d: %w", batchID, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning cancelled calculation: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over cancelled calculations: %w", err)
+	}
+	return ids, nil
+}
@@ -9,7 +9,7 @@ import (
 	"sync"                         // Синхронизация горутин
 	"time"                         // Работа со временем
 
-	_ "github.com/lib/pq"        // Драйвер PostgreSQL
+	"github.com/lib/pq"          // Драйвер PostgreSQL
 	"golang.org/x/crypto/bcrypt" // Драйвер для хэширования паролей
 )
 
@@ -26,6 +26,30 @@ var (
 	dbMu sync.Mutex
 )
 
+// connString возвращает строку подключения к базе данных.
+func connString() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+}
+
+// DispatchChannel - канал LISTEN/NOTIFY, в который триггер таблицы calculations сообщает
+// идентификаторы вычислений, готовых к отправке агентам.
+const DispatchChannel = "calculations_created"
+
+// ListenDispatch подписывается на уведомления канала DispatchChannel отдельным соединением.
+// При обрыве соединение восстанавливается автоматически, после переподключения в канал Notify приходит nil.
+func ListenDispatch() (*pq.Listener, error) {
+	listener := pq.NewListener(connString(), time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Dispatch listener: %v", err)
+		}
+	})
+	if err := listener.Listen(DispatchChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listening on channel %s: %w", DispatchChannel, err)
+	}
+	return listener, nil
+}
+
 func InitializeDB() {
 	dbMu.Lock()
 	defer dbMu.Unlock()
@@ -34,9 +58,8 @@ func InitializeDB() {
 		return
 	}
 
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
 	var err error
-	db, err = sql.Open("postgres", psqlInfo)
+	db, err = sql.Open("postgres", connString())
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
 	}
@@ -78,8 +101,7 @@ func GetDB() *sql.DB {
 }
 
 func ConnectToDatabase() (*sql.DB, error) {
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := sql.Open("postgres", psqlInfo)
+	db, err := sql.Open("postgres", connString())
 	if err != nil {
 		return nil, err // Возвращение ошибки при неудаче
 	}
@@ -88,8 +110,7 @@ func ConnectToDatabase() (*sql.DB, error) {
 
 func SetupDatabase() (*sql.DB, error) {
 
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
-	db, err := sql.Open("postgres", psqlInfo)
+	db, err := sql.Open("postgres", connString())
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +140,42 @@ func SetupDatabase() (*sql.DB, error) {
 		return nil, err
 	}
 
+	err = MigrateCalculationsTable(db)
+	if err != nil {
+		log.Fatalf("Failed to migrate Calculations table: %v", err)
+		return nil, err
+	}
+
+	err = CreateWebhookTablesIfNotExists(db)
+	if err != nil {
+		log.Fatalf("Failed to create Webhook tables: %v", err)
+		return nil, err
+	}
+
+	err = CreateBatchTableIfNotExists(db)
+	if err != nil {
+		log.Fatalf("Failed to create Batch tables: %v", err)
+		return nil, err
+	}
+
+	err = CreateIdempotencyTableIfNotExists(db)
+	if err != nil {
+		log.Fatalf("Failed to create Idempotency tables: %v", err)
+		return nil, err
+	}
+
+	err = CreateSettingsTableIfNotExists(db)
+	if err != nil {
+		log.Fatalf("Failed to create Settings tables: %v", err)
+		return nil, err
+	}
+
+	err = CreateScheduleTableIfNotExists(db)
+	if err != nil {
+		log.Fatalf("Failed to create Schedule tables: %v", err)
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -173,7 +230,101 @@ func CreateTableIfNotExists(db *sql.DB) error {
 	return nil
 }
 
+// calculationMigrations содержит изменения таблицы calculations, добавленные после её первоначального создания.
+// Все запросы идемпотентны и выполняются при каждом запуске.
+var calculationMigrations = []string{
+	// Вебхук, указанный при отправке вычисления
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS webhook_url TEXT`,
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS webhook_secret TEXT`,
+	// Признак того, что уведомления о завершении уже поставлены в очередь.
+	// Существующие записи считаются уведомленными, новые - нет.
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS notified BOOLEAN NOT NULL DEFAULT TRUE`,
+	`ALTER TABLE calculations ALTER COLUMN notified SET DEFAULT FALSE`,
+	// Пакет, в составе которого было отправлено вычисление
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS batch_id INTEGER`,
+	`CREATE INDEX IF NOT EXISTS calculations_batch_id_idx ON calculations (batch_id)`,
+	// Приоритет вычисления в очереди
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 5`,
+	`CREATE INDEX IF NOT EXISTS calculations_queue_idx ON calculations (priority DESC, id) WHERE status = 'created'`,
+	// Расписание, запуском которого создано вычисление
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS schedule_id INTEGER`,
+	`CREATE INDEX IF NOT EXISTS calculations_schedule_id_idx ON calculations (schedule_id)`,
+	// Время удаления вычисления в корзину, NULL - вычисление не удалено
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS deleted_time TIMESTAMP`,
+	`CREATE INDEX IF NOT EXISTS calculations_deleted_time_idx ON calculations (deleted_time) WHERE deleted_time IS NOT NULL`,
+	// Количество попыток выполнения, время, раньше которого вычисление не отправляется повторно, и причина последней неудачи
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS next_attempt_time TIMESTAMP`,
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS failure_reason TEXT`,
+	// Количество попыток, сделанных до последнего возврата в очередь администратором: номера попыток не повторяются,
+	// а политика повторов учитывает только попытки после attempts_base
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS attempts_base INTEGER NOT NULL DEFAULT 0`,
+	// Идентификатор, которым представился агент, выполнявший последнюю попытку
+	`ALTER TABLE calculations ADD COLUMN IF NOT EXISTS agent_id TEXT`,
+	// Уведомление оркестраторов о вычислениях, появившихся в очереди: новых, возвращенных в очередь и восстановленных из корзины
+	`CREATE OR REPLACE FUNCTION notify_calculation_created() RETURNS trigger AS $$
+	BEGIN
+		PERFORM pg_notify('` + DispatchChannel + `', NEW.id::text);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS calculations_created_notify ON calculations`,
+	`CREATE TRIGGER calculations_created_notify AFTER INSERT OR UPDATE OF status, deleted_time ON calculations
+		FOR EACH ROW WHEN (NEW.status = 'created' AND NEW.deleted_time IS NULL)
+		EXECUTE FUNCTION notify_calculation_created()`,
+}
+
+// MigrateCalculationsTable добавляет в таблицу calculations недостающие столбцы.
+func MigrateCalculationsTable(db *sql.DB) error {
+	for _, query := range calculationMigrations {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("migrating calculations table: %w", err)
+		}
+	}
+	return nil
+}
+
+// createTableIfNotExists создает таблицу name запросом query, если её еще нет в базе данных.
+func createTableIfNotExists(db *sql.DB, name, query string) error {
+	var tableExists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = $1)", name).Scan(&tableExists)
+	if err != nil {
+		return err
+	}
+
+	if tableExists {
+		fmt.Printf("Table '%s' already exists.\n", name)
+		return nil
+	}
+
+	if _, err = db.Exec(query); err != nil {
+		return err
+	}
+	fmt.Printf("Table '%s' created successfully.\n", name)
+	return nil
+}
+
+// nullString превращает пустую строку в NULL при записи в базу данных.
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
 func InsertCalculation(db *sql.DB, userId int, operation string, addDuration, subtractDuration, multiplyDuration, divideDuration, inactiveServerTime int) (int, error) {
+	return InsertCalculationRequest(db, models.CalculationRequest{
+		UserId:             userId,
+		Operation:          operation,
+		AddDuration:        addDuration,
+		SubtractDuration:   subtractDuration,
+		MultiplyDuration:   multiplyDuration,
+		DivideDuration:     divideDuration,
+		InactiveServerTime: inactiveServerTime,
+		Priority:           models.DefaultPriority,
+	}, 0)
+}
+
+// InsertCalculationRequest добавляет запись о вычислении вместе с дополнительными параметрами запроса.
+// Если в очереди пользователя уже maxQueued вычислений, возвращает ErrQueueFull (0 отключает ограничение).
+func InsertCalculationRequest(db *sql.DB, req models.CalculationRequest, maxQueued int) (int, error) {
 
 	if err := db.Ping(); err != nil {
 
@@ -188,18 +339,32 @@ func InsertCalculation(db *sql.DB, userId int, operation string, addDuration, su
 	}
 
 	query := `
-        INSERT INTO calculations (userId, operation, status, created_time, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        INSERT INTO calculations (userId, operation, status, created_time, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, webhook_url, webhook_secret, priority)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
         RETURNING id
     `
 	status := `created`
 	createdTime := time.Now().UTC()
 
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := reserveQueue(tx, req.UserId, 1, maxQueued); err != nil {
+		return 0, err
+	}
+
 	var id int
-	err := db.QueryRow(query, userId, operation, status, createdTime, addDuration, subtractDuration, multiplyDuration, divideDuration, inactiveServerTime).Scan(&id)
+	err = tx.QueryRow(query, req.UserId, req.Operation, status, createdTime, req.AddDuration, req.SubtractDuration, req.MultiplyDuration, req.DivideDuration, req.InactiveServerTime,
+		nullString(req.WebhookURL), nullString(req.WebhookSecret), req.Priority).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing calculation: %w", err)
+	}
 
 	fmt.Println("Calculation record inserted successfully.")
 	return id, nil
@@ -262,45 +427,38 @@ func checkAndPrintCreatedRecords(db *sql.DB) error {
 	return nil
 }
 
-func UpdateCalculation(db *sql.DB, id int, result float64, status string) error {
-
-	query := `
-        UPDATE calculations
-        SET result = $1, status = $2, end_time = $3
-        WHERE id = $4
-    `
-	endTime := time.Now().UTC()
-
-	_, err := db.Exec(query, result, status, endTime, id)
-	if err != nil {
-		return err
-	}
-
-	fmt.Printf("Calculation record with ID %d updated successfully.\n", id)
-	return nil
-}
-
-func UpdateCalculationStatusToWork(db *sql.DB, id int) error {
-	query := `
-        UPDATE calculations
-        SET status = 'work', start_time = timezone('UTC', NOW())
-        WHERE id = $1
-    `
-
-	_, err := db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("error updating calculation status to work and setting start time: %w", err)
-	}
-
-	fmt.Printf("Calculation record with ID %d status updated to work and start time set.\n", id)
-	return nil
-}
-
-func FetchCalculationsToProcess(db *sql.DB) ([]models.CalculationRequest, error) {
+// FetchCalculationsToProcess выбирает до limit вычислений в статусе 'created' для отправки на калькуляторы:
+// сначала с наибольшим приоритетом, при равном приоритете - в порядке поступления.
+// Приоритет ожидающего вычисления растет на единицу за каждый интервал aging (0 - без старения) до MaxPriority,
+// поэтому вычисления с низким приоритетом не ждут бесконечно. У каждого пользователя вместе с уже
+// выполняющимися будет не более maxRunning вычислений (0 - без ограничения).
+// Вычисления, повторная попытка которых отложена, не выбираются до наступления next_attempt_time.
+// Вместе с вычислением возвращается его время ожидания неактивного сервера и количество начатых попыток.
+func FetchCalculationsToProcess(db *sql.DB, limit, maxRunning int, aging time.Duration) ([]models.CalculationRequest, error) {
 	var calculations []models.CalculationRequest
 
-	query := `SELECT id, userId, operation, add_duration, subtract_duration, multiply_duration, divide_duration FROM calculations WHERE status = 'created' LIMIT 5`
-	rows, err := db.Query(query)
+	query := `
+		SELECT id, userId, operation, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, attempts
+		FROM (
+			SELECT *,
+				ROW_NUMBER() OVER (PARTITION BY userId ORDER BY effective_priority DESC, id) AS position
+			FROM (
+				SELECT c.id, c.userId, c.operation, c.add_duration, c.subtract_duration, c.multiply_duration, c.divide_duration,
+					COALESCE(c.inactive_server_time, 0) AS inactive_server_time, c.attempts,
+					CASE WHEN $3::integer > 0
+						THEN LEAST($5::integer, c.priority + FLOOR(EXTRACT(EPOCH FROM ($4::timestamp - c.created_time)) / $3::integer)::integer)
+						ELSE c.priority
+					END AS effective_priority,
+					(SELECT COUNT(*) FROM calculations w WHERE w.userId = c.userId AND w.status = 'work') AS running
+				FROM calculations c
+				WHERE c.status = 'created' AND (c.next_attempt_time IS NULL OR c.next_attempt_time <= $4)
+			) aged
+		) queued
+		WHERE $2 <= 0 OR running + position <= $2
+		ORDER BY effective_priority DESC, id
+		LIMIT $1
+	`
+	rows, err := db.Query(query, limit, maxRunning, int(aging/time.Second), time.Now().UTC(), models.MaxPriority)
 	if err != nil {
 		return nil, err
 	}
@@ -308,7 +466,7 @@ func FetchCalculationsToProcess(db *sql.DB) ([]models.CalculationRequest, error)
 
 	for rows.Next() { // Перебор всех полученных записей.
 		var calc models.CalculationRequest
-		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &calc.AddDuration, &calc.SubtractDuration, &calc.MultiplyDuration, &calc.DivideDuration); err != nil {
+		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &calc.AddDuration, &calc.SubtractDuration, &calc.MultiplyDuration, &calc.DivideDuration, &calc.InactiveServerTime, &calc.Attempts); err != nil {
 			return nil, err // Возврат ошибки при возникновении.
 		}
 		calculations = append(calculations, calc) // Добавление записи в слайс.
@@ -321,25 +479,42 @@ func FetchCalculationsToProcess(db *sql.DB) ([]models.CalculationRequest, error)
 	return calculations, nil // Возвращение слайса с результатами и nil в случае успешного выполнения функции.
 }
 
-// GetCalculationResultByID извлекает результат вычисления по его ID.
+// CountActiveCalculations возвращает количество вычислений пользователя в очереди ('created') и в работе ('work').
+func CountActiveCalculations(db *sql.DB, userId int) (int, int, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE status = 'created'), COUNT(*) FILTER (WHERE status = 'work')
+		FROM calculations
+		WHERE userId = $1
+	`
+	var queued, running int
+	if err := db.QueryRow(query, userId).Scan(&queued, &running); err != nil {
+		return 0, 0, fmt.Errorf("counting active calculations for user %d: %w", userId, err)
+	}
+	return queued, running, nil
+}
+
+// GetCalculationResultByID извлекает результат вычисления по его ID. Вычисления в корзине не возвращаются.
 func GetCalculationResultByID(db *sql.DB, id int) (*models.CalculationResponse, error) {
 	var (
 		operation string
 		result    sql.NullFloat64 // Использование sql.NullFloat64 для обработки NULL значений.
 		status    string
 		userId    int
+		execution executionRow
 	)
-	query := `SELECT operation, result, status, userId FROM calculations WHERE id = $1` // SQL-запрос для выборки.
-	err := db.QueryRow(query, id).Scan(&operation, &result, &status, &userId)           // Выполнение запроса и считывание результатов.
+	query := `SELECT operation, result, status, userId, ` + executionColumns + ` FROM calculations WHERE id = $1 AND deleted_time IS NULL` // SQL-запрос для выборки.
+	dest := append([]interface{}{&operation, &result, &status, &userId}, execution.dest()...)
+	err := db.QueryRow(query, id).Scan(dest...) // Выполнение запроса и считывание результатов.
 	if err != nil {
 		return nil, err // Возврат ошибки при возникновении.
 	}
 
 	calcResult := &models.CalculationResponse{
-		ID:        id,
-		Operation: operation,
-		UserId:    userId,
-		Status:    status,
+		ID:                   id,
+		Operation:            operation,
+		UserId:               userId,
+		Status:               status,
+		CalculationExecution: execution.execution(),
 	}
 
 	if result.Valid {
@@ -349,12 +524,12 @@ func GetCalculationResultByID(db *sql.DB, id int) (*models.CalculationResponse,
 	return calcResult, nil // Возвращение ответа и nil в случае успешного выполнения функции.
 }
 
-// FetchAllCalculations извлекает все вычисления из базы данных.
+// FetchAllCalculations извлекает все вычисления из базы данных, кроме находящихся в корзине.
 func FetchAllCalculations(db *sql.DB) ([]models.OperationResponse, error) {
 	var calculations []models.OperationResponse // Слайс для хранения результатов.
 
-	query := `SELECT id, userId, operation, result, status FROM calculations` // SQL-запрос для выборки всех записей.
-	rows, err := db.Query(query)                                              // Выполнение запроса.
+	query := `SELECT id, userId, operation, result, status, ` + executionColumns + ` FROM calculations WHERE deleted_time IS NULL` // SQL-запрос для выборки всех записей.
+	rows, err := db.Query(query)                                                                                                   // Выполнение запроса.
 	if err != nil {
 		return nil, fmt.Errorf("querying calculations: %w", err)
 	}
@@ -363,10 +538,13 @@ func FetchAllCalculations(db *sql.DB) ([]models.OperationResponse, error) {
 	for rows.Next() { // Перебор всех полученных записей.
 		var calc models.OperationResponse
 		var result sql.NullFloat64 // Использование sql.NullFloat64 для обработки NULL значений.
+		var execution executionRow
 
-		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status); err != nil {
+		dest := append([]interface{}{&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status}, execution.dest()...)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("scanning calculation: %w", err)
 		}
+		calc.CalculationExecution = execution.execution()
 
 		if result.Valid {
 			calc.Result = result.Float64 // Присвоение результата, если он не NULL.
@@ -384,17 +562,65 @@ func FetchAllCalculations(db *sql.DB) ([]models.OperationResponse, error) {
 	return calculations, nil // Возвращение слайса с результатами и nil в случае успешного выполнения функции.
 }
 
-// FetchCalculationsByUser извлекает все вычисления для конкретного пользователя.
+// FetchCalculationsByUser извлекает все вычисления для конкретного пользователя, кроме находящихся в корзине.
 func FetchCalculationsByUser(db *sql.DB, userId int) ([]models.OperationResponse, error) {
 	var calculations []models.OperationResponse
 
-	query := `SELECT id, userId, operation, result, status FROM calculations WHERE userId = $1`
+	query := `SELECT id, userId, operation, result, status, ` + executionColumns + ` FROM calculations WHERE userId = $1 AND deleted_time IS NULL`
 	rows, err := db.Query(query, userId) // Выполнение запроса с фильтрацией по userId.
 	if err != nil {
 		return nil, fmt.Errorf("querying calculations for user %d: %w", userId, err)
 	}
 	defer rows.Close()
 
+	for rows.Next() {
+		var calc models.OperationResponse
+		var result sql.NullFloat64 // Для обработки NULL значений.
+		var execution executionRow
+
+		dest := append([]interface{}{&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status}, execution.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("scanning calculation: %w", err)
+		}
+		calc.CalculationExecution = execution.execution()
+
+		if result.Valid {
+			calc.Result = result.Float64
+		}
+
+		calculations = append(calculations, calc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over calculations results: %w", err)
+	}
+
+	return calculations, nil
+}
+
+// FetchCalculationStatuses извлекает текущие статусы вычислений по списку ID и по списку пользователей.
+// Используется для отслеживания изменений статусов и рассылки событий подписчикам.
+// По пользователям извлекаются только вычисления, которые еще не перешли в один из окончательных статусов terminal
+// или перешли в него не раньше since, чтобы опрос не перечитывал всю историю вычислений пользователя.
+// Вычисления в корзине не извлекаются.
+func FetchCalculationStatuses(db *sql.DB, ids []int, userIds []int, terminal []string, since time.Time) ([]models.OperationResponse, error) {
+	var calculations []models.OperationResponse
+
+	if len(ids) == 0 && len(userIds) == 0 {
+		return calculations, nil
+	}
+
+	query := `
+		SELECT id, userId, operation, result, status FROM calculations
+		WHERE deleted_time IS NULL
+			AND (id = ANY($1) OR (userId = ANY($2) AND (status <> ALL($3) OR end_time >= $4)))
+	`
+	rows, err := db.Query(query, pq.Array(ids), pq.Array(userIds), pq.Array(terminal), since)
+	if err != nil {
+		return nil, fmt.Errorf("querying calculation statuses: %w", err)
+	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var calc models.OperationResponse
 		var result sql.NullFloat64 // Для обработки NULL значений.
@@ -417,6 +643,44 @@ func FetchCalculationsByUser(db *sql.DB, userId int) ([]models.OperationResponse
 	return calculations, nil
 }
 
+// CancelCalculation переводит еще не отправленное на калькулятор вычисление в статус 'cancelled'.
+// Возвращает false, если вычисление уже выполняется или завершено.
+func CancelCalculation(db *sql.DB, id int) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'cancelled', end_time = $1
+		WHERE id = $2 AND status = 'created'
+	`
+	res, err := db.Exec(query, time.Now().UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("cancelling calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("checking cancelled calculation %d: %w", id, err)
+	}
+	return affected > 0, nil
+}
+
+// SetCalculationPriority меняет приоритет вычисления, еще находящегося в очереди.
+// Возвращает false, если вычисление уже не в статусе 'created'.
+func SetCalculationPriority(db *sql.DB, id, priority int) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET priority = $1
+		WHERE id = $2 AND status = 'created'
+	`
+	res, err := db.Exec(query, priority, id)
+	if err != nil {
+		return false, fmt.Errorf("updating priority of calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("checking priority of calculation %d: %w", id, err)
+	}
+	return affected > 0, nil
+}
+
 // ClearAllCalculations удаляет все строки из таблицы 'calculations'.
 func ClearAllCalculations(db *sql.DB) error {
 	// SQL statement to delete all rows
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// executionColumns - столбцы со сведениями о выполнении, которые выбираются вместе с вычислением.
+const executionColumns = `created_time, start_time, end_time, operation_server, server_status, agent_id, attempts`
+
+// executionRow считывает столбцы executionColumns, допускающие NULL.
+type executionRow struct {
+	createdTime, startTime, endTime        sql.NullTime
+	operationServer, serverStatus, agentID sql.NullString
+	attempts                               int
+}
+
+// dest возвращает приемники для rows.Scan в порядке executionColumns.
+func (r *executionRow) dest() []interface{} {
+	return []interface{}{&r.createdTime, &r.startTime, &r.endTime, &r.operationServer, &r.serverStatus, &r.agentID, &r.attempts}
+}
+
+// execution преобразует считанные значения в сведения о выполнении.
+func (r *executionRow) execution() models.CalculationExecution {
+	return models.CalculationExecution{
+		CreatedTime:     nullableTime(r.createdTime),
+		StartTime:       nullableTime(r.startTime),
+		EndTime:         nullableTime(r.endTime),
+		OperationServer: r.operationServer.String,
+		ServerStatus:    r.serverStatus.String,
+		AgentID:         r.agentID.String,
+		Attempts:        r.attempts,
+	}
+}
+
+// nullableTime возвращает nil для NULL, иначе указатель на время в UTC.
+func nullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+// RecordDispatch записывает агента agentID с адресом server, которому отправляется попытка attempt вычисления.
+// Запись делается до отправки, чтобы отчеты агента о попытке не опередили ее. Попытка записывается, только если
+// предыдущая попытка все еще последняя, поэтому два оркестратора, выбравшие одно вычисление, не отправят его оба.
+// При повторной отправке той же попытки другому агенту передается previousAgentID - агент, которому попытка была
+// записана, и запись перезаписывается, только если она не изменилась. Возвращает false, если вычисление уже
+// не ждет отправки, например завершено, отменено, отправлено другим оркестратором или арендовано агентом (LeaseCalculation).
+func RecordDispatch(db *sql.DB, id, attempt int, server, agentID, previousAgentID string) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET operation_server = $1, server_status = 'dispatched', agent_id = $2, attempts = $3
+		WHERE id = $4 AND status = 'created'
+			AND (($5 = '' AND attempts = $3 - 1) OR ($5 <> '' AND attempts = $3 AND agent_id = $5))
+	`
+	res, err := db.Exec(query, server, agentID, attempt, id, previousAgentID)
+	if err != nil {
+		return false, fmt.Errorf("recording dispatch of calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ReleaseDispatch отменяет запись попытки attempt, которую не принял ни один агент,
+// чтобы неудачная отправка не расходовала попытки вычисления.
+func ReleaseDispatch(db *sql.DB, id, attempt int) error {
+	query := `
+		UPDATE calculations
+		SET operation_server = NULL, server_status = NULL, agent_id = NULL, attempts = attempts - 1
+		WHERE id = $1 AND attempts = $2 AND status = 'created'
+	`
+	if _, err := db.Exec(query, id, attempt); err != nil {
+		return fmt.Errorf("releasing dispatch of calculation %d: %w", id, err)
+	}
+	return nil
+}
+
+// LeaseCalculation передает вычисление агенту agentID, который сам запросил задачи, и начинает новую попытку.
+// Вычисление арендуется одним запросом, поэтому агенты, одновременно запросившие задачи, не получат его дважды.
+// Возвращает номер попытки или false, если вычисление уже не ждет отправки.
+func LeaseCalculation(db *sql.DB, id int, server, agentID string) (int, bool, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'work', start_time = timezone('UTC', NOW()), operation_server = $1, server_status = 'queued',
+			agent_id = $2, attempts = attempts + 1
+		WHERE id = $3 AND status = 'created'
+		RETURNING attempts
+	`
+	var attempt int
+	err := db.QueryRow(query, server, agentID, id).Scan(&attempt)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("leasing calculation %d: %w", id, err)
+	}
+	return attempt, true, nil
+}
+
+// MarkCalculationQueued отмечает, что агент agentID принял попытку attempt вычисления и поставил ее в свою очередь.
+// Если агент уже сообщил о начале выполнения, состояние на сервере не откатывается. Если агент уже сообщил,
+// что прервал попытку, вычисление остается в очереди оркестратора.
+func MarkCalculationQueued(db *sql.DB, id, attempt int, agentID string) error {
+	query := `
+		UPDATE calculations
+		SET status = 'work', start_time = timezone('UTC', NOW()),
+			server_status = CASE WHEN server_status = 'dispatched' THEN 'queued' ELSE server_status END
+		WHERE id = $1 AND attempts = $2 AND agent_id = $3 AND status = 'created' AND server_status IN ('dispatched', 'work')
+	`
+	if _, err := db.Exec(query, id, attempt, agentID); err != nil {
+		return fmt.Errorf("recording acceptance of calculation %d: %w", id, err)
+	}
+	return nil
+}
+
+// Отчеты агента о вычислении принимаются, только пока попытка attempt назначена этому агенту
+// и вычисление не завершено и не отменено. Функции ниже возвращают false для устаревших отчетов.
+// О начале и прерывании выполнения в статусе 'created' принимаются только отчеты об отправленной попытке,
+// которую оркестратор еще не отметил принятой, но не о попытке, после которой вычисление вернулось в очередь.
+
+// MarkCalculationStarted отмечает, что агент agentID взял попытку attempt вычисления из очереди и начал ее выполнение.
+// Время начала обновляется, чтобы ожидание в очереди агента не сокращало время на выполнение.
+func MarkCalculationStarted(db *sql.DB, id, attempt int, agentID string) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET server_status = 'work', start_time = timezone('UTC', NOW())
+		WHERE id = $1 AND attempts = $2 AND agent_id = $3 AND (status = 'work' OR (status = 'created' AND server_status = 'dispatched'))
+	`
+	res, err := db.Exec(query, id, attempt, agentID)
+	if err != nil {
+		return false, fmt.Errorf("recording start of calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CompleteCalculation записывает результат попытки attempt вычисления, полученный агентом agentID.
+func CompleteCalculation(db *sql.DB, id, attempt int, agentID string, result float64) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET result = $4, status = 'completed', server_status = 'completed', end_time = $5
+		WHERE id = $1 AND attempts = $2 AND agent_id = $3 AND status IN ('created', 'work')
+	`
+	res, err := db.Exec(query, id, attempt, agentID, result, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("recording result of calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ReleaseAttempt возвращает в очередь вычисление, попытку attempt которого агент agentID отклонил, не начав выполнение,
+// например аренду, для которой у агента не нашлось места. Как и в ReleaseDispatch, попытка не расходуется.
+// Возвращает false, если попытка не назначена агенту или агент уже начал ее выполнение.
+func ReleaseAttempt(db *sql.DB, id, attempt int, agentID string) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'created', start_time = NULL, operation_server = NULL, server_status = NULL, agent_id = NULL,
+			attempts = attempts - 1
+		WHERE id = $1 AND attempts = $2 AND agent_id = $3 AND status = 'work' AND server_status IN ('dispatched', 'queued')
+	`
+	res, err := db.Exec(query, id, attempt, agentID)
+	if err != nil {
+		return false, fmt.Errorf("releasing attempt of calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// AbortCalculation отмечает, что агент agentID прервал начатую попытку attempt вычисления, не получив результата.
+// Возвращает пользователя, выражение и количество попыток после последнего возврата в очередь администратором,
+// чтобы прерванная попытка была учтена по политике повторов.
+func AbortCalculation(db *sql.DB, id, attempt int, agentID string) (userId int, operation string, attempts int, ok bool, err error) {
+	query := `
+		UPDATE calculations
+		SET server_status = 'aborted'
+		WHERE id = $1 AND attempts = $2 AND agent_id = $3 AND (status = 'work' OR (status = 'created' AND server_status = 'dispatched'))
+		RETURNING userId, operation, attempts - attempts_base
+	`
+	err = db.QueryRow(query, id, attempt, agentID).Scan(&userId, &operation, &attempts)
+	if err == sql.ErrNoRows {
+		return 0, "", 0, false, nil
+	}
+	if err != nil {
+		return 0, "", 0, false, fmt.Errorf("recording abort of calculation %d: %w", id, err)
+	}
+	return userId, operation, attempts, true, nil
+}
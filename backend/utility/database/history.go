@@ -0,0 +1,114 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"io"                           // Признак конца импортируемых записей
+	"time"                         // Работа со временем
+)
+
+// nullTime превращает нулевое время в NULL при записи в базу данных.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// FetchCalculationsByUserPage извлекает до limit вычислений пользователя с ID больше afterID в порядке ID.
+// Вычисления в корзине не экспортируются.
+// Используется для постраничного чтения истории без загрузки её целиком в память:
+// следующая страница запрашивается с ID последней записи предыдущей.
+func FetchCalculationsByUserPage(db *sql.DB, userId, afterID, limit int, filter models.HistoryFilter) ([]models.HistoryRecord, error) {
+	var records []models.HistoryRecord
+
+	query := `
+		SELECT id, operation, result, status, created_time, end_time
+		FROM calculations
+		WHERE userId = $1 AND id > $2 AND deleted_time IS NULL
+			AND ($3::timestamp IS NULL OR created_time >= $3)
+			AND ($4::timestamp IS NULL OR created_time < $4)
+		ORDER BY id
+		LIMIT $5
+	`
+	rows, err := db.Query(query, userId, afterID, nullTime(filter.From), nullTime(filter.To), limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying calculations for user %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			record      models.HistoryRecord
+			operation   sql.NullString
+			result      sql.NullFloat64
+			status      sql.NullString
+			createdTime sql.NullTime
+			endTime     sql.NullTime
+		)
+		if err := rows.Scan(&record.ID, &operation, &result, &status, &createdTime, &endTime); err != nil {
+			return nil, fmt.Errorf("scanning calculation: %w", err)
+		}
+
+		record.Operation = operation.String
+		record.Status = status.String
+		record.CreatedTime = createdTime.Time
+		if result.Valid {
+			record.Result = &result.Float64
+		}
+		if endTime.Valid {
+			record.EndTime = &endTime.Time
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over calculations results: %w", err)
+	}
+	return records, nil
+}
+
+// ImportCalculations в одной транзакции создает завершенные вычисления пользователя из записей,
+// которые возвращает next, пока он не вернет io.EOF. Сохраняются исходные времена создания и завершения.
+// Уведомления о завершении импортированных вычислений не отправляются.
+func ImportCalculations(db *sql.DB, userId int, next func() (*models.HistoryRecord, error)) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+        INSERT INTO calculations (userId, operation, result, status, created_time, start_time, end_time, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, notified)
+        VALUES ($1, $2, $3, 'completed', $4, $4, $5, 0, 0, 0, 0, 0, TRUE)
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("preparing import: %w", err)
+	}
+	defer stmt.Close()
+
+	imported := 0
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		endTime := record.CreatedTime
+		if record.EndTime != nil {
+			endTime = *record.EndTime
+		}
+		if _, err := stmt.Exec(userId, record.Operation, *record.Result, record.CreatedTime.UTC(), endTime.UTC()); err != nil {
+			return 0, fmt.Errorf("importing calculation: %w", err)
+		}
+		imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing import: %w", err)
+	}
+
+	fmt.Printf("%d calculations imported for user %d.\n", imported, userId)
+	return imported, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// CreateIdempotencyTableIfNotExists создает таблицу ключей идемпотентности с уникальностью ключа в пределах пользователя.
+func CreateIdempotencyTableIfNotExists(db *sql.DB) error {
+	return createTableIfNotExists(db, "idempotency_keys", `
+		CREATE TABLE idempotency_keys (
+			id SERIAL PRIMARY KEY,
+			userId INTEGER NOT NULL,
+			key TEXT NOT NULL,
+			endpoint TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			response_code INTEGER,
+			response_body TEXT,
+			created_time TIMESTAMP NOT NULL,
+			UNIQUE (userId, key)
+		)
+	`)
+}
+
+// ClaimIdempotencyKey пытается закрепить ключ пользователя за новым запросом.
+// Ключи старше expiredBefore считаются истекшими и освобождаются.
+// Если ключ уже занят, возвращает false и существующую запись.
+func ClaimIdempotencyKey(db *sql.DB, userId int, key, endpoint, requestHash string, expiredBefore time.Time) (bool, *models.IdempotencyRecord, error) {
+	if _, err := db.Exec(`DELETE FROM idempotency_keys WHERE userId = $1 AND key = $2 AND created_time < $3`, userId, key, expiredBefore); err != nil {
+		return false, nil, fmt.Errorf("releasing expired idempotency key: %w", err)
+	}
+
+	record := &models.IdempotencyRecord{UserId: userId, Key: key, Endpoint: endpoint, RequestHash: requestHash, CreatedTime: time.Now().UTC()}
+	err := db.QueryRow(`
+		INSERT INTO idempotency_keys (userId, key, endpoint, request_hash, created_time)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (userId, key) DO NOTHING
+		RETURNING id
+	`, userId, key, endpoint, requestHash, record.CreatedTime).Scan(&record.ID)
+	if err == nil {
+		return true, record, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, nil, fmt.Errorf("claiming idempotency key: %w", err)
+	}
+
+	// Ключ уже использован, возвращаем сохраненную запись
+	var (
+		responseCode sql.NullInt64
+		responseBody sql.NullString
+	)
+	err = db.QueryRow(`
+		SELECT id, endpoint, request_hash, response_code, response_body, created_time
+		FROM idempotency_keys WHERE userId = $1 AND key = $2
+	`, userId, key).Scan(&record.ID, &record.Endpoint, &record.RequestHash, &responseCode, &responseBody, &record.CreatedTime)
+	if err != nil {
+		return false, nil, fmt.Errorf("fetching idempotency key: %w", err)
+	}
+	record.ResponseCode = int(responseCode.Int64)
+	record.ResponseBody = responseBody.String
+	return false, record, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, закрепивший ключ.
+func CompleteIdempotencyKey(db *sql.DB, id, responseCode int, responseBody string) error {
+	_, err := db.Exec(`UPDATE idempotency_keys SET response_code = $1, response_body = $2 WHERE id = $3`, responseCode, responseBody, id)
+	if err != nil {
+		return fmt.Errorf("completing idempotency key %d: %w", id, err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey удаляет ключ, запрос с которым завершился ошибкой, чтобы его можно было повторить.
+func ReleaseIdempotencyKey(db *sql.DB, id int) error {
+	if _, err := db.Exec(`DELETE FROM idempotency_keys WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("releasing idempotency key %d: %w", id, err)
+	}
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys удаляет все ключи, созданные раньше expiredBefore.
+func PurgeExpiredIdempotencyKeys(db *sql.DB, expiredBefore time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM idempotency_keys WHERE created_time < $1`, expiredBefore)
+	if err != nil {
+		return 0, fmt.Errorf("purging idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrQueueFull - в очереди пользователя нет места для новых вычислений.
+var ErrQueueFull = errors.New("queued calculations quota exceeded")
+
+// Первый ключ рекомендательных блокировок очередей пользователей, второй ключ - ID пользователя
+const queueLockClass = 1
+
+// reserveQueue блокирует очередь пользователя userId до конца транзакции tx и проверяет, что в ней есть место
+// еще для count вычислений. Одновременные отправки одного пользователя ждут друг друга, поэтому вместе
+// не превышают maxQueued. При maxQueued равном 0 ограничение не действует. Возвращает ErrQueueFull, если места нет.
+func reserveQueue(tx *sql.Tx, userId, count, maxQueued int) error {
+	if maxQueued <= 0 {
+		return nil
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, queueLockClass, userId); err != nil {
+		return fmt.Errorf("locking queue of user %d: %w", userId, err)
+	}
+
+	var queued int
+	query := `SELECT COUNT(*) FROM calculations WHERE userId = $1 AND status = 'created'`
+	if err := tx.QueryRow(query, userId).Scan(&queued); err != nil {
+		return fmt.Errorf("counting queued calculations for user %d: %w", userId, err)
+	}
+	if queued+count > maxQueued {
+		return ErrQueueFull
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// RetryCalculation возвращает выполняемое вычисление в очередь после неудачной попытки.
+// Вычисление не будет отправлено на калькулятор раньше nextAttempt. Состояние попытки на сервере сбрасывается,
+// чтобы запоздалые отчеты агента о ней не изменили вычисление, ждущее новой попытки.
+// Вычисление, попытку которого агент прервал до того, как оркестратор отметил ее принятой, еще в статусе 'created'
+// и тоже возвращается в очередь. Возвращает false, если вычисление уже не выполняется, например успело завершиться.
+func RetryCalculation(db *sql.DB, id int, reason string, nextAttempt time.Time) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'created', start_time = NULL, server_status = NULL, next_attempt_time = $1, failure_reason = $2
+		WHERE id = $3 AND (status = 'work' OR (status = 'created' AND server_status = 'aborted'))
+	`
+	res, err := db.Exec(query, nextAttempt, reason, id)
+	if err != nil {
+		return false, fmt.Errorf("retrying calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// FailCalculation переводит выполняемое вычисление в окончательный статус 'failed' с причиной неудачи.
+// Как и RetryCalculation, учитывает попытку, прерванную агентом до того, как оркестратор отметил ее принятой.
+// Возвращает false, если вычисление уже не выполняется.
+func FailCalculation(db *sql.DB, id int, reason string, now time.Time) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'failed', end_time = $1, next_attempt_time = NULL, failure_reason = $2
+		WHERE id = $3 AND (status = 'work' OR (status = 'created' AND server_status = 'aborted'))
+	`
+	res, err := db.Exec(query, now, reason, id)
+	if err != nil {
+		return false, fmt.Errorf("failing calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// FetchFailedCalculations извлекает до limit вычислений в статусе 'failed', начиная с последнего.
+func FetchFailedCalculations(db *sql.DB, limit int) ([]models.FailedCalculation, error) {
+	var calculations []models.FailedCalculation
+
+	query := `
+		SELECT id, userId, operation, attempts, failure_reason, end_time
+		FROM calculations
+		WHERE status = 'failed' AND deleted_time IS NULL
+		ORDER BY end_time DESC, id DESC
+		LIMIT $1
+	`
+	rows, err := db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying failed calculations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			calc    models.FailedCalculation
+			reason  sql.NullString
+			endTime sql.NullTime
+		)
+		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &calc.Attempts, &reason, &endTime); err != nil {
+			return nil, fmt.Errorf("scanning failed calculation: %w", err)
+		}
+		calc.FailureReason = reason.String
+		calc.EndTime = endTime.Time
+		calculations = append(calculations, calc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over failed calculations: %w", err)
+	}
+	return calculations, nil
+}
+
+// RequeueCalculation возвращает вычисление из статуса 'failed' в очередь с новым набором попыток.
+// Счетчик попыток не сбрасывается, чтобы отчеты агентов о старых попытках не совпали с новыми.
+// Уведомления о завершении будут отправлены повторно.
+// Возвращает false, если вычисление не в статусе 'failed' или находится в корзине.
+func RequeueCalculation(db *sql.DB, id int) (bool, error) {
+	query := `
+		UPDATE calculations
+		SET status = 'created', attempts_base = attempts, start_time = NULL, end_time = NULL, next_attempt_time = NULL,
+			server_status = NULL, failure_reason = NULL, notified = FALSE
+		WHERE id = $1 AND status = 'failed' AND deleted_time IS NULL
+	`
+	res, err := db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("requeueing calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
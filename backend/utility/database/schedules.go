@@ -0,0 +1,266 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"errors"                       // Сравнение ошибок
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// CreateScheduleTableIfNotExists создает таблицу расписаний вычислений.
+func CreateScheduleTableIfNotExists(db *sql.DB) error {
+	err := createTableIfNotExists(db, "schedules", `
+		CREATE TABLE schedules (
+			id SERIAL PRIMARY KEY,
+			userId INTEGER NOT NULL,
+			operation TEXT NOT NULL,
+			cron TEXT,
+			run_at TIMESTAMP,
+			timezone TEXT NOT NULL,
+			add_duration INTEGER NOT NULL,
+			subtract_duration INTEGER NOT NULL,
+			multiply_duration INTEGER NOT NULL,
+			divide_duration INTEGER NOT NULL,
+			inactive_server_time INTEGER NOT NULL,
+			priority INTEGER NOT NULL,
+			enabled BOOLEAN NOT NULL,
+			next_run_time TIMESTAMP,
+			last_run_time TIMESTAMP,
+			last_calculation_id INTEGER,
+			created_time TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS schedules_next_run_time_idx ON schedules (next_run_time) WHERE enabled`)
+	return err
+}
+
+// Столбцы расписания в порядке, ожидаемом scanSchedule
+const scheduleColumns = `id, userId, operation, cron, run_at, timezone, add_duration, subtract_duration, multiply_duration, divide_duration,
+	inactive_server_time, priority, enabled, next_run_time, last_run_time, last_calculation_id, created_time`
+
+// scanSchedule читает расписание из строки результата запроса.
+func scanSchedule(row interface{ Scan(...interface{}) error }) (*models.Schedule, error) {
+	var (
+		s                 models.Schedule
+		cron              sql.NullString
+		runAt             sql.NullTime
+		nextRunTime       sql.NullTime
+		lastRunTime       sql.NullTime
+		lastCalculationID sql.NullInt64
+	)
+	err := row.Scan(&s.ID, &s.UserId, &s.Operation, &cron, &runAt, &s.Timezone, &s.AddDuration, &s.SubtractDuration, &s.MultiplyDuration, &s.DivideDuration,
+		&s.InactiveServerTime, &s.Priority, &s.Enabled, &nextRunTime, &lastRunTime, &lastCalculationID, &s.CreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Cron = cron.String
+	if runAt.Valid {
+		s.RunAt = &runAt.Time
+	}
+	if nextRunTime.Valid {
+		s.NextRunTime = &nextRunTime.Time
+	}
+	if lastRunTime.Valid {
+		s.LastRunTime = &lastRunTime.Time
+	}
+	if lastCalculationID.Valid {
+		id := int(lastCalculationID.Int64)
+		s.LastCalculationID = &id
+	}
+	return &s, nil
+}
+
+// InsertSchedule сохраняет новое расписание и заполняет его ID и время создания.
+func InsertSchedule(db *sql.DB, s *models.Schedule) error {
+	s.CreatedTime = time.Now().UTC()
+
+	query := `
+		INSERT INTO schedules (userId, operation, cron, run_at, timezone, add_duration, subtract_duration, multiply_duration, divide_duration,
+			inactive_server_time, priority, enabled, next_run_time, created_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id
+	`
+	err := db.QueryRow(query, s.UserId, s.Operation, nullString(s.Cron), s.RunAt, s.Timezone, s.AddDuration, s.SubtractDuration, s.MultiplyDuration, s.DivideDuration,
+		s.InactiveServerTime, s.Priority, s.Enabled, s.NextRunTime, s.CreatedTime).Scan(&s.ID)
+	if err != nil {
+		return fmt.Errorf("inserting schedule: %w", err)
+	}
+	return nil
+}
+
+// GetSchedule извлекает расписание пользователя по ID. Возвращает sql.ErrNoRows, если расписание не найдено.
+func GetSchedule(db *sql.DB, id, userId int) (*models.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = $1 AND userId = $2`
+	return scanSchedule(db.QueryRow(query, id, userId))
+}
+
+// FetchSchedulesByUser извлекает все расписания пользователя.
+func FetchSchedulesByUser(db *sql.DB, userId int) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE userId = $1 ORDER BY id`
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying schedules for user %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning schedule: %w", err)
+		}
+		schedules = append(schedules, *s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule сохраняет измененное расписание пользователя. Возвращает false, если расписание не найдено.
+func UpdateSchedule(db *sql.DB, s *models.Schedule) (bool, error) {
+	query := `
+		UPDATE schedules
+		SET operation = $1, cron = $2, run_at = $3, timezone = $4, add_duration = $5, subtract_duration = $6, multiply_duration = $7,
+			divide_duration = $8, inactive_server_time = $9, priority = $10, enabled = $11, next_run_time = $12
+		WHERE id = $13 AND userId = $14
+	`
+	res, err := db.Exec(query, s.Operation, nullString(s.Cron), s.RunAt, s.Timezone, s.AddDuration, s.SubtractDuration, s.MultiplyDuration,
+		s.DivideDuration, s.InactiveServerTime, s.Priority, s.Enabled, s.NextRunTime, s.ID, s.UserId)
+	if err != nil {
+		return false, fmt.Errorf("updating schedule %d: %w", s.ID, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteSchedule удаляет расписание пользователя. Созданные им вычисления сохраняются.
+// Возвращает false, если расписание не найдено.
+func DeleteSchedule(db *sql.DB, id, userId int) (bool, error) {
+	res, err := db.Exec(`DELETE FROM schedules WHERE id = $1 AND userId = $2`, id, userId)
+	if err != nil {
+		return false, fmt.Errorf("deleting schedule %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// FetchScheduleCalculations извлекает вычисления, созданные запусками расписания, начиная с последнего.
+func FetchScheduleCalculations(db *sql.DB, scheduleID, limit int) ([]models.OperationResponse, error) {
+	var calculations []models.OperationResponse
+
+	query := `SELECT id, userId, operation, result, status FROM calculations WHERE schedule_id = $1 AND deleted_time IS NULL ORDER BY id DESC LIMIT $2`
+	rows, err := db.Query(query, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying calculations for schedule %d: %w", scheduleID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var calc models.OperationResponse
+		var result sql.NullFloat64 // Для обработки NULL значений.
+
+		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status); err != nil {
+			return nil, fmt.Errorf("scanning calculation: %w", err)
+		}
+		if result.Valid {
+			calc.Result = result.Float64
+		}
+		calculations = append(calculations, calc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over calculations results: %w", err)
+	}
+	return calculations, nil
+}
+
+// RunDueSchedules в одной транзакции создает вычисления для включенных расписаний, время запуска которых
+// наступило к now, и переносит их следующий запуск на время, возвращенное nextRun (nil - запусков больше нет).
+// Расписания блокируются до конца транзакции, поэтому каждый запуск выполняется ровно один раз,
+// а при сбое до фиксации транзакции будет повторен. Если в очереди пользователя уже maxQueued вычислений,
+// запуск пропускается (Skipped), а следующий планируется как обычно. maxQueued равный 0 отключает ограничение.
+func RunDueSchedules(db *sql.DB, now time.Time, limit, maxQueued int, nextRun func(s models.Schedule) *time.Time) ([]models.ScheduleRun, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM schedules
+		WHERE enabled AND next_run_time <= $1
+		ORDER BY next_run_time
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying due schedules: %w", err)
+	}
+	var due []models.Schedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning schedule: %w", err)
+		}
+		due = append(due, *s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over due schedules: %w", err)
+	}
+
+	runs := make([]models.ScheduleRun, 0, len(due))
+	for _, s := range due {
+		err := reserveQueue(tx, s.UserId, 1, maxQueued)
+		if errors.Is(err, ErrQueueFull) {
+			if _, err := tx.Exec(`UPDATE schedules SET next_run_time = $1 WHERE id = $2`, nextRun(s), s.ID); err != nil {
+				return nil, fmt.Errorf("updating schedule %d: %w", s.ID, err)
+			}
+			runs = append(runs, models.ScheduleRun{ScheduleID: s.ID, UserId: s.UserId, Operation: s.Operation, Time: now, Skipped: true})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var id int
+		err = tx.QueryRow(`
+			INSERT INTO calculations (userId, operation, status, created_time, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, priority, schedule_id)
+			VALUES ($1, $2, 'created', $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id
+		`, s.UserId, s.Operation, now, s.AddDuration, s.SubtractDuration, s.MultiplyDuration, s.DivideDuration, s.InactiveServerTime, s.Priority, s.ID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("inserting calculation for schedule %d: %w", s.ID, err)
+		}
+
+		_, err = tx.Exec(`UPDATE schedules SET next_run_time = $1, last_run_time = $2, last_calculation_id = $3 WHERE id = $4`,
+			nextRun(s), now, id, s.ID)
+		if err != nil {
+			return nil, fmt.Errorf("updating schedule %d: %w", s.ID, err)
+		}
+		runs = append(runs, models.ScheduleRun{ScheduleID: s.ID, CalculationID: id, UserId: s.UserId, Operation: s.Operation, Time: now})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing schedule runs: %w", err)
+	}
+	return runs, nil
+}
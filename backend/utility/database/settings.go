@@ -0,0 +1,73 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// CreateSettingsTableIfNotExists создает таблицу настроек пользователей.
+func CreateSettingsTableIfNotExists(db *sql.DB) error {
+	return createTableIfNotExists(db, "user_settings", `
+		CREATE TABLE user_settings (
+			userId INTEGER PRIMARY KEY,
+			add_duration INTEGER NOT NULL,
+			subtract_duration INTEGER NOT NULL,
+			multiply_duration INTEGER NOT NULL,
+			divide_duration INTEGER NOT NULL,
+			inactive_server_time INTEGER NOT NULL,
+			precision INTEGER NOT NULL,
+			output_format TEXT NOT NULL,
+			locale TEXT NOT NULL,
+			updated_time TIMESTAMP NOT NULL
+		)
+	`)
+}
+
+// GetUserSettings извлекает сохраненные настройки пользователя.
+// Возвращает sql.ErrNoRows, если пользователь их еще не сохранял.
+func GetUserSettings(db *sql.DB, userId int) (*models.UserSettings, error) {
+	settings := &models.UserSettings{UserId: userId}
+	var updatedTime time.Time
+
+	query := `
+		SELECT add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, precision, output_format, locale, updated_time
+		FROM user_settings WHERE userId = $1
+	`
+	err := db.QueryRow(query, userId).Scan(&settings.AddDuration, &settings.SubtractDuration, &settings.MultiplyDuration, &settings.DivideDuration,
+		&settings.InactiveServerTime, &settings.Precision, &settings.OutputFormat, &settings.Locale, &updatedTime)
+	if err != nil {
+		return nil, err
+	}
+	settings.UpdatedTime = &updatedTime
+	return settings, nil
+}
+
+// SaveUserSettings создает или полностью заменяет настройки пользователя.
+func SaveUserSettings(db *sql.DB, settings *models.UserSettings) error {
+	updatedTime := time.Now().UTC()
+
+	query := `
+		INSERT INTO user_settings (userId, add_duration, subtract_duration, multiply_duration, divide_duration, inactive_server_time, precision, output_format, locale, updated_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (userId) DO UPDATE SET
+			add_duration = EXCLUDED.add_duration,
+			subtract_duration = EXCLUDED.subtract_duration,
+			multiply_duration = EXCLUDED.multiply_duration,
+			divide_duration = EXCLUDED.divide_duration,
+			inactive_server_time = EXCLUDED.inactive_server_time,
+			precision = EXCLUDED.precision,
+			output_format = EXCLUDED.output_format,
+			locale = EXCLUDED.locale,
+			updated_time = EXCLUDED.updated_time
+	`
+	_, err := db.Exec(query, settings.UserId, settings.AddDuration, settings.SubtractDuration, settings.MultiplyDuration, settings.DivideDuration,
+		settings.InactiveServerTime, settings.Precision, settings.OutputFormat, settings.Locale, updatedTime)
+	if err != nil {
+		return fmt.Errorf("saving settings for user %d: %w", settings.UserId, err)
+	}
+
+	settings.UpdatedTime = &updatedTime
+	return nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+)
+
+// Удаление в корзину возможно только для вычислений, которые уже не выполняются и не ожидают выполнения.
+const trashableCondition = `deleted_time IS NULL AND status NOT IN ('created', 'work')`
+
+// TrashCalculation удаляет завершенное вычисление пользователя в корзину.
+// Возвращает false, если вычисление не найдено, уже в корзине или еще не завершено.
+func TrashCalculation(db *sql.DB, id, userId int, now time.Time) (bool, error) {
+	query := `UPDATE calculations SET deleted_time = $1 WHERE id = $2 AND userId = $3 AND ` + trashableCondition
+	res, err := db.Exec(query, now, id, userId)
+	if err != nil {
+		return false, fmt.Errorf("trashing calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// TrashCalculationsByUser удаляет в корзину все завершенные вычисления пользователя.
+// Возвращает количество удаленных вычислений.
+func TrashCalculationsByUser(db *sql.DB, userId int, now time.Time) (int64, error) {
+	query := `UPDATE calculations SET deleted_time = $1 WHERE userId = $2 AND ` + trashableCondition
+	res, err := db.Exec(query, now, userId)
+	if err != nil {
+		return 0, fmt.Errorf("trashing calculations of user %d: %w", userId, err)
+	}
+	return res.RowsAffected()
+}
+
+// FetchTrashByUser извлекает вычисления пользователя, удаленные в корзину не раньше deletedAfter,
+// начиная с последнего удаленного.
+func FetchTrashByUser(db *sql.DB, userId int, deletedAfter time.Time) ([]models.TrashedCalculation, error) {
+	var calculations []models.TrashedCalculation
+
+	query := `
+		SELECT id, userId, operation, result, status, deleted_time
+		FROM calculations
+		WHERE userId = $1 AND deleted_time >= $2
+		ORDER BY deleted_time DESC, id
+	`
+	rows, err := db.Query(query, userId, deletedAfter)
+	if err != nil {
+		return nil, fmt.Errorf("querying trash of user %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var calc models.TrashedCalculation
+		var result sql.NullFloat64 // Для обработки NULL значений.
+
+		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status, &calc.DeletedTime); err != nil {
+			return nil, fmt.Errorf("scanning calculation: %w", err)
+		}
+		if result.Valid {
+			calc.Result = result.Float64
+		}
+		calculations = append(calculations, calc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over trash: %w", err)
+	}
+	return calculations, nil
+}
+
+// RestoreCalculation возвращает вычисление пользователя из корзины, если оно удалено не раньше deletedAfter.
+// Возвращает false, если вычисления нет в корзине или срок его хранения истек.
+func RestoreCalculation(db *sql.DB, id, userId int, deletedAfter time.Time) (bool, error) {
+	query := `UPDATE calculations SET deleted_time = NULL WHERE id = $1 AND userId = $2 AND deleted_time >= $3`
+	res, err := db.Exec(query, id, userId, deletedAfter)
+	if err != nil {
+		return false, fmt.Errorf("restoring calculation %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// EmptyTrash окончательно удаляет все вычисления пользователя из корзины.
+func EmptyTrash(db *sql.DB, userId int) (int64, error) {
+	res, err := db.Exec(`DELETE FROM calculations WHERE userId = $1 AND deleted_time IS NOT NULL`, userId)
+	if err != nil {
+		return 0, fmt.Errorf("emptying trash of user %d: %w", userId, err)
+	}
+	return res.RowsAffected()
+}
+
+// PurgeTrash окончательно удаляет вычисления, удаленные в корзину раньше deletedBefore.
+func PurgeTrash(db *sql.DB, deletedBefore time.Time) (int64, error) {
+	res, err := db.Exec(`DELETE FROM calculations WHERE deleted_time < $1`, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("purging trash: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,300 @@
+package database
+
+import (
+	"calculatorapi/utility/models" // Структуры данных для калькулятора
+	"database/sql"                 // Импорт пакета для работы с SQL базами данных
+	"fmt"                          // Форматированный вывод
+	"time"                         // Работа со временем
+
+	"github.com/lib/pq" // Драйвер PostgreSQL
+)
+
+// CreateWebhookTablesIfNotExists создает таблицы вебхуков пользователей и журнала их доставки.
+func CreateWebhookTablesIfNotExists(db *sql.DB) error {
+	err := createTableIfNotExists(db, "webhooks", `
+		CREATE TABLE webhooks (
+			id SERIAL PRIMARY KEY,
+			userId INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created_time TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	return createTableIfNotExists(db, "webhook_deliveries", `
+		CREATE TABLE webhook_deliveries (
+			id SERIAL PRIMARY KEY,
+			webhook_id INTEGER,
+			userId INTEGER NOT NULL,
+			calculation_id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			event TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_status_code INTEGER,
+			last_error TEXT,
+			next_attempt_time TIMESTAMP,
+			created_time TIMESTAMP NOT NULL,
+			delivered_time TIMESTAMP
+		)
+	`)
+}
+
+// InsertWebhook регистрирует новый вебхук пользователя.
+func InsertWebhook(db *sql.DB, userId int, url, secret string) (*models.Webhook, error) {
+	hook := &models.Webhook{UserId: userId, URL: url, Secret: secret, CreatedTime: time.Now().UTC()}
+
+	query := `INSERT INTO webhooks (userId, url, secret, created_time) VALUES ($1, $2, $3, $4) RETURNING id`
+	if err := db.QueryRow(query, userId, url, secret, hook.CreatedTime).Scan(&hook.ID); err != nil {
+		return nil, fmt.Errorf("inserting webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// FetchWebhooksByUser извлекает все вебхуки пользователя вместе с ключами подписи.
+func FetchWebhooksByUser(db *sql.DB, userId int) ([]models.Webhook, error) {
+	var hooks []models.Webhook
+
+	query := `SELECT id, userId, url, secret, created_time FROM webhooks WHERE userId = $1 ORDER BY id`
+	rows, err := db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying webhooks for user %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hook models.Webhook
+		if err := rows.Scan(&hook.ID, &hook.UserId, &hook.URL, &hook.Secret, &hook.CreatedTime); err != nil {
+			return nil, fmt.Errorf("scanning webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя. Возвращает false, если вебхук не найден.
+func DeleteWebhook(db *sql.DB, id, userId int) (bool, error) {
+	res, err := db.Exec(`DELETE FROM webhooks WHERE id = $1 AND userId = $2`, id, userId)
+	if err != nil {
+		return false, fmt.Errorf("deleting webhook %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// EnqueueFinishedCalculations в одной транзакции ставит в очередь уведомления по вычислениям в одном из
+// окончательных статусов, по которым они еще не поставлены, и помечает эти вычисления как уведомленные.
+// Доставки каждого вычисления возвращает deliveries. Вычисления блокируются до конца транзакции, поэтому
+// несколько оркестраторов не поставят уведомления об одном вычислении дважды. Возвращает количество вычислений.
+func EnqueueFinishedCalculations(db *sql.DB, statuses []string, limit int, deliveries func(calc models.FinishedCalculation) ([]models.WebhookDelivery, error)) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, userId, operation, result, status, end_time, webhook_url, webhook_secret
+		FROM calculations
+		WHERE notified = FALSE AND status = ANY($1)
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(query, pq.Array(statuses), limit)
+	if err != nil {
+		return 0, fmt.Errorf("querying unnotified calculations: %w", err)
+	}
+	var calculations []models.FinishedCalculation
+	for rows.Next() {
+		var (
+			calc          models.FinishedCalculation
+			result        sql.NullFloat64
+			endTime       sql.NullTime
+			webhookURL    sql.NullString
+			webhookSecret sql.NullString
+		)
+		if err := rows.Scan(&calc.ID, &calc.UserId, &calc.Operation, &result, &calc.Status, &endTime, &webhookURL, &webhookSecret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning calculation: %w", err)
+		}
+		calc.Result = result.Float64
+		calc.EndTime = endTime.Time
+		calc.WebhookURL = webhookURL.String
+		calc.WebhookSecret = webhookSecret.String
+		calculations = append(calculations, calc)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterating over calculations results: %w", err)
+	}
+
+	insert := `
+		INSERT INTO webhook_deliveries (webhook_id, userId, calculation_id, url, secret, event, payload, status, attempts, next_attempt_time, created_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending', 0, $8, $8)
+	`
+	now := time.Now().UTC()
+	for _, calc := range calculations {
+		calcDeliveries, err := deliveries(calc)
+		if err != nil {
+			return 0, fmt.Errorf("building webhook deliveries for calculation %d: %w", calc.ID, err)
+		}
+		for _, d := range calcDeliveries {
+			var webhookID sql.NullInt64
+			if d.WebhookID != nil {
+				webhookID = sql.NullInt64{Int64: int64(*d.WebhookID), Valid: true}
+			}
+			if _, err := tx.Exec(insert, webhookID, d.UserId, calc.ID, d.URL, d.Secret, d.Event, d.Payload, now); err != nil {
+				return 0, fmt.Errorf("inserting webhook delivery: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(`UPDATE calculations SET notified = TRUE WHERE id = $1`, calc.ID); err != nil {
+			return 0, fmt.Errorf("marking calculation %d as notified: %w", calc.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("committing webhook deliveries: %w", err)
+	}
+	return len(calculations), nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, userId, calculation_id, url, secret, event, payload, status, attempts,
+	last_status_code, last_error, next_attempt_time, created_time, delivered_time`
+
+// scanWebhookDeliveries считывает строки журнала доставки.
+func scanWebhookDeliveries(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var (
+			d              models.WebhookDelivery
+			webhookID      sql.NullInt64
+			lastStatusCode sql.NullInt64
+			lastError      sql.NullString
+			nextAttempt    sql.NullTime
+			deliveredTime  sql.NullTime
+		)
+		if err := rows.Scan(&d.ID, &webhookID, &d.UserId, &d.CalculationID, &d.URL, &d.Secret, &d.Event, &d.Payload, &d.Status, &d.Attempts,
+			&lastStatusCode, &lastError, &nextAttempt, &d.CreatedTime, &deliveredTime); err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		if webhookID.Valid {
+			id := int(webhookID.Int64)
+			d.WebhookID = &id
+		}
+		d.LastStatusCode = int(lastStatusCode.Int64)
+		d.LastError = lastError.String
+		if nextAttempt.Valid {
+			d.NextAttempt = &nextAttempt.Time
+		}
+		if deliveredTime.Valid {
+			d.DeliveredTime = &deliveredTime.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimDueWebhookDeliveries забирает ожидающие доставки, время очередной попытки которых наступило, и переводит их
+// в статус 'sending' до leaseUntil. Доставки забираются одним запросом, поэтому несколько оркестраторов не отправят
+// одну доставку дважды. Доставка, результат которой не сохранен к leaseUntil, например после сбоя оркестратора,
+// забирается снова.
+func ClaimDueWebhookDeliveries(db *sql.DB, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	query := `UPDATE webhook_deliveries SET status = 'sending', next_attempt_time = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status IN ('pending', 'sending') AND next_attempt_time <= $1
+			ORDER BY next_attempt_time
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+	rows, err := db.Query(query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claiming due webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// FetchWebhookDeliveriesByUser извлекает журнал доставки пользователя, начиная с самых новых.
+// Если calculationID не равен 0, возвращаются только доставки по этому вычислению.
+func FetchWebhookDeliveriesByUser(db *sql.DB, userId, calculationID, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE userId = $1 AND ($2 = 0 OR calculation_id = $2)
+		ORDER BY id DESC
+		LIMIT $3`
+	rows, err := db.Query(query, userId, calculationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying webhook deliveries for user %d: %w", userId, err)
+	}
+	defer rows.Close()
+
+	return scanWebhookDeliveries(rows)
+}
+
+// UpdateWebhookDelivery сохраняет результат попытки доставки.
+func UpdateWebhookDelivery(db *sql.DB, d models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, last_status_code = $3, last_error = $4, next_attempt_time = $5, delivered_time = $6
+		WHERE id = $7
+	`
+	var (
+		lastStatusCode sql.NullInt64
+		nextAttempt    sql.NullTime
+		deliveredTime  sql.NullTime
+	)
+	if d.LastStatusCode != 0 {
+		lastStatusCode = sql.NullInt64{Int64: int64(d.LastStatusCode), Valid: true}
+	}
+	if d.NextAttempt != nil {
+		nextAttempt = sql.NullTime{Time: *d.NextAttempt, Valid: true}
+	}
+	if d.DeliveredTime != nil {
+		deliveredTime = sql.NullTime{Time: *d.DeliveredTime, Valid: true}
+	}
+
+	_, err := db.Exec(query, d.Status, d.Attempts, lastStatusCode, nullString(d.LastError), nextAttempt, deliveredTime, d.ID)
+	if err != nil {
+		return fmt.Errorf("updating webhook delivery %d: %w", d.ID, err)
+	}
+	return nil
+}
+
+// RequeueWebhookDelivery ставит доставку пользователя на немедленную повторную отправку
+// с обнулением счетчика попыток. Возвращает false, если доставка не найдена.
+func RequeueWebhookDelivery(db *sql.DB, id, userId int) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, last_error = NULL, next_attempt_time = $1, delivered_time = NULL
+		WHERE id = $2 AND userId = $3
+	`
+	res, err := db.Exec(query, time.Now().UTC(), id, userId)
+	if err != nil {
+		return false, fmt.Errorf("requeueing webhook delivery %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
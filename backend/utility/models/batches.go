@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Batch определяет структуру пакета вычислений, отправленных одним запросом.
+type Batch struct {
+	ID          int       `json:"id"`           // Идентификатор пакета
+	UserId      int       `json:"userId"`       // Идентификатор юзера
+	Total       int       `json:"total"`        // Количество вычислений в пакете
+	CreatedTime time.Time `json:"created_time"` // Время создания пакета
+}
+
+// BatchStatus определяет структуру сводного статуса пакета.
+type BatchStatus struct {
+	Batch
+	Counts   map[string]int      `json:"counts"`   // Количество вычислений в каждом статусе
+	Finished int                 `json:"finished"` // Количество вычислений в окончательном статусе
+	Progress float64             `json:"progress"` // Доля завершенных вычислений от 0 до 1
+	Results  []OperationResponse `json:"results"`  // Вычисления пакета с результатами
+}
@@ -1,5 +1,14 @@
 package models
 
+import "time"
+
+// Уровни приоритета вычислений: вычисления с большим приоритетом отправляются на калькуляторы раньше
+const (
+	MinPriority     = 0
+	MaxPriority     = 9
+	DefaultPriority = 5
+)
+
 // CalculationRequest определяет структуру запроса на вычисление.
 type CalculationRequest struct {
 	ID                 int    `json:"id"`                             // Идентификатор запроса, должен соответствовать схеме базы данных
@@ -10,6 +19,26 @@ type CalculationRequest struct {
 	MultiplyDuration   int    `json:"multiply_duration"`              // Продолжительность операции умножения в секундах
 	DivideDuration     int    `json:"divide_duration"`                // Продолжительность операции деления в секундах
 	InactiveServerTime int    `json:"inactive_server_time,omitempty"` // Время бездействия сервера, может быть опущено
+	WebhookURL         string `json:"webhook_url,omitempty"`          // Адрес вебхука, уведомляемого о завершении этого вычисления
+	WebhookSecret      string `json:"-"`                              // Ключ подписи вебхука, наружу не отдается
+	Priority           int    `json:"priority"`                       // Приоритет от MinPriority до MaxPriority
+	Attempts           int    `json:"-"`                              // Количество начатых попыток, заполняется при выборке для отправки агентам
+}
+
+// HistoryRecord определяет структуру записи истории вычислений при экспорте и импорте.
+type HistoryRecord struct {
+	ID          int        `json:"id,omitempty"`       // Идентификатор вычисления, при импорте не используется
+	Operation   string     `json:"operation"`          // Строка операции
+	Result      *float64   `json:"result"`             // Результат, пусто для незавершенных вычислений
+	Status      string     `json:"status"`             // Статус вычисления
+	CreatedTime time.Time  `json:"created_time"`       // Время создания вычисления
+	EndTime     *time.Time `json:"end_time,omitempty"` // Время завершения вычисления
+}
+
+// HistoryFilter определяет условия выборки истории вычислений. Нулевые значения не ограничивают выборку.
+type HistoryFilter struct {
+	From time.Time // Начало интервала времени создания включительно
+	To   time.Time // Конец интервала времени создания, не включая
 }
 
 // CalculationResponse определяет структуру для возвращения результатов вычислений.
@@ -19,6 +48,19 @@ type CalculationResponse struct {
 	UserId    int     `json:"userId"`           // Идентификатор юзера
 	Result    float64 `json:"result,omitempty"` // Результат вычисления, может быть опущен, если вычисление не завершено
 	Status    string  `json:"status"`           // Статус запроса, например "completed" или "error"
+	CalculationExecution
+}
+
+// CalculationExecution определяет сведения о том, когда и каким агентом выполнялось вычисление.
+// Сведения об отправке и об агенте относятся к последней попытке выполнения.
+type CalculationExecution struct {
+	CreatedTime     *time.Time `json:"created_time,omitempty"`     // Время создания вычисления
+	StartTime       *time.Time `json:"start_time,omitempty"`       // Время начала выполнения агентом
+	EndTime         *time.Time `json:"end_time,omitempty"`         // Время завершения вычисления
+	OperationServer string     `json:"operation_server,omitempty"` // Адрес агента, на который оркестратор отправил вычисление
+	ServerStatus    string     `json:"server_status,omitempty"`    // Состояние попытки: "dispatched", "queued", "work", "completed" или "aborted"
+	AgentID         string     `json:"agent_id,omitempty"`         // Идентификатор, которым представился выполнявший вычисление агент
+	Attempts        int        `json:"attempts,omitempty"`         // Номер последней попытки выполнения
 }
 
 // OperationResponse определяет структуру для возвращения информации об операции.
@@ -28,6 +70,24 @@ type OperationResponse struct {
 	Operation string  `json:"operation"`        // Строка операции, выполненной калькулятором
 	Result    float64 `json:"result,omitempty"` // Результат операции, может быть опущен, если операция не завершена
 	Status    string  `json:"status"`           // Статус операции, например "created", "work" или "completed"
+	CalculationExecution
+}
+
+// TrashedCalculation определяет структуру вычисления, удаленного в корзину.
+type TrashedCalculation struct {
+	OperationResponse
+	DeletedTime time.Time `json:"deleted_time"` // Время удаления в корзину
+	ExpiresTime time.Time `json:"expires_time"` // Время, после которого вычисление будет удалено окончательно
+}
+
+// FailedCalculation определяет структуру вычисления, все попытки выполнения которого закончились неудачей.
+type FailedCalculation struct {
+	ID            int       `json:"id"`             // Идентификатор вычисления
+	UserId        int       `json:"userId"`         // Идентификатор юзера
+	Operation     string    `json:"operation"`      // Строка операции
+	Attempts      int       `json:"attempts"`       // Количество выполненных попыток
+	FailureReason string    `json:"failure_reason"` // Причина последней неудачи
+	EndTime       time.Time `json:"end_time"`       // Время перевода в статус 'failed'
 }
 
 // User определяет структуру для юзера.
@@ -36,3 +96,13 @@ type User struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
 }
+
+// CalculationEvent определяет структуру события об изменении статуса вычисления.
+type CalculationEvent struct {
+	ID        int       `json:"id"`               // Идентификатор вычисления
+	UserId    int       `json:"userId"`           // Идентификатор юзера
+	Operation string    `json:"operation"`        // Строка операции
+	Result    float64   `json:"result,omitempty"` // Результат, если вычисление завершено
+	Status    string    `json:"status"`           // Новый статус: "created", "work", "completed" или "cancelled"
+	Time      time.Time `json:"time"`             // Время, когда изменение было обнаружено
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord определяет структуру сохраненного ключа идемпотентности и ответа на первый запрос с ним.
+type IdempotencyRecord struct {
+	ID           int       // Идентификатор записи
+	UserId       int       // Идентификатор юзера, ключи уникальны в пределах пользователя
+	Key          string    // Значение заголовка Idempotency-Key
+	Endpoint     string    // Путь эндпоинта, на который был отправлен запрос
+	RequestHash  string    // SHA-256 хэш тела первого запроса
+	ResponseCode int       // HTTP код ответа, 0 пока запрос обрабатывается
+	ResponseBody string    // Тело ответа на первый запрос
+	CreatedTime  time.Time // Время первого запроса
+}
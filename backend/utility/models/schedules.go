@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Schedule определяет структуру расписания, по которому вычисление запускается однократно в заданное время
+// или повторно по выражению cron. Каждый запуск создает новую запись в calculations со ссылкой на расписание.
+type Schedule struct {
+	ID                 int        `json:"id"`                            // Идентификатор расписания
+	UserId             int        `json:"userId"`                        // Идентификатор юзера
+	Operation          string     `json:"operation"`                     // Строка операции, например "2+2"
+	Cron               string     `json:"cron,omitempty"`                // Выражение cron повторяющегося расписания, пусто для однократного
+	RunAt              *time.Time `json:"run_at,omitempty"`              // Время однократного запуска
+	Timezone           string     `json:"timezone"`                      // Часовой пояс выражения cron, например "Europe/Moscow"
+	AddDuration        int        `json:"add_duration"`                  // Продолжительность операции сложения в секундах
+	SubtractDuration   int        `json:"subtract_duration"`             // Продолжительность операции вычитания в секундах
+	MultiplyDuration   int        `json:"multiply_duration"`             // Продолжительность операции умножения в секундах
+	DivideDuration     int        `json:"divide_duration"`               // Продолжительность операции деления в секундах
+	InactiveServerTime int        `json:"inactive_server_time"`          // Время бездействия сервера в секундах
+	Priority           int        `json:"priority"`                      // Приоритет создаваемых вычислений
+	Enabled            bool       `json:"enabled"`                       // Выключенное расписание не запускается
+	NextRunTime        *time.Time `json:"next_run_time"`                 // Время следующего запуска, пусто после однократного запуска
+	LastRunTime        *time.Time `json:"last_run_time,omitempty"`       // Время последнего запуска
+	LastCalculationID  *int       `json:"last_calculation_id,omitempty"` // Вычисление, созданное последним запуском
+	CreatedTime        time.Time  `json:"created_time"`                  // Время создания расписания
+}
+
+// ScheduleRun определяет вычисление, созданное запуском расписания.
+type ScheduleRun struct {
+	ScheduleID    int       // Расписание, по которому выполнен запуск
+	CalculationID int       // Созданное вычисление, 0 для пропущенного запуска
+	UserId        int       // Идентификатор юзера
+	Operation     string    // Строка операции
+	Time          time.Time // Время запуска
+	Skipped       bool      // Запуск пропущен, потому что очередь пользователя заполнена
+}
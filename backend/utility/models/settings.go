@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UserSettings определяет структуру настроек пользователя: длительности операций по умолчанию
+// и параметры отображения результатов.
+type UserSettings struct {
+	UserId             int        `json:"userId"`                 // Идентификатор юзера
+	AddDuration        int        `json:"add_duration"`           // Длительность сложения по умолчанию в секундах
+	SubtractDuration   int        `json:"subtract_duration"`      // Длительность вычитания по умолчанию в секундах
+	MultiplyDuration   int        `json:"multiply_duration"`      // Длительность умножения по умолчанию в секундах
+	DivideDuration     int        `json:"divide_duration"`        // Длительность деления по умолчанию в секундах
+	InactiveServerTime int        `json:"inactive_server_time"`   // Время ожидания неактивного сервера по умолчанию в секундах
+	Precision          int        `json:"precision"`              // Количество знаков после запятой при отображении результата
+	OutputFormat       string     `json:"output_format"`          // Формат вывода результата: "decimal" или "scientific"
+	Locale             string     `json:"locale"`                 // Локаль для отображения, например "ru-RU"
+	UpdatedTime        *time.Time `json:"updated_time,omitempty"` // Время последнего изменения, пусто для настроек по умолчанию
+}
+
+// DefaultUserSettings возвращает настройки пользователя, который еще их не сохранял.
+func DefaultUserSettings(userId int) UserSettings {
+	return UserSettings{
+		UserId:             userId,
+		AddDuration:        1,
+		SubtractDuration:   1,
+		MultiplyDuration:   1,
+		DivideDuration:     1,
+		InactiveServerTime: 60,
+		Precision:          6,
+		OutputFormat:       "decimal",
+		Locale:             "en-US",
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Webhook определяет структуру зарегистрированного пользователем вебхука.
+type Webhook struct {
+	ID          int       `json:"id"`               // Идентификатор вебхука
+	UserId      int       `json:"userId"`           // Идентификатор юзера
+	URL         string    `json:"url"`              // Адрес, на который отправляются уведомления
+	Secret      string    `json:"secret,omitempty"` // Ключ HMAC подписи, возвращается только при создании
+	CreatedTime time.Time `json:"created_time"`     // Время регистрации
+}
+
+// WebhookDelivery определяет структуру записи журнала доставки вебхука.
+type WebhookDelivery struct {
+	ID             int        `json:"id"`                         // Идентификатор доставки
+	WebhookID      *int       `json:"webhook_id,omitempty"`       // Вебхук пользователя, пусто для вебхука из запроса на вычисление
+	UserId         int        `json:"userId"`                     // Идентификатор юзера
+	CalculationID  int        `json:"calculation_id"`             // Вычисление, о котором отправлено уведомление
+	URL            string     `json:"url"`                        // Адрес получателя
+	Secret         string     `json:"-"`                          // Ключ подписи, наружу не отдается
+	Event          string     `json:"event"`                      // Тип события, например "calculation.completed"
+	Payload        string     `json:"payload"`                    // Отправляемое JSON тело
+	Status         string     `json:"status"`                     // Статус доставки: "pending", "delivered" или "failed"
+	Attempts       int        `json:"attempts"`                   // Количество выполненных попыток
+	LastStatusCode int        `json:"last_status_code,omitempty"` // HTTP код последнего ответа получателя
+	LastError      string     `json:"last_error,omitempty"`       // Ошибка последней попытки
+	NextAttempt    *time.Time `json:"next_attempt_time,omitempty"`
+	CreatedTime    time.Time  `json:"created_time"`
+	DeliveredTime  *time.Time `json:"delivered_time,omitempty"`
+}
+
+// FinishedCalculation определяет завершенное вычисление, по которому еще не поставлены в очередь уведомления.
+type FinishedCalculation struct {
+	ID            int       // Идентификатор вычисления
+	UserId        int       // Идентификатор юзера
+	Operation     string    // Строка операции
+	Result        float64   // Результат вычисления
+	Status        string    // Окончательный статус
+	EndTime       time.Time // Время завершения
+	WebhookURL    string    // Вебхук, указанный при отправке вычисления
+	WebhookSecret string    // Ключ подписи вебхука из запроса
+}
@@ -0,0 +1,382 @@
+// Пакет openapi загружает документ OpenAPI 3 и проверяет по нему тела запросов и ответов.
+// Поддерживается подмножество JSON Schema, используемое в спецификации оркестратора.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Document определяет структуру документа OpenAPI.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Paths      map[string]map[string]*Operation `json:"paths"` // Путь -> HTTP метод в нижнем регистре -> операция
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	} `json:"components"`
+}
+
+// Operation определяет структуру описания одной операции.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter определяет параметр пути или строки запроса.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody определяет тело запроса операции.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response определяет ответ операции.
+type Response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType связывает тип содержимого со схемой.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema определяет поддерживаемое подмножество JSON Schema.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+}
+
+// FieldError описывает ошибку проверки конкретного поля.
+type FieldError struct {
+	Field   string `json:"field"`   // Путь к полю, например "items[2].operation"; пусто для всего тела
+	Message string `json:"message"` // Описание ошибки
+}
+
+// Parse разбирает документ OpenAPI в формате JSON.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// FindOperation находит операцию по методу и пути запроса. Шаблоны вида {id} совпадают с любым сегментом,
+// при нескольких совпадениях выбирается путь с наибольшим числом буквальных сегментов.
+// Возвращает операцию, шаблон пути и признак того, что путь описан в документе.
+func (d *Document) FindOperation(method, path string) (*Operation, string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	best, bestScore := "", -1
+	for template := range d.Paths {
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		score := 0
+		matched := true
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				continue
+			}
+			if part != segments[i] {
+				matched = false
+				break
+			}
+			score++
+		}
+		if matched && score > bestScore {
+			best, bestScore = template, score
+		}
+	}
+
+	if bestScore < 0 {
+		return nil, "", false
+	}
+	return d.Paths[best][strings.ToLower(method)], best, true
+}
+
+// ValidateRequestBody проверяет JSON тело запроса операции. Тело с типом содержимого, не описанным
+// в документе, считается JSON, как и в обработчиках; тела других описанных типов не проверяются.
+func (d *Document) ValidateRequestBody(op *Operation, contentType string, body []byte) []FieldError {
+	if op == nil || op.RequestBody == nil {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if _, ok := op.RequestBody.Content[mediaType]; !ok {
+		mediaType = "application/json"
+	}
+	media, ok := op.RequestBody.Content[mediaType]
+	if mediaType != "application/json" || !ok || media.Schema == nil {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return []FieldError{{Message: "request body is required"}}
+		}
+		return nil
+	}
+	return d.validateJSON(media.Schema, body)
+}
+
+// ValidateResponse проверяет тело ответа операции с кодом statusCode.
+// Возвращает ошибку, если код ответа или тип содержимого не описаны в документе.
+func (d *Document) ValidateResponse(op *Operation, statusCode int, contentType string, body []byte) []FieldError {
+	if op == nil {
+		return []FieldError{{Message: "operation is not described"}}
+	}
+	resp, ok := op.Responses[strconv.Itoa(statusCode)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return []FieldError{{Message: fmt.Sprintf("response status %d is not described", statusCode)}}
+	}
+	if resp.Ref != "" {
+		if resp, ok = d.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]; !ok {
+			return []FieldError{{Message: "unresolved response reference"}}
+		}
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return []FieldError{{Message: fmt.Sprintf("content type %q is not described for status %d", contentType, statusCode)}}
+	}
+	if mediaType != "application/json" || media.Schema == nil {
+		return nil
+	}
+	return d.validateJSON(media.Schema, body)
+}
+
+func (d *Document) validateJSON(schema *Schema, body []byte) []FieldError {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{Message: "invalid JSON: " + err.Error()}}
+	}
+	return d.ValidateValue(schema, value, "")
+}
+
+// ValidateValue проверяет разобранное JSON значение (числа как json.Number) по схеме.
+func (d *Document) ValidateValue(schema *Schema, value interface{}, field string) []FieldError {
+	schema = d.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return []FieldError{{field, "must not be null"}}
+	}
+
+	var errs []FieldError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, FieldError{field, fmt.Sprintf(format, args...)})
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return errs
+		}
+		errs = append(errs, d.validateObject(schema, object, field)...)
+
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return errs
+		}
+		if schema.MinItems != nil && len(array) < *schema.MinItems {
+			fail("must contain at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(array) > *schema.MaxItems {
+			fail("must contain at most %d items", *schema.MaxItems)
+		}
+		for i, item := range array {
+			errs = append(errs, d.ValidateValue(schema.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return errs
+		}
+		length := len([]rune(str))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(str) {
+				fail("must match pattern %s", schema.Pattern)
+			}
+		}
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("must be a number")
+			return errs
+		}
+		f, err := number.Float64()
+		if err != nil {
+			fail("must be a number")
+			return errs
+		}
+		if schema.Type == "integer" && f != math.Trunc(f) {
+			fail("must be an integer")
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			fail("must be greater than or equal to %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			fail("must be less than or equal to %v", *schema.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		fail("must be one of %v", schema.Enum)
+	}
+	return errs
+}
+
+// validateObject проверяет свойства объекта. Имена свойств сравниваются без учета регистра,
+// как это делает encoding/json при декодировании в структуры.
+func (d *Document) validateObject(schema *Schema, object map[string]interface{}, field string) []FieldError {
+	var errs []FieldError
+
+	// Сопоставляем ключи объекта со свойствами схемы
+	matched := make(map[string]string, len(object)) // свойство схемы -> ключ объекта
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, ok := lookupProperty(schema.Properties, key)
+		if !ok {
+			if string(bytes.TrimSpace(schema.AdditionalProperties)) == "false" {
+				errs = append(errs, FieldError{joinField(field, key), "is not allowed"})
+			}
+			continue
+		}
+		matched[name] = key
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := matched[name]; !ok {
+			errs = append(errs, FieldError{joinField(field, name), "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(matched))
+	for name := range matched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, d.ValidateValue(schema.Properties[name], object[matched[name]], joinField(field, name))...)
+	}
+	return errs
+}
+
+func lookupProperty(properties map[string]*Schema, key string) (string, bool) {
+	if _, ok := properties[key]; ok {
+		return key, true
+	}
+	for name := range properties {
+		if strings.EqualFold(name, key) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// resolve подставляет схему по ссылке вида "#/components/schemas/Name".
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		schema = d.Components.Schemas[name]
+	}
+	return schema
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		switch v := value.(type) {
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				if a, ok := allowed.(float64); ok && a == f {
+					return true
+				}
+			}
+		default:
+			if allowed == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
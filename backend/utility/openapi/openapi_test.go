@@ -0,0 +1,126 @@
+package openapi
+
+import (
+	"testing"
+)
+
+const testDocument = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/items/{id}": { "get": { "responses": { "200": { "$ref": "#/components/responses/Item" } } } },
+    "/items/search": {
+      "post": {
+        "requestBody": { "required": true, "content": { "application/json": { "schema": { "$ref": "#/components/schemas/Query" } } } },
+        "responses": { "204": { "description": "No content" } }
+      }
+    }
+  },
+  "components": {
+    "responses": {
+      "Item": { "description": "Item", "content": { "application/json": { "schema": { "$ref": "#/components/schemas/Item" } } } }
+    },
+    "schemas": {
+      "Item": {
+        "type": "object",
+        "required": ["id", "tags"],
+        "properties": {
+          "id": { "type": "integer", "minimum": 1 },
+          "tags": { "type": "array", "items": { "type": "string", "minLength": 1 } }
+        }
+      },
+      "Query": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "text": { "type": "string", "pattern": "^[a-z]+$" },
+          "limit": { "type": "integer", "nullable": true, "maximum": 10 },
+          "sort": { "type": "string", "enum": ["asc", "desc"] }
+        }
+      }
+    }
+  }
+}`
+
+func TestFindOperationPrefersLiteralSegments(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		method, path, template string
+		found, hasOp           bool
+	}{
+		{"POST", "/items/search", "/items/search", true, true},
+		{"GET", "/items/5", "/items/{id}", true, true},
+		{"DELETE", "/items/5", "/items/{id}", true, false},
+		{"GET", "/items/5/parts", "", false, false},
+	}
+	for _, tt := range tests {
+		op, template, found := doc.FindOperation(tt.method, tt.path)
+		if template != tt.template || found != tt.found || (op != nil) != tt.hasOp {
+			t.Errorf("FindOperation(%s %s) = %v, %q, %v", tt.method, tt.path, op != nil, template, found)
+		}
+	}
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	op, _, _ := doc.FindOperation("POST", "/items/search")
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []FieldError
+	}{
+		{"valid", "application/json", `{"Text":"abc","limit":null,"sort":"asc"}`, nil},
+		{"undescribed content type is JSON", "text/plain", `{"limit":2.5}`, []FieldError{{"limit", "must be an integer"}}},
+		{"empty body", "application/json", ` `, []FieldError{{"", "request body is required"}}},
+		{"invalid JSON", "application/json", `{`, []FieldError{{"", "invalid JSON: unexpected EOF"}}},
+		{"several errors", "application/json", `{"text":"ABC","limit":11,"sort":"up","extra":1}`, []FieldError{
+			{"extra", "is not allowed"},
+			{"limit", "must be less than or equal to 10"},
+			{"sort", "must be one of [asc desc]"},
+			{"text", "must match pattern ^[a-z]+$"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := doc.ValidateRequestBody(op, tt.contentType, []byte(tt.body))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	op, _, _ := doc.FindOperation("GET", "/items/1")
+
+	if errs := doc.ValidateResponse(op, 200, "application/json", []byte(`{"id":1,"tags":["a"]}`)); len(errs) != 0 {
+		t.Errorf("Expected valid response, got %v", errs)
+	}
+	if errs := doc.ValidateResponse(op, 200, "application/json", []byte(`{"id":0,"tags":[""]}`)); len(errs) != 2 {
+		t.Errorf("Expected errors for id and tags[0], got %v", errs)
+	}
+	if errs := doc.ValidateResponse(op, 200, "text/plain; charset=utf-8", []byte(`oops`)); len(errs) != 1 {
+		t.Errorf("Expected undescribed content type to be reported, got %v", errs)
+	}
+	if errs := doc.ValidateResponse(op, 404, "application/json", []byte(`{}`)); len(errs) != 1 {
+		t.Errorf("Expected undescribed status to be reported, got %v", errs)
+	}
+}
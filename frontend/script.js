@@ -155,7 +155,8 @@ function submitCalculation() {
     fetch('http://localhost:8080/submit-calculation', {
         method: 'POST',
         headers: {
-            'Content-Type': 'application/json'
+            'Content-Type': 'application/json',
+            'Authorization': 'Bearer ' + localStorage.getItem('jwt')
         },
         body: JSON.stringify({
             UserId: getUserIDFromJWT(),
@@ -358,10 +359,11 @@ function updateResults() {
 
 // Функция для очистки и обновления результатов операций
 function clearAllCalculationsAndUpdate() {
-    fetch('http://localhost:8080/clear-all-calculations', {
-        method: 'POST',
+    // Вычисления пользователя перемещаются в корзину, откуда их можно восстановить
+    fetch('http://localhost:8080/api/v1/calculations', {
+        method: 'DELETE',
         headers: {
-            'Content-Type': 'application/json'
+            'Authorization': 'Bearer ' + localStorage.getItem('jwt')
         }
     })
     .then(response => {